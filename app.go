@@ -0,0 +1,16 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/robfig/cron/v3"
+	"webook/internal/events"
+	"webook/internal/job"
+)
+
+type App struct {
+	server     *gin.Engine
+	consumers  []events.Consumer
+	cron       *cron.Cron
+	rankingJob *job.RankingJob
+	scheduler  *job.Scheduler
+}
@@ -17,6 +17,7 @@ require (
 	github.com/hashicorp/golang-lru v1.0.2
 	github.com/lithammer/shortuuid/v4 v4.0.0
 	github.com/redis/go-redis/v9 v9.4.0
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.18.2
 	github.com/stretchr/testify v1.8.4
@@ -25,6 +26,7 @@ require (
 	go.uber.org/mock v0.4.0
 	go.uber.org/zap v1.21.0
 	golang.org/x/crypto v0.18.0
+	golang.org/x/sync v0.6.0
 	gorm.io/driver/mysql v1.5.2
 	gorm.io/gorm v1.25.6
 )
@@ -105,7 +107,6 @@ require (
 	golang.org/x/exp v0.0.0-20230905200255-921286631fa9 // indirect
 	golang.org/x/net v0.20.0 // indirect
 	golang.org/x/oauth2 v0.15.0 // indirect
-	golang.org/x/sync v0.6.0 // indirect
 	golang.org/x/sys v0.16.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	golang.org/x/time v0.5.0 // indirect
@@ -1,13 +1,46 @@
 package domain
 
+import "time"
+
 type Article struct {
 	Id      int64
 	Title   string
 	Content string
 
 	Author Author
+	Status ArticleStatus
+	Ctime  time.Time
+	Utime  time.Time
+}
+
+// Abstract 取内容的前面一部分作为摘要
+func (a Article) Abstract() string {
+	cs := []rune(a.Content)
+	if len(cs) < 100 {
+		return a.Content
+	}
+	return string(cs[:100])
 }
 
 type Author struct {
 	Id int64
 }
+
+type ArticleStatus uint8
+
+const (
+	// ArticleStatusUnknown 为了避免零值之类的问题
+	ArticleStatusUnknown ArticleStatus = iota
+	// ArticleStatusUnpublished 未发表，也就是只保存在制作库
+	ArticleStatusUnpublished
+	// ArticleStatusPublished 已发表，读者可见
+	ArticleStatusPublished
+	// ArticleStatusPrivate 仅自己可见，撤回之后就是这个状态
+	ArticleStatusPrivate
+	// ArticleStatusTakenDown 被管理员下架，作者不能再修改和发表
+	ArticleStatusTakenDown
+)
+
+func (s ArticleStatus) ToUint8() uint8 {
+	return uint8(s)
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"github.com/robfig/cron/v3"
+	"time"
+)
+
+type CronJob struct {
+	Id   int64
+	Name string
+	// Expression cron 表达式，支持秒
+	Expression string
+	// Executor 用哪个执行器执行
+	Executor string
+	// Cfg 执行器自己解析的配置
+	Cfg string
+	// Version 抢占时拿到的版本号，续约和释放的时候用来确认任务还是自己的
+	Version  int
+	NextTime time.Time
+
+	// CancelFunc 释放任务，停止续约
+	CancelFunc func()
+}
+
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour |
+	cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Schedule 解析 cron 表达式，创建任务的时候用来校验表达式
+func (j CronJob) Schedule() (cron.Schedule, error) {
+	return cronParser.Parse(j.Expression)
+}
+
+// Next 下一次执行的时间，没有下一次了返回零值
+func (j CronJob) Next(t time.Time) time.Time {
+	s, err := j.Schedule()
+	if err != nil {
+		// AddJob 的时候校验过，只有直接写进数据库的表达式才会走到这里，那就不再调度了
+		return time.Time{}
+	}
+	return s.Next(t)
+}
@@ -0,0 +1,14 @@
+package domain
+
+type Interactive struct {
+	Biz   string
+	BizId int64
+
+	ReadCnt    int64
+	LikeCnt    int64
+	CollectCnt int64
+
+	// 下面两个是针对当前用户的
+	Liked     bool
+	Collected bool
+}
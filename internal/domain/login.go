@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// LoginLockPolicy 登录失败多少次之后锁定，以及锁定多久
+type LoginLockPolicy struct {
+	// Threshold 在 Window 内连续失败这么多次就锁定
+	Threshold int
+	// BaseLock 第一次锁定的时间，之后每多失败一次翻倍，最多 MaxLock
+	BaseLock time.Duration
+	MaxLock  time.Duration
+	Window   time.Duration
+}
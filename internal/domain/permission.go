@@ -0,0 +1,42 @@
+package domain
+
+const (
+	// RoleAdmin 管理员，拥有所有权限
+	RoleAdmin = "admin"
+	// RoleOperator 运营，可以管理内容但是不能封禁用户
+	RoleOperator = "operator"
+)
+
+type Permission string
+
+const (
+	PermissionUserList        Permission = "user:list"
+	PermissionUserBan         Permission = "user:ban"
+	PermissionUserMerge       Permission = "user:merge"
+	PermissionArticleTakedown Permission = "article:takedown"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		PermissionUserList,
+		PermissionUserBan,
+		PermissionUserMerge,
+		PermissionArticleTakedown,
+	},
+	RoleOperator: {
+		PermissionUserList,
+		PermissionArticleTakedown,
+	},
+}
+
+// HasPermission 只要有一个角色拥有这个权限就可以
+func HasPermission(roles []string, p Permission) bool {
+	for _, r := range roles {
+		for _, rp := range rolePermissions[r] {
+			if rp == p {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,9 @@
+package domain
+
+// TOTP 用户绑定的 TOTP 两步验证
+type TOTP struct {
+	Uid    int64
+	Secret string
+	// Enabled 绑定之后输入过一次正确的验证码才算启用
+	Enabled bool
+}
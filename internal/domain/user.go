@@ -1,13 +1,86 @@
 package domain
 
+import "time"
+
 type User struct {
-	Id       int64
-	Email    string
-	Password string
-	NickName string
-	Birthday int64
-	AboutMe  string
-	Phone    string
+	Id    int64
+	Email string
+	// EmailVerified 邮箱是不是已经验证过了
+	EmailVerified bool
+	Password      string
+	NickName      string
+	Birthday      int64
+	AboutMe       string
+	Phone         string
+	// Roles 角色，普通用户没有任何角色
+	Roles  []string
+	Status UserStatus
 
 	WechatInfo WechatInfo
 }
+
+// LoginMethods 还能用几种方式登录，邮箱要设置了密码才能登录
+func (u User) LoginMethods() int {
+	cnt := 0
+	if u.Email != "" && u.Password != "" {
+		cnt++
+	}
+	if u.Phone != "" {
+		cnt++
+	}
+	if u.WechatInfo.OpenId != "" {
+		cnt++
+	}
+	return cnt
+}
+
+// Identity 可以绑定到账号上的身份
+type Identity string
+
+const (
+	IdentityPhone  Identity = "phone"
+	IdentityEmail  Identity = "email"
+	IdentityWechat Identity = "wechat"
+)
+
+// UserMergeDetail 账号合并的时候，从被合并的账号上搬过来了哪些数据
+type UserMergeDetail struct {
+	Email    bool
+	Phone    bool
+	Wechat   bool
+	Articles int64
+	Likes    int64
+	Collects int64
+}
+
+// UserArchive 导出给用户自己的个人数据
+type UserArchive struct {
+	Profile  User
+	Articles []Article
+	Likes    []UserInteraction
+	Collects []UserInteraction
+}
+
+// UserInteraction 用户点赞或者收藏过的资源
+type UserInteraction struct {
+	Biz   string
+	BizId int64
+	Ctime time.Time
+}
+
+type UserStatus uint8
+
+const (
+	// UserStatusActive 零值就是正常状态，老数据不需要迁移
+	UserStatusActive UserStatus = iota
+	// UserStatusBanned 被封禁
+	UserStatusBanned
+	// UserStatusMerged 被合并到别的账号了，只剩下一个墓碑
+	UserStatusMerged
+	// UserStatusDeleted 用户自己注销了，个人信息都被抹掉了，只剩下一个墓碑
+	UserStatusDeleted
+)
+
+func (s UserStatus) ToUint8() uint8 {
+	return uint8(s)
+}
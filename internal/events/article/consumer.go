@@ -0,0 +1,84 @@
+package article
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"webook/internal/events"
+	"webook/internal/repository"
+	"webook/pkg/logger"
+)
+
+// InteractiveReadEventBatchConsumer 批量消费阅读事件，
+// 把同一篇文章的多次阅读合并成一次数据库写入
+type InteractiveReadEventBatchConsumer struct {
+	sub  events.Subscriber
+	repo repository.InteractiveRepository
+	l    logger.LoggerV1
+	// 攒够 batchSize 条消息，或者等了 batchDuration 就提交一次
+	batchSize     int
+	batchDuration time.Duration
+}
+
+func NewInteractiveReadEventBatchConsumer(sub events.Subscriber,
+	repo repository.InteractiveRepository, l logger.LoggerV1) *InteractiveReadEventBatchConsumer {
+	return &InteractiveReadEventBatchConsumer{
+		sub:           sub,
+		repo:          repo,
+		l:             l,
+		batchSize:     100,
+		batchDuration: time.Second,
+	}
+}
+
+func (r *InteractiveReadEventBatchConsumer) Start() error {
+	msgs, err := r.sub.Subscribe(context.Background(), TopicReadEvent, "interactive")
+	if err != nil {
+		return err
+	}
+	go r.consume(msgs)
+	return nil
+}
+
+func (r *InteractiveReadEventBatchConsumer) consume(msgs <-chan events.Message) {
+	for {
+		cnts, closed := r.collect(msgs)
+		if len(cnts) > 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			err := r.repo.BatchIncrReadCnt(ctx, "article", cnts)
+			cancel()
+			if err != nil {
+				// 阅读数丢一点问题不大，记录日志就可以
+				r.l.Error("批量增加阅读计数失败", logger.Field{Key: "cnts", Value: cnts}, logger.Error(err))
+			}
+		}
+		if closed {
+			return
+		}
+	}
+}
+
+// collect 攒一批消息，返回每篇文章的阅读次数，以及 channel 是否已经关闭
+func (r *InteractiveReadEventBatchConsumer) collect(msgs <-chan events.Message) (map[int64]int64, bool) {
+	cnts := make(map[int64]int64)
+	timer := time.NewTimer(r.batchDuration)
+	defer timer.Stop()
+	for i := 0; i < r.batchSize; i++ {
+		select {
+		case <-timer.C:
+			return cnts, false
+		case msg, ok := <-msgs:
+			if !ok {
+				return cnts, true
+			}
+			var evt ReadEvent
+			err := json.Unmarshal(msg.Value, &evt)
+			if err != nil {
+				r.l.Error("反序列化阅读事件失败", logger.String("topic", msg.Topic), logger.Error(err))
+				continue
+			}
+			cnts[evt.Aid]++
+		}
+	}
+	return cnts, false
+}
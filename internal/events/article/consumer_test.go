@@ -0,0 +1,75 @@
+package article
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"webook/internal/events"
+	repomocks "webook/internal/repository/mocks"
+	"webook/pkg/logger"
+)
+
+func TestInteractiveReadEventBatchConsumer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	done := make(chan struct{})
+	repo := repomocks.NewMockInteractiveRepository(ctrl)
+	// 同一篇文章的阅读被合并成一次写入
+	repo.EXPECT().BatchIncrReadCnt(gomock.Any(), "article", map[int64]int64{
+		1: 2,
+		2: 1,
+	}).DoAndReturn(func(ctx context.Context, biz string, cnts map[int64]int64) error {
+		close(done)
+		return nil
+	})
+
+	broker := events.NewMemoryBroker(10, logger.NewNopLogger())
+	consumer := NewInteractiveReadEventBatchConsumer(broker, repo, logger.NewNopLogger())
+	consumer.batchSize = 3
+	require.NoError(t, consumer.Start())
+
+	producer := NewEventProducer(broker)
+	ctx := context.Background()
+	require.NoError(t, producer.ProduceReadEvent(ctx, ReadEvent{Uid: 123, Aid: 1}))
+	require.NoError(t, producer.ProduceReadEvent(ctx, ReadEvent{Uid: 124, Aid: 1}))
+	require.NoError(t, producer.ProduceReadEvent(ctx, ReadEvent{Uid: 123, Aid: 2}))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 3):
+		assert.Fail(t, "没有在预期时间内消费消息")
+	}
+}
+
+func TestInteractiveReadEventBatchConsumer_Timeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	done := make(chan struct{})
+	repo := repomocks.NewMockInteractiveRepository(ctrl)
+	// 没有攒够一批，但是超时了也要提交
+	repo.EXPECT().BatchIncrReadCnt(gomock.Any(), "article", map[int64]int64{
+		1: 1,
+	}).DoAndReturn(func(ctx context.Context, biz string, cnts map[int64]int64) error {
+		close(done)
+		return nil
+	})
+
+	broker := events.NewMemoryBroker(10, logger.NewNopLogger())
+	consumer := NewInteractiveReadEventBatchConsumer(broker, repo, logger.NewNopLogger())
+	consumer.batchDuration = time.Millisecond * 100
+	require.NoError(t, consumer.Start())
+
+	producer := NewEventProducer(broker)
+	require.NoError(t, producer.ProduceReadEvent(context.Background(), ReadEvent{Uid: 123, Aid: 1}))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 3):
+		assert.Fail(t, "没有在预期时间内消费消息")
+	}
+}
@@ -0,0 +1,42 @@
+package article
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"webook/internal/events"
+)
+
+const TopicReadEvent = "article_read"
+
+type ReadEvent struct {
+	Uid int64 `json:"uid"`
+	Aid int64 `json:"aid"`
+}
+
+type Producer interface {
+	ProduceReadEvent(ctx context.Context, evt ReadEvent) error
+}
+
+type EventProducer struct {
+	producer events.Producer
+}
+
+func NewEventProducer(producer events.Producer) Producer {
+	return &EventProducer{
+		producer: producer,
+	}
+}
+
+func (p *EventProducer) ProduceReadEvent(ctx context.Context, evt ReadEvent) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	return p.producer.SendMessage(ctx, events.Message{
+		Topic: TopicReadEvent,
+		// 同一篇文章的消息落到同一个分区
+		Key:   []byte(strconv.FormatInt(evt.Aid, 10)),
+		Value: data,
+	})
+}
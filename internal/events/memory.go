@@ -0,0 +1,60 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"webook/pkg/logger"
+)
+
+// MemoryBroker 基于 channel 的进程内实现，用于本地开发和测试。
+// 进程重启消息就丢了，所以线上环境要换成 Kafka
+type MemoryBroker struct {
+	lock sync.RWMutex
+	// topic => group => channel
+	topics     map[string]map[string]chan Message
+	bufferSize int
+	l          logger.LoggerV1
+}
+
+func NewMemoryBroker(bufferSize int, l logger.LoggerV1) *MemoryBroker {
+	return &MemoryBroker{
+		topics:     make(map[string]map[string]chan Message),
+		bufferSize: bufferSize,
+		l:          l,
+	}
+}
+
+func (b *MemoryBroker) SendMessage(ctx context.Context, msg Message) error {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	// 没有人订阅的消息直接丢弃，和 Kafka 没有消费者组的时候效果差不多
+	for group, ch := range b.topics[msg.Topic] {
+		select {
+		case ch <- msg:
+		default:
+			// 消费者跟不上的时候也丢弃，不能让发送方等着，阅读文章的时候就会发消息
+			b.l.Warn("消费者积压，丢弃消息",
+				logger.String("topic", msg.Topic), logger.String("group", group))
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, topic string, group string) (<-chan Message, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	groups, ok := b.topics[topic]
+	if !ok {
+		groups = make(map[string]chan Message)
+		b.topics[topic] = groups
+	}
+	ch, ok := groups[group]
+	if !ok {
+		ch = make(chan Message, b.bufferSize)
+		groups[group] = ch
+	}
+	return ch, nil
+}
+
+var _ Producer = &MemoryBroker{}
+var _ Subscriber = &MemoryBroker{}
@@ -0,0 +1,34 @@
+package events
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"webook/pkg/logger"
+)
+
+func TestMemoryBroker_SendMessage(t *testing.T) {
+	b := NewMemoryBroker(1, logger.NewNopLogger())
+	ch, err := b.Subscribe(context.Background(), "read_article", "interactive")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	err = b.SendMessage(ctx, Message{Topic: "read_article", Value: []byte("1")})
+	require.NoError(t, err)
+	// 缓冲满了，没有人消费，也不能阻塞
+	err = b.SendMessage(ctx, Message{Topic: "read_article", Value: []byte("2")})
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Millisecond*100)
+
+	msg := <-ch
+	assert.Equal(t, []byte("1"), msg.Value)
+	select {
+	case msg = <-ch:
+		t.Fatalf("第二条消息应该被丢弃了：%s", msg.Value)
+	default:
+	}
+}
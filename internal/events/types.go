@@ -0,0 +1,25 @@
+package events
+
+import "context"
+
+// Message 和 Kafka 的消息结构保持一致，后面切换成 Kafka 实现的时候上层代码不需要修改
+type Message struct {
+	Topic string
+	Key   []byte
+	Value []byte
+}
+
+type Producer interface {
+	SendMessage(ctx context.Context, msg Message) error
+}
+
+// Subscriber 订阅 topic。和 Kafka 的消费者组语义一样，
+// 不同的 group 都会收到全部消息，同一个 group 内的消费者瓜分消息
+type Subscriber interface {
+	Subscribe(ctx context.Context, topic string, group string) (<-chan Message, error)
+}
+
+// Consumer 启动之后在后台消费消息
+type Consumer interface {
+	Start() error
+}
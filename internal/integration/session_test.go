@@ -0,0 +1,66 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"webook/internal/integration/startup"
+	ijwt "webook/internal/web/jwt"
+)
+
+// TestRevokeAllSessionsAfterRotate 登录之后一直刷新，超过登录时设置的过期时间，
+// 退出所有设备的时候也要能踢掉这个会话
+func TestRevokeAllSessionsAfterRotate(t *testing.T) {
+	rdb := startup.InitRedis()
+	hdl := startup.InitJWTHandler(rdb)
+	const uid int64 = 99901
+	sessionsKey := fmt.Sprintf("users:sessions:%d", uid)
+	newCtx := func() (*gin.Context, *httptest.ResponseRecorder) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/users/refresh_token", nil)
+		return ctx, recorder
+	}
+
+	ctx, recorder := newCtx()
+	err := hdl.SetLoginToken(ctx, uid, nil, ijwt.LoginMethodPassword)
+	require.NoError(t, err)
+	rc, err := hdl.VerifyRefreshToken(recorder.Header().Get("x-refresh-token"))
+	require.NoError(t, err)
+	refreshKey := "users:refresh:" + rc.Ssid
+	defer func() {
+		c, cancel := context.WithTimeout(context.Background(), time.Second*3)
+		defer cancel()
+		rdb.Del(c, sessionsKey, refreshKey, "users:ssid:"+rc.Ssid)
+	}()
+
+	// 模拟登录时设置的过期时间马上就要到了
+	err = rdb.Expire(context.Background(), sessionsKey, time.Second).Err()
+	require.NoError(t, err)
+	ctx, recorder = newCtx()
+	err = hdl.RotateRefreshToken(ctx, rc, nil)
+	require.NoError(t, err)
+	rc, err = hdl.VerifyRefreshToken(recorder.Header().Get("x-refresh-token"))
+	require.NoError(t, err)
+
+	time.Sleep(time.Second * 2)
+	cnt, err := rdb.Exists(context.Background(), sessionsKey).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), cnt)
+
+	ctx, _ = newCtx()
+	err = hdl.RevokeAllSessions(ctx, uid)
+	require.NoError(t, err)
+	cnt, err = rdb.Exists(context.Background(), refreshKey).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), cnt)
+	ctx, _ = newCtx()
+	err = hdl.RotateRefreshToken(ctx, rc, nil)
+	assert.Equal(t, ijwt.ErrRefreshTokenRevoked, err)
+}
@@ -0,0 +1,19 @@
+package startup
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	ijwt "webook/internal/web/jwt"
+)
+
+func InitJWTHandler(cmd redis.Cmdable) ijwt.Handler {
+	accessKeys, err := ijwt.NewKeyRing(ijwt.Key{Id: "access-test", Secret: "cgWrzQrzH2tfJngYC59iuqh3Dix246FX"})
+	if err != nil {
+		panic(err)
+	}
+	refreshKeys, err := ijwt.NewKeyRing(ijwt.Key{Id: "refresh-test", Secret: "cgWrzQrzH2tfJngYC59iuqh3Dix246FR"})
+	if err != nil {
+		panic(err)
+	}
+	return ijwt.NewRedisJWTHandler(cmd, jwt.SigningMethodHS512, accessKeys, refreshKeys)
+}
@@ -5,43 +5,74 @@ package startup
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
+	"webook/internal/events"
+	"webook/internal/events/article"
 	"webook/internal/repository"
 	"webook/internal/repository/cache"
 	"webook/internal/repository/dao"
 	"webook/internal/service"
 	"webook/internal/web"
-	ijwt "webook/internal/web/jwt"
 	"webook/ioc"
 )
 
 var thirdPartySet = wire.NewSet(InitDB, InitRedis,
 	InitLogger)
 
+var eventsSet = wire.NewSet(
+	ioc.InitMemoryBroker,
+	wire.Bind(new(events.Producer), new(*events.MemoryBroker)),
+	article.NewEventProducer,
+)
+
 func InitWebServer() *gin.Engine {
 	wire.Build(
 		// 第三方依赖
 		thirdPartySet,
+		eventsSet,
 		// Dao 部分
 		dao.NewUserDAO,
+		dao.NewGORMUserMergeDAO,
+		dao.NewGORMUserAccountDAO,
+		dao.NewGORMUserTOTPDAO,
 		dao.NewArticleGORMDAO,
+		dao.NewGORMInteractiveDAO,
 		// cache 部分
 		cache.NewCodeCache, cache.NewUserCache,
+		cache.NewInteractiveRedisCache,
+		cache.NewLoginAttemptCache,
+		cache.NewRankingRedisCache, cache.NewRankingLocalCache,
 
 		// Repository 部分
 		repository.NewCachedUserRepository, repository.NewCodeRepository, repository.NewCachedArticleRepository,
+		repository.NewCachedUserMergeRepository,
+		repository.NewCachedUserAccountRepository,
+		repository.NewGORMUserTOTPRepository,
+		repository.NewLoginAttemptRepository,
+		repository.NewCachedInteractiveRepository,
+		repository.NewCachedRankingRepository,
 
 		// Service 部分
 		ioc.InitSMSService,
+		ioc.InitEmailService,
 		ioc.InitWechatService,
 		service.NewUserService,
+		service.NewUserMergeService,
+		service.NewUserAccountService,
+		service.NewTOTPService,
+		service.NewLoginAttemptService,
 		service.NewCodeService,
+		service.NewEmailCodeService,
 		service.NewArticleService,
+		service.NewInteractiveService,
+		service.NewBatchRankingService,
 
 		// Handler 部分
 		web.NewUserHandler,
 		web.NewOAuth2WechatHandler,
-		ijwt.NewRedisJWTHandler,
+		InitJWTHandler,
 		web.NewArticleHandler,
+		web.NewJWKSHandler,
+		web.NewAdminHandler,
 
 		ioc.InitGinMiddlewares,
 		ioc.InitWebServer,
@@ -52,9 +83,39 @@ func InitWebServer() *gin.Engine {
 func InitArticleHandler() *web.ArticleHandler {
 	wire.Build(
 		thirdPartySet,
+		eventsSet,
 		dao.NewArticleGORMDAO,
+		dao.NewGORMInteractiveDAO,
+		cache.NewInteractiveRedisCache,
+		cache.NewRankingRedisCache, cache.NewRankingLocalCache,
 		repository.NewCachedArticleRepository,
+		repository.NewCachedInteractiveRepository,
+		repository.NewCachedRankingRepository,
 		web.NewArticleHandler,
-		service.NewArticleService)
+		service.NewArticleService,
+		service.NewInteractiveService,
+		service.NewBatchRankingService)
 	return &web.ArticleHandler{}
 }
+
+func InitUserHandler() *web.UserHandler {
+	wire.Build(
+		thirdPartySet,
+		dao.NewUserDAO, dao.NewGORMUserAccountDAO, dao.NewGORMUserTOTPDAO,
+		cache.NewUserCache, cache.NewCodeCache, cache.NewLoginAttemptCache,
+		repository.NewCachedUserRepository, repository.NewCodeRepository,
+		repository.NewCachedUserAccountRepository,
+		repository.NewGORMUserTOTPRepository,
+		repository.NewLoginAttemptRepository,
+		ioc.InitSMSService,
+		ioc.InitEmailService,
+		service.NewUserService,
+		service.NewCodeService,
+		service.NewEmailCodeService,
+		service.NewUserAccountService,
+		service.NewTOTPService,
+		service.NewLoginAttemptService,
+		InitJWTHandler,
+		web.NewUserHandler)
+	return &web.UserHandler{}
+}
@@ -9,12 +9,13 @@ package startup
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
+	"webook/internal/events"
+	"webook/internal/events/article"
 	"webook/internal/repository"
 	"webook/internal/repository/cache"
 	"webook/internal/repository/dao"
 	"webook/internal/service"
 	"webook/internal/web"
-	"webook/internal/web/jwt"
 	"webook/ioc"
 )
 
@@ -22,9 +23,8 @@ import (
 
 func InitWebServer() *gin.Engine {
 	cmdable := InitRedis()
-	handler := jwt.NewRedisJWTHandler(cmdable)
+	handler := InitJWTHandler(cmdable)
 	loggerV1 := InitLogger()
-	v := ioc.InitGinMiddlewares(cmdable, handler, loggerV1)
 	db := InitDB()
 	userDAO := dao.NewUserDAO(db)
 	userCache := cache.NewUserCache(cmdable)
@@ -34,28 +34,95 @@ func InitWebServer() *gin.Engine {
 	codeRepository := repository.NewCodeRepository(codeRedisCache)
 	smsService := ioc.InitSMSService()
 	codeService := service.NewCodeService(codeRepository, smsService)
-	userHandler := web.NewUserHandler(userService, handler, codeService)
+	emailService := ioc.InitEmailService()
+	emailCodeService := service.NewEmailCodeService(codeRepository, emailService)
+	userAccountDAO := dao.NewGORMUserAccountDAO(db)
+	userAccountRepository := repository.NewCachedUserAccountRepository(userAccountDAO, userCache)
+	userAccountService := service.NewUserAccountService(userAccountRepository)
+	userTOTPDAO := dao.NewGORMUserTOTPDAO(db)
+	userTOTPRepository := repository.NewGORMUserTOTPRepository(userTOTPDAO)
+	totpService := service.NewTOTPService(userTOTPRepository, userRepository)
+	loginAttemptCache := cache.NewLoginAttemptCache(cmdable)
+	loginAttemptRepository := repository.NewLoginAttemptRepository(loginAttemptCache)
+	loginAttemptService := service.NewLoginAttemptService(loginAttemptRepository)
+	userHandler := web.NewUserHandler(userService, handler, codeService, emailCodeService, userAccountService, totpService, loginAttemptService)
 	articleDAO := dao.NewArticleGORMDAO(db)
-	articleRepository := repository.NewCachedArticleRepository(articleDAO)
+	articleRepository := repository.NewCachedArticleRepository(articleDAO, db)
 	articleService := service.NewArticleService(articleRepository)
-	articleHandler := web.NewArticleHandler(loggerV1, articleService)
+	interactiveDAO := dao.NewGORMInteractiveDAO(db)
+	interactiveCache := cache.NewInteractiveRedisCache(cmdable)
+	interactiveRepository := repository.NewCachedInteractiveRepository(interactiveDAO, interactiveCache, loggerV1)
+	interactiveService := service.NewInteractiveService(interactiveRepository)
+	rankingRedisCache := cache.NewRankingRedisCache(cmdable)
+	rankingLocalCache := cache.NewRankingLocalCache()
+	rankingRepository := repository.NewCachedRankingRepository(rankingRedisCache, rankingLocalCache)
+	rankingService := service.NewBatchRankingService(articleService, interactiveService, rankingRepository)
+	memoryBroker := ioc.InitMemoryBroker(loggerV1)
+	producer := article.NewEventProducer(memoryBroker)
+	articleHandler := web.NewArticleHandler(loggerV1, articleService, interactiveService, rankingService, producer)
 	wechatService := ioc.InitWechatService(loggerV1)
 	oAuth2WechatHandler := web.NewOAuth2WechatHandler(wechatService, handler, userService)
-	engine := ioc.InitWebServer(v, userHandler, articleHandler, oAuth2WechatHandler)
+	jwksHandler := web.NewJWKSHandler(handler)
+	userMergeDAO := dao.NewGORMUserMergeDAO(db)
+	userMergeRepository := repository.NewCachedUserMergeRepository(userMergeDAO, userCache, interactiveCache)
+	userMergeService := service.NewUserMergeService(userMergeRepository)
+	adminHandler := web.NewAdminHandler(userService, articleService, userMergeService, handler, loggerV1)
+	v := ioc.InitGinMiddlewares(cmdable, handler, loggerV1, userService, userHandler, articleHandler, oAuth2WechatHandler, jwksHandler)
+	engine := ioc.InitWebServer(v, userHandler, articleHandler, oAuth2WechatHandler, jwksHandler, adminHandler)
 	return engine
 }
 
 func InitArticleHandler() *web.ArticleHandler {
 	loggerV1 := InitLogger()
 	db := InitDB()
+	cmdable := InitRedis()
 	articleDAO := dao.NewArticleGORMDAO(db)
-	articleRepository := repository.NewCachedArticleRepository(articleDAO)
+	articleRepository := repository.NewCachedArticleRepository(articleDAO, db)
 	articleService := service.NewArticleService(articleRepository)
-	articleHandler := web.NewArticleHandler(loggerV1, articleService)
+	interactiveDAO := dao.NewGORMInteractiveDAO(db)
+	interactiveCache := cache.NewInteractiveRedisCache(cmdable)
+	interactiveRepository := repository.NewCachedInteractiveRepository(interactiveDAO, interactiveCache, loggerV1)
+	interactiveService := service.NewInteractiveService(interactiveRepository)
+	rankingRedisCache := cache.NewRankingRedisCache(cmdable)
+	rankingLocalCache := cache.NewRankingLocalCache()
+	rankingRepository := repository.NewCachedRankingRepository(rankingRedisCache, rankingLocalCache)
+	rankingService := service.NewBatchRankingService(articleService, interactiveService, rankingRepository)
+	memoryBroker := ioc.InitMemoryBroker(loggerV1)
+	producer := article.NewEventProducer(memoryBroker)
+	articleHandler := web.NewArticleHandler(loggerV1, articleService, interactiveService, rankingService, producer)
 	return articleHandler
 }
 
+func InitUserHandler() *web.UserHandler {
+	db := InitDB()
+	userDAO := dao.NewUserDAO(db)
+	cmdable := InitRedis()
+	userCache := cache.NewUserCache(cmdable)
+	userRepository := repository.NewCachedUserRepository(userDAO, userCache)
+	userService := service.NewUserService(userRepository)
+	handler := InitJWTHandler(cmdable)
+	codeRedisCache := cache.NewCodeCache(cmdable)
+	codeRepository := repository.NewCodeRepository(codeRedisCache)
+	smsService := ioc.InitSMSService()
+	codeService := service.NewCodeService(codeRepository, smsService)
+	emailService := ioc.InitEmailService()
+	emailCodeService := service.NewEmailCodeService(codeRepository, emailService)
+	userAccountDAO := dao.NewGORMUserAccountDAO(db)
+	userAccountRepository := repository.NewCachedUserAccountRepository(userAccountDAO, userCache)
+	userAccountService := service.NewUserAccountService(userAccountRepository)
+	userTOTPDAO := dao.NewGORMUserTOTPDAO(db)
+	userTOTPRepository := repository.NewGORMUserTOTPRepository(userTOTPDAO)
+	totpService := service.NewTOTPService(userTOTPRepository, userRepository)
+	loginAttemptCache := cache.NewLoginAttemptCache(cmdable)
+	loginAttemptRepository := repository.NewLoginAttemptRepository(loginAttemptCache)
+	loginAttemptService := service.NewLoginAttemptService(loginAttemptRepository)
+	userHandler := web.NewUserHandler(userService, handler, codeService, emailCodeService, userAccountService, totpService, loginAttemptService)
+	return userHandler
+}
+
 // wire.go:
 
 var thirdPartySet = wire.NewSet(InitDB, InitRedis,
 	InitLogger)
+
+var eventsSet = wire.NewSet(ioc.InitMemoryBroker, wire.Bind(new(events.Producer), new(*events.MemoryBroker)), article.NewEventProducer)
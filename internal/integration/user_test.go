@@ -6,16 +6,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/fsnotify/fsnotify"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 	_ "github.com/spf13/viper/remote"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 	"webook/internal/integration/startup"
+	"webook/internal/repository/dao"
 	"webook/internal/web"
+	ijwt "webook/internal/web/jwt"
 )
 
 func initViperRemote() {
@@ -181,3 +187,140 @@ func TestUserHandler_SendSMSCode(t *testing.T) {
 		})
 	}
 }
+
+type UserProfileSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	rdb    redis.Cmdable
+	server *gin.Engine
+}
+
+func (s *UserProfileSuite) SetupSuite() {
+	s.db = startup.InitDB()
+	s.rdb = startup.InitRedis()
+	server := gin.Default()
+	hdl := startup.InitUserHandler()
+	server.Use(func(ctx *gin.Context) {
+		ctx.Set("user", ijwt.UserClaims{
+			Uid: 123,
+		})
+	})
+	hdl.RegisterRoutes(server)
+	s.server = server
+}
+
+func (s *UserProfileSuite) SetupTest() {
+	err := s.db.Create(&dao.User{
+		Id:       123,
+		NickName: "旧昵称",
+		Birthday: time.Date(2000, 1, 1, 0, 0, 0, 0, time.Local).Unix(),
+		AboutMe:  "旧的自我介绍",
+		Ctime:    456,
+		Utime:    789,
+	}).Error
+	assert.NoError(s.T(), err)
+}
+
+func (s *UserProfileSuite) TearDownTest() {
+	s.db.Exec("truncate table `users`")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s.rdb.Del(ctx, "user:info:123")
+}
+
+func (s *UserProfileSuite) TestEditThenProfile() {
+	t := s.T()
+	testCase := []struct {
+		name string
+		// 编辑之前先访问多少次 profile，用来预热缓存
+		warmUp int
+		// 编辑之后等待多久再访问 profile
+		wait time.Duration
+		req  string
+
+		wantProfile map[string]any
+	}{
+		{
+			name:   "缓存已经存在，编辑后立刻可见",
+			warmUp: 1,
+			req:    `{"nickname":"新昵称","birthday":"2001-02-03","about_me":"新的自我介绍"}`,
+			wantProfile: map[string]any{
+				"Id":       float64(123),
+				"Email":    "",
+				"NickName": "新昵称",
+				"Birthday": "2001-02-03",
+				"AboutMe":  "新的自我介绍",
+			},
+		},
+		{
+			name: "缓存不存在，编辑后立刻可见",
+			req:  `{"nickname":"新昵称","birthday":"2001-02-03","about_me":"新的自我介绍"}`,
+			wantProfile: map[string]any{
+				"Id":       float64(123),
+				"Email":    "",
+				"NickName": "新昵称",
+				"Birthday": "2001-02-03",
+				"AboutMe":  "新的自我介绍",
+			},
+		},
+		{
+			name:   "延迟双删之后依旧是新数据",
+			warmUp: 1,
+			wait:   time.Second * 2,
+			req:    `{"nickname":"新昵称","birthday":"2001-02-03","about_me":"新的自我介绍"}`,
+			wantProfile: map[string]any{
+				"Id":       float64(123),
+				"Email":    "",
+				"NickName": "新昵称",
+				"Birthday": "2001-02-03",
+				"AboutMe":  "新的自我介绍",
+			},
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			s.TearDownTest()
+			s.SetupTest()
+			for i := 0; i < tc.warmUp; i++ {
+				s.profile(t)
+			}
+
+			req, err := http.NewRequest(http.MethodPost, "/users/edit", bytes.NewReader([]byte(tc.req)))
+			req.Header.Set("Content-Type", "application/json")
+			assert.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			s.server.ServeHTTP(recorder, req)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "更新成功", recorder.Body.String())
+
+			// 编辑之后缓存就应该被删除了
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			cnt, err := s.rdb.Exists(ctx, "user:info:123").Result()
+			assert.NoError(t, err)
+			assert.Equal(t, int64(0), cnt)
+
+			time.Sleep(tc.wait)
+			assert.Equal(t, tc.wantProfile, s.profile(t))
+			// 再来一次，这一次是从缓存里面读的
+			assert.Equal(t, tc.wantProfile, s.profile(t))
+		})
+	}
+}
+
+func (s *UserProfileSuite) profile(t *testing.T) map[string]any {
+	req, err := http.NewRequest(http.MethodGet, "/users/profile", nil)
+	assert.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	s.server.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var res map[string]any
+	err = json.NewDecoder(recorder.Body).Decode(&res)
+	assert.NoError(t, err)
+	return res
+}
+
+func TestUserProfile(t *testing.T) {
+	suite.Run(t, &UserProfileSuite{})
+}
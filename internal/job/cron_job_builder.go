@@ -0,0 +1,38 @@
+package job
+
+import (
+	"github.com/robfig/cron/v3"
+	"time"
+	"webook/pkg/logger"
+)
+
+// CronJobBuilder 把 Job 适配成 cron.Job，顺便记录日志
+type CronJobBuilder struct {
+	l logger.LoggerV1
+}
+
+func NewCronJobBuilder(l logger.LoggerV1) *CronJobBuilder {
+	return &CronJobBuilder{
+		l: l,
+	}
+}
+
+func (b *CronJobBuilder) Build(job Job) cron.Job {
+	name := job.Name()
+	return cronJobFuncAdapter(func() {
+		start := time.Now()
+		b.l.Debug("任务开始", logger.String("job", name))
+		err := job.Run()
+		if err != nil {
+			b.l.Error("任务执行失败", logger.String("job", name), logger.Error(err))
+		}
+		b.l.Debug("任务结束", logger.String("job", name),
+			logger.Field{Key: "duration", Value: time.Since(start)})
+	})
+}
+
+type cronJobFuncAdapter func()
+
+func (c cronJobFuncAdapter) Run() {
+	c()
+}
@@ -0,0 +1,40 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"webook/internal/domain"
+)
+
+// Executor 执行抢占到的任务，domain.CronJob.Executor 决定用哪个
+type Executor interface {
+	Name() string
+	Exec(ctx context.Context, j domain.CronJob) error
+}
+
+// LocalFuncExecutor 在本地调用注册好的方法，按照任务名字找方法
+type LocalFuncExecutor struct {
+	funcs map[string]func(ctx context.Context, j domain.CronJob) error
+}
+
+func NewLocalFuncExecutor() *LocalFuncExecutor {
+	return &LocalFuncExecutor{
+		funcs: make(map[string]func(ctx context.Context, j domain.CronJob) error),
+	}
+}
+
+func (l *LocalFuncExecutor) Name() string {
+	return "local"
+}
+
+func (l *LocalFuncExecutor) RegisterFunc(name string, fn func(ctx context.Context, j domain.CronJob) error) {
+	l.funcs[name] = fn
+}
+
+func (l *LocalFuncExecutor) Exec(ctx context.Context, j domain.CronJob) error {
+	fn, ok := l.funcs[j.Name]
+	if !ok {
+		return fmt.Errorf("未注册本地方法 %s", j.Name)
+	}
+	return fn(ctx, j)
+}
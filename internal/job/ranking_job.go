@@ -0,0 +1,94 @@
+package job
+
+import (
+	"context"
+	"sync"
+	"time"
+	"webook/internal/service"
+	"webook/pkg/logger"
+	"webook/pkg/rlock"
+)
+
+// RankingJob 计算热榜。多个实例同时部署的时候，
+// 只有拿到分布式锁的那个实例会计算
+type RankingJob struct {
+	svc     service.RankingService
+	l       logger.LoggerV1
+	timeout time.Duration
+	client  *rlock.Client
+	key     string
+
+	localLock *sync.Mutex
+	lock      *rlock.Lock
+}
+
+func NewRankingJob(svc service.RankingService, client *rlock.Client,
+	l logger.LoggerV1, timeout time.Duration) *RankingJob {
+	return &RankingJob{
+		svc:       svc,
+		l:         l,
+		timeout:   timeout,
+		client:    client,
+		key:       "job:ranking",
+		localLock: &sync.Mutex{},
+	}
+}
+
+func (r *RankingJob) Name() string {
+	return "ranking"
+}
+
+func (r *RankingJob) Run() error {
+	r.localLock.Lock()
+	lock := r.lock
+	if lock == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		// 锁的过期时间和任务超时时间一样，拿到锁之后一直续约，
+		// 只要实例不挂，就一直是这个实例在计算
+		var err error
+		lock, err = r.client.TryLock(ctx, r.key, r.timeout)
+		if err != nil {
+			r.localLock.Unlock()
+			// 别人拿着锁，不是错误
+			if err == rlock.ErrFailedToPreemptLock {
+				return nil
+			}
+			return err
+		}
+		r.lock = lock
+		r.localLock.Unlock()
+		go func() {
+			er := lock.AutoRefresh(r.timeout/2, time.Second)
+			if er != nil {
+				// 续约失败，下一次执行的时候重新抢锁
+				r.l.Error("续约分布式锁失败", logger.String("key", r.key), logger.Error(er))
+			}
+			r.localLock.Lock()
+			if r.lock == lock {
+				r.lock = nil
+			}
+			r.localLock.Unlock()
+		}()
+	} else {
+		r.localLock.Unlock()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	return r.svc.TopN(ctx)
+}
+
+// Close 释放分布式锁，让别的实例能够马上接手
+func (r *RankingJob) Close() error {
+	r.localLock.Lock()
+	lock := r.lock
+	r.lock = nil
+	r.localLock.Unlock()
+	if lock == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return lock.Unlock(ctx)
+}
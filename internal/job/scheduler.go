@@ -0,0 +1,101 @@
+package job
+
+import (
+	"context"
+	"golang.org/x/sync/semaphore"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/service"
+	"webook/pkg/logger"
+)
+
+// Scheduler 从数据库里面抢占任务来执行，多个实例可以同时运行
+type Scheduler struct {
+	execs   map[string]Executor
+	svc     service.CronJobService
+	l       logger.LoggerV1
+	limiter *semaphore.Weighted
+	// 没有任务可以抢占的时候，隔多久再试
+	interval  time.Duration
+	dbTimeout time.Duration
+	// 单个任务执行的超时时间
+	jobTimeout time.Duration
+}
+
+func NewScheduler(svc service.CronJobService, l logger.LoggerV1) *Scheduler {
+	return &Scheduler{
+		execs: make(map[string]Executor),
+		svc:   svc,
+		l:     l,
+		// 一个实例最多同时执行 100 个任务
+		limiter:    semaphore.NewWeighted(100),
+		interval:   time.Second,
+		dbTimeout:  time.Second,
+		jobTimeout: time.Minute,
+	}
+}
+
+func (s *Scheduler) RegisterExecutor(exec Executor) {
+	s.execs[exec.Name()] = exec
+}
+
+// Schedule 一直调度，直到 ctx 被取消
+func (s *Scheduler) Schedule(ctx context.Context) error {
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err := s.limiter.Acquire(ctx, 1)
+		if err != nil {
+			return err
+		}
+		dbCtx, cancel := context.WithTimeout(ctx, s.dbTimeout)
+		j, err := s.svc.Preempt(dbCtx)
+		cancel()
+		if err != nil {
+			s.limiter.Release(1)
+			if err != service.ErrNoJobToPreempt {
+				s.l.Error("抢占任务失败", logger.Error(err))
+			}
+			select {
+			case <-time.After(s.interval):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+		exec, ok := s.execs[j.Executor]
+		if !ok {
+			// 跳过这一次，不然马上又会抢到这个任务
+			s.l.Error("找不到执行器", logger.Int64("jid", j.Id), logger.String("executor", j.Executor))
+			s.finish(j)
+			continue
+		}
+		go s.run(exec, j)
+	}
+}
+
+func (s *Scheduler) run(exec Executor, j domain.CronJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.jobTimeout)
+	err := exec.Exec(ctx, j)
+	cancel()
+	if err != nil {
+		s.l.Error("执行任务失败", logger.Int64("jid", j.Id), logger.String("name", j.Name), logger.Error(err))
+	}
+	// 不管成功失败都要计算下一次执行时间
+	s.finish(j)
+}
+
+// finish 计算下一次执行时间，然后释放任务
+func (s *Scheduler) finish(j domain.CronJob) {
+	defer func() {
+		s.limiter.Release(1)
+		j.CancelFunc()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), s.dbTimeout)
+	defer cancel()
+	err := s.svc.ResetNextTime(ctx, j)
+	if err != nil {
+		s.l.Error("设置下一次执行时间失败", logger.Int64("jid", j.Id), logger.Error(err))
+	}
+}
@@ -0,0 +1,59 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/service"
+	svcmocks "webook/internal/service/mocks"
+	"webook/pkg/logger"
+)
+
+func TestScheduler_Schedule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	released := make(chan struct{})
+	svc := svcmocks.NewMockCronJobService(ctrl)
+	j := domain.CronJob{
+		Id:         1,
+		Name:       "test_job",
+		Executor:   "local",
+		Expression: "*/5 * * * * ?",
+		CancelFunc: func() {
+			close(released)
+		},
+	}
+	svc.EXPECT().Preempt(gomock.Any()).Return(j, nil)
+	// 后面就没有任务可以抢了
+	svc.EXPECT().Preempt(gomock.Any()).Return(domain.CronJob{}, service.ErrNoJobToPreempt).AnyTimes()
+	svc.EXPECT().ResetNextTime(gomock.Any(), gomock.Any()).Return(nil)
+
+	var executed domain.CronJob
+	local := NewLocalFuncExecutor()
+	local.RegisterFunc("test_job", func(ctx context.Context, j domain.CronJob) error {
+		executed = j
+		return errors.New("执行失败也要计算下一次执行时间")
+	})
+
+	scheduler := NewScheduler(svc, logger.NewNopLogger())
+	scheduler.interval = time.Millisecond * 10
+	scheduler.RegisterExecutor(local)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-released:
+		case <-time.After(time.Second * 3):
+			assert.Fail(t, "任务没有被释放")
+		}
+		cancel()
+	}()
+	err := scheduler.Schedule(ctx)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, int64(1), executed.Id)
+}
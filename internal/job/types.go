@@ -0,0 +1,6 @@
+package job
+
+type Job interface {
+	Name() string
+	Run() error
+}
@@ -2,15 +2,25 @@ package repository
 
 import (
 	"context"
+	"github.com/ecodeclub/ekit/slice"
 	"gorm.io/gorm"
+	"time"
 	"webook/internal/domain"
 	"webook/internal/repository/dao"
 )
 
+var ErrArticleNotFound = dao.ErrRecordNotFound
+
 type ArticleRepository interface {
 	Create(ctx context.Context, art domain.Article) (int64, error)
 	Update(ctx context.Context, art domain.Article) error
 	Sync(ctx context.Context, art domain.Article) (int64, error)
+	GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error)
+	GetById(ctx context.Context, id int64) (domain.Article, error)
+	GetPubById(ctx context.Context, id int64) (domain.Article, error)
+	SyncStatus(ctx context.Context, uid int64, id int64, status domain.ArticleStatus) error
+	UpdateStatusById(ctx context.Context, id int64, status domain.ArticleStatus) error
+	ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]domain.Article, error)
 }
 
 type CachedArticleRepository struct {
@@ -20,9 +30,59 @@ type CachedArticleRepository struct {
 	db        *gorm.DB
 }
 
+func (c *CachedArticleRepository) GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error) {
+	arts, err := c.dao.GetByAuthor(ctx, uid, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.Article, domain.Article](arts, func(idx int, src dao.Article) domain.Article {
+		return articleToDomain(src)
+	}), nil
+}
+
+func (c *CachedArticleRepository) GetById(ctx context.Context, id int64) (domain.Article, error) {
+	art, err := c.dao.GetById(ctx, id)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	return articleToDomain(art), nil
+}
+
+func (c *CachedArticleRepository) GetPubById(ctx context.Context, id int64) (domain.Article, error) {
+	art, err := c.dao.GetPubById(ctx, id)
+	if err != nil {
+		return domain.Article{}, err
+	}
+	res := articleToDomain(dao.Article(art))
+	// 撤回了或者仅自己可见的文章，对读者来说就是不存在
+	if res.Status != domain.ArticleStatusPublished {
+		return domain.Article{}, ErrArticleNotFound
+	}
+	return res, nil
+}
+
+// ListPub 只会查询已发表的文章
+func (c *CachedArticleRepository) ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]domain.Article, error) {
+	arts, err := c.dao.ListPub(ctx, start, domain.ArticleStatusPublished.ToUint8(), offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.PublishedArticle, domain.Article](arts, func(idx int, src dao.PublishedArticle) domain.Article {
+		return articleToDomain(dao.Article(src))
+	}), nil
+}
+
+func (c *CachedArticleRepository) SyncStatus(ctx context.Context, uid int64, id int64, status domain.ArticleStatus) error {
+	return c.dao.SyncStatus(ctx, uid, id, status.ToUint8())
+}
+
+func (c *CachedArticleRepository) UpdateStatusById(ctx context.Context, id int64, status domain.ArticleStatus) error {
+	return c.dao.UpdateStatusById(ctx, id, status.ToUint8())
+}
+
+// Sync 在同一个事务里面同时写制作库和线上库，要么都成功，要么都失败
 func (c *CachedArticleRepository) Sync(ctx context.Context, art domain.Article) (int64, error) {
-	//TODO implement me
-	panic("implement me")
+	return c.SyncV2(ctx, art)
 }
 
 func (c *CachedArticleRepository) SyncV2(ctx context.Context, art domain.Article) (int64, error) {
@@ -31,6 +91,7 @@ func (c *CachedArticleRepository) SyncV2(ctx context.Context, art domain.Article
 		return 0, matters.Error
 	}
 
+	// 提交之后再 Rollback 不会有影响
 	defer matters.Rollback()
 	authorDao := dao.NewArticleGORMAuthorDAO(matters)
 	readerDao := dao.NewArticleReaderGORMDAO(matters)
@@ -53,9 +114,7 @@ func (c *CachedArticleRepository) SyncV2(ctx context.Context, art domain.Article
 	if err != nil {
 		return 0, err
 	}
-	matters.Commit()
-	return id, nil
-
+	return id, matters.Commit().Error
 }
 
 func (c *CachedArticleRepository) SyncV1(ctx context.Context, art domain.Article) (int64, error) {
@@ -77,8 +136,11 @@ func (c *CachedArticleRepository) SyncV1(ctx context.Context, art domain.Article
 	return id, err
 }
 
-func NewCachedArticleRepository(dao dao.ArticleDAO) ArticleRepository {
-	return &CachedArticleRepository{dao: dao}
+func NewCachedArticleRepository(dao dao.ArticleDAO, db *gorm.DB) ArticleRepository {
+	return &CachedArticleRepository{
+		dao: dao,
+		db:  db,
+	}
 }
 
 func NewCachedArticleRepositoryV2(readerDao dao.ArticleReaderDAO, authorDao dao.ArticleAuthorDAO) *CachedArticleRepository {
@@ -102,5 +164,21 @@ func (c *CachedArticleRepository) toEntity(art domain.Article) dao.Article {
 		Title:    art.Title,
 		Content:  art.Content,
 		AuthorId: art.Author.Id,
+		Status:   art.Status.ToUint8(),
+	}
+}
+
+// articleToDomain 导出个人数据的时候也要用
+func articleToDomain(art dao.Article) domain.Article {
+	return domain.Article{
+		Id:      art.Id,
+		Title:   art.Title,
+		Content: art.Content,
+		Author: domain.Author{
+			Id: art.AuthorId,
+		},
+		Status: domain.ArticleStatus(art.Status),
+		Ctime:  time.UnixMilli(art.Ctime),
+		Utime:  time.UnixMilli(art.Utime),
 	}
 }
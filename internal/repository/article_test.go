@@ -2,8 +2,13 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
 	"testing"
 	"webook/internal/domain"
 	"webook/internal/repository/dao"
@@ -89,3 +94,93 @@ func TestCachedArticleRepository_SyncV1(t *testing.T) {
 		})
 	}
 }
+
+func TestCachedArticleRepository_Sync(t *testing.T) {
+	testCase := []struct {
+		name    string
+		mock    func(t *testing.T) *sql.DB
+		art     domain.Article
+		wantId  int64
+		wantErr error
+	}{
+		{
+			name: "新建并同步成功",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO `articles` .*").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO `published_articles` .*").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+				return db
+			},
+			art: domain.Article{
+				Title:   "新标题",
+				Content: "新内容",
+				Author:  domain.Author{Id: 123},
+			},
+			wantId: 1,
+		},
+		{
+			name: "修改并同步成功",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE `articles` .*").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO `published_articles` .*").
+					WillReturnResult(sqlmock.NewResult(11, 1))
+				mock.ExpectCommit()
+				return db
+			},
+			art: domain.Article{
+				Id:      11,
+				Title:   "新标题",
+				Content: "新内容",
+				Author:  domain.Author{Id: 123},
+			},
+			wantId: 11,
+		},
+		{
+			name: "同步线上库失败，回滚",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE `articles` .*").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO `published_articles` .*").
+					WillReturnError(errors.New("mock db error"))
+				mock.ExpectRollback()
+				return db
+			},
+			art: domain.Article{
+				Id:      11,
+				Title:   "新标题",
+				Content: "新内容",
+				Author:  domain.Author{Id: 123},
+			},
+			wantErr: errors.New("mock db error"),
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlDB := tc.mock(t)
+			db, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      sqlDB,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			assert.NoError(t, err)
+			repo := NewCachedArticleRepository(nil, db)
+			id, err := repo.Sync(context.Background(), tc.art)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantId, id)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+	"webook/internal/domain"
+)
+
+var (
+	//go:embed lua/incr_cnt.lua
+	luaIncrCnt string
+)
+
+const (
+	fieldReadCnt    = "read_cnt"
+	fieldLikeCnt    = "like_cnt"
+	fieldCollectCnt = "collect_cnt"
+)
+
+type InteractiveCache interface {
+	IncrReadCntIfPresent(ctx context.Context, biz string, bizId int64) error
+	BatchIncrReadCntIfPresent(ctx context.Context, biz string, cnts map[int64]int64) error
+	IncrLikeCntIfPresent(ctx context.Context, biz string, bizId int64) error
+	DecrLikeCntIfPresent(ctx context.Context, biz string, bizId int64) error
+	IncrCollectCntIfPresent(ctx context.Context, biz string, bizId int64) error
+	Get(ctx context.Context, biz string, bizId int64) (domain.Interactive, error)
+	Set(ctx context.Context, biz string, bizId int64, intr domain.Interactive) error
+	// Del 数据库里的计数不是一个一个加减的时候，比如说合并用户，直接删掉缓存
+	Del(ctx context.Context, biz string, bizId int64) error
+}
+
+type InteractiveRedisCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+}
+
+func NewInteractiveRedisCache(client redis.Cmdable) InteractiveCache {
+	return &InteractiveRedisCache{
+		client:     client,
+		expiration: time.Minute * 15,
+	}
+}
+
+func (i *InteractiveRedisCache) IncrReadCntIfPresent(ctx context.Context, biz string, bizId int64) error {
+	return i.client.Eval(ctx, luaIncrCnt, []string{i.key(biz, bizId)}, fieldReadCnt, 1).Err()
+}
+
+func (i *InteractiveRedisCache) BatchIncrReadCntIfPresent(ctx context.Context, biz string, cnts map[int64]int64) error {
+	pipe := i.client.Pipeline()
+	for bizId, cnt := range cnts {
+		pipe.Eval(ctx, luaIncrCnt, []string{i.key(biz, bizId)}, fieldReadCnt, cnt)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (i *InteractiveRedisCache) IncrLikeCntIfPresent(ctx context.Context, biz string, bizId int64) error {
+	return i.client.Eval(ctx, luaIncrCnt, []string{i.key(biz, bizId)}, fieldLikeCnt, 1).Err()
+}
+
+func (i *InteractiveRedisCache) DecrLikeCntIfPresent(ctx context.Context, biz string, bizId int64) error {
+	return i.client.Eval(ctx, luaIncrCnt, []string{i.key(biz, bizId)}, fieldLikeCnt, -1).Err()
+}
+
+func (i *InteractiveRedisCache) IncrCollectCntIfPresent(ctx context.Context, biz string, bizId int64) error {
+	return i.client.Eval(ctx, luaIncrCnt, []string{i.key(biz, bizId)}, fieldCollectCnt, 1).Err()
+}
+
+func (i *InteractiveRedisCache) Get(ctx context.Context, biz string, bizId int64) (domain.Interactive, error) {
+	res, err := i.client.HGetAll(ctx, i.key(biz, bizId)).Result()
+	if err != nil {
+		return domain.Interactive{}, err
+	}
+	if len(res) == 0 {
+		return domain.Interactive{}, ErrKeyNotExist
+	}
+	// 字段都是 Set 的时候写进去的，这里不会解析失败
+	readCnt, _ := strconv.ParseInt(res[fieldReadCnt], 10, 64)
+	likeCnt, _ := strconv.ParseInt(res[fieldLikeCnt], 10, 64)
+	collectCnt, _ := strconv.ParseInt(res[fieldCollectCnt], 10, 64)
+	return domain.Interactive{
+		Biz:        biz,
+		BizId:      bizId,
+		ReadCnt:    readCnt,
+		LikeCnt:    likeCnt,
+		CollectCnt: collectCnt,
+	}, nil
+}
+
+func (i *InteractiveRedisCache) Set(ctx context.Context, biz string, bizId int64, intr domain.Interactive) error {
+	key := i.key(biz, bizId)
+	err := i.client.HSet(ctx, key,
+		fieldReadCnt, intr.ReadCnt,
+		fieldLikeCnt, intr.LikeCnt,
+		fieldCollectCnt, intr.CollectCnt).Err()
+	if err != nil {
+		return err
+	}
+	return i.client.Expire(ctx, key, i.expiration).Err()
+}
+
+func (i *InteractiveRedisCache) Del(ctx context.Context, biz string, bizId int64) error {
+	return i.client.Del(ctx, i.key(biz, bizId)).Err()
+}
+
+func (i *InteractiveRedisCache) key(biz string, bizId int64) string {
+	return fmt.Sprintf("interactive:%s:%d", biz, bizId)
+}
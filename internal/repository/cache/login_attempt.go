@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+	"webook/internal/domain"
+)
+
+//go:embed lua/login_fail.lua
+var luaLoginFail string
+
+// LoginAttemptCache 记录登录失败的次数，typ 区分是按照账号还是按照 IP 统计
+type LoginAttemptCache interface {
+	// LockTTL 剩余的锁定时间，没有锁定返回 0
+	LockTTL(ctx context.Context, typ string, id string) (time.Duration, error)
+	// Fail 记录一次失败，返回这一次触发的锁定时间，没有锁定返回 0
+	Fail(ctx context.Context, typ string, id string, p domain.LoginLockPolicy) (time.Duration, error)
+	// Reset 清空失败次数，同时解锁
+	Reset(ctx context.Context, typ string, id string) error
+}
+
+type RedisLoginAttemptCache struct {
+	cmd redis.Cmdable
+}
+
+func NewLoginAttemptCache(cmd redis.Cmdable) LoginAttemptCache {
+	return &RedisLoginAttemptCache{
+		cmd: cmd,
+	}
+}
+
+func (c *RedisLoginAttemptCache) LockTTL(ctx context.Context, typ string, id string) (time.Duration, error) {
+	ttl, err := c.cmd.TTL(ctx, c.lockKey(typ, id)).Result()
+	if err != nil {
+		return 0, err
+	}
+	// key 不存在的时候是 -2，没有过期时间的时候是 -1，都当作没有锁定
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (c *RedisLoginAttemptCache) Fail(ctx context.Context, typ string, id string, p domain.LoginLockPolicy) (time.Duration, error) {
+	sec, err := c.cmd.Eval(ctx, luaLoginFail,
+		[]string{c.cntKey(typ, id), c.lockKey(typ, id)},
+		p.Threshold, int64(p.BaseLock/time.Second), int64(p.MaxLock/time.Second),
+		int64(p.Window/time.Second)).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(sec) * time.Second, nil
+}
+
+func (c *RedisLoginAttemptCache) Reset(ctx context.Context, typ string, id string) error {
+	return c.cmd.Del(ctx, c.cntKey(typ, id), c.lockKey(typ, id)).Err()
+}
+
+func (c *RedisLoginAttemptCache) cntKey(typ string, id string) string {
+	return fmt.Sprintf("login:fail:%s:%s", typ, id)
+}
+
+func (c *RedisLoginAttemptCache) lockKey(typ string, id string) string {
+	return fmt.Sprintf("login:lock:%s:%s", typ, id)
+}
@@ -0,0 +1,14 @@
+-- 具体业务的 key，比如 interactive:article:1
+local key = KEYS[1]
+-- 要修改的字段，read_cnt/like_cnt/collect_cnt
+local cntKey = ARGV[1]
+-- +1 或者 -1
+local delta = tonumber(ARGV[2])
+local exist = redis.call("exists", key)
+if exist == 1 then
+    -- 缓存存在才自增，不存在就等下一次查询的时候回写
+    redis.call("hincrby", key, cntKey, delta)
+    return 1
+else
+    return 0
+end
@@ -0,0 +1,27 @@
+-- 失败次数
+local cntKey = KEYS[1]
+-- 锁定标记，过期了就解锁
+local lockKey = KEYS[2]
+local threshold = tonumber(ARGV[1])
+-- 第一次锁定的秒数，后面每多失败一次翻倍
+local baseLock = tonumber(ARGV[2])
+local maxLock = tonumber(ARGV[3])
+-- 失败次数的统计窗口，秒数
+local window = tonumber(ARGV[4])
+
+local cnt = redis.call("incr", cntKey)
+-- 每次失败都续期，只要一直在试就一直累计
+redis.call("expire", cntKey, window)
+if cnt < threshold then
+    return 0
+end
+
+local exp = cnt - threshold
+local ttl = maxLock
+-- 指数太大会溢出，反正已经超过上限了
+if exp < 30 then
+    ttl = math.min(baseLock * 2 ^ exp, maxLock)
+end
+ttl = math.floor(ttl)
+redis.call("set", lockKey, cnt, "EX", ttl)
+return ttl
@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"sync/atomic"
+	"time"
+	"webook/internal/domain"
+)
+
+var ErrRankingNotExist = errors.New("本地缓存没有榜单数据")
+
+type RankingCache interface {
+	Set(ctx context.Context, arts []domain.Article) error
+	Get(ctx context.Context) ([]domain.Article, error)
+}
+
+// RankingRedisCache 用 sorted set 保存热榜，score 就是排名
+type RankingRedisCache struct {
+	client     redis.Cmdable
+	key        string
+	expiration time.Duration
+}
+
+func NewRankingRedisCache(client redis.Cmdable) *RankingRedisCache {
+	return &RankingRedisCache{
+		client: client,
+		key:    "ranking:top_n",
+		// 比计算榜单的间隔长一点，任务偶尔失败一次榜单也还在
+		expiration: time.Minute * 10,
+	}
+}
+
+func (r *RankingRedisCache) Set(ctx context.Context, arts []domain.Article) error {
+	members := make([]redis.Z, 0, len(arts))
+	for i, art := range arts {
+		// 榜单只展示摘要，不需要缓存全文
+		art.Content = art.Abstract()
+		data, err := json.Marshal(art)
+		if err != nil {
+			return err
+		}
+		members = append(members, redis.Z{
+			// 排在前面的分数高
+			Score:  float64(len(arts) - i),
+			Member: data,
+		})
+	}
+	// 旧榜单和新榜单一起替换，读者不会看到新旧混合的榜单
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, r.key)
+	if len(members) > 0 {
+		pipe.ZAdd(ctx, r.key, members...)
+		pipe.Expire(ctx, r.key, r.expiration)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *RankingRedisCache) Get(ctx context.Context) ([]domain.Article, error) {
+	vals, err := r.client.ZRevRange(ctx, r.key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Article, 0, len(vals))
+	for _, val := range vals {
+		var art domain.Article
+		err = json.Unmarshal([]byte(val), &art)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, art)
+	}
+	return res, nil
+}
+
+// RankingLocalCache 本地缓存一份榜单，Redis 崩了的时候还能兜底
+type RankingLocalCache struct {
+	topN       atomic.Value
+	ddl        atomic.Value
+	expiration time.Duration
+}
+
+func NewRankingLocalCache() *RankingLocalCache {
+	return &RankingLocalCache{
+		expiration: time.Minute * 3,
+	}
+}
+
+func (r *RankingLocalCache) Set(ctx context.Context, arts []domain.Article) error {
+	r.topN.Store(arts)
+	r.ddl.Store(time.Now().Add(r.expiration))
+	return nil
+}
+
+func (r *RankingLocalCache) Get(ctx context.Context) ([]domain.Article, error) {
+	ddl, ok := r.ddl.Load().(time.Time)
+	arts, _ := r.topN.Load().([]domain.Article)
+	if !ok || len(arts) == 0 || ddl.Before(time.Now()) {
+		return nil, ErrRankingNotExist
+	}
+	return arts, nil
+}
+
+// ForceGet 不管有没有过期都返回
+func (r *RankingLocalCache) ForceGet(ctx context.Context) ([]domain.Article, error) {
+	arts, _ := r.topN.Load().([]domain.Article)
+	if len(arts) == 0 {
+		return nil, ErrRankingNotExist
+	}
+	return arts, nil
+}
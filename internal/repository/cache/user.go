@@ -3,20 +3,32 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/redis/go-redis/v9"
 	"time"
 	"webook/internal/domain"
 )
 
+// ErrUserNotExist 缓存里面记录了这个用户不存在，用来防止缓存穿透
+var ErrUserNotExist = errors.New("用户不存在")
+
+// notExistVal 用户不存在的时候缓存的占位值，正常的用户数据是 JSON，不会是空字符串
+const notExistVal = ""
+
 type UserCache interface {
 	Get(ctx context.Context, id int64) (domain.User, error)
 	Set(ctx context.Context, du domain.User) error
+	// SetNotExist 缓存一个用户不存在的结果，过期时间比较短
+	SetNotExist(ctx context.Context, id int64) error
+	Del(ctx context.Context, id int64) error
 }
 
 type RedisUserCache struct {
 	cmd        redis.Cmdable
 	expiration time.Duration
+	// notExistExpiration 不存在的用户缓存的过期时间
+	notExistExpiration time.Duration
 }
 
 func (c *RedisUserCache) Get(ctx context.Context, id int64) (domain.User, error) {
@@ -25,6 +37,9 @@ func (c *RedisUserCache) Get(ctx context.Context, id int64) (domain.User, error)
 	if err != nil {
 		return domain.User{}, err
 	}
+	if data == notExistVal {
+		return domain.User{}, ErrUserNotExist
+	}
 
 	var u domain.User
 	err = json.Unmarshal([]byte(data), &u)
@@ -44,9 +59,18 @@ func (c *RedisUserCache) Set(ctx context.Context, du domain.User) error {
 	return c.cmd.Set(ctx, key, data, c.expiration).Err()
 }
 
+func (c *RedisUserCache) SetNotExist(ctx context.Context, id int64) error {
+	return c.cmd.Set(ctx, c.key(id), notExistVal, c.notExistExpiration).Err()
+}
+
+func (c *RedisUserCache) Del(ctx context.Context, id int64) error {
+	return c.cmd.Del(ctx, c.key(id)).Err()
+}
+
 func NewUserCache(cmd redis.Cmdable) UserCache {
 	return &RedisUserCache{
-		cmd:        cmd,
-		expiration: time.Minute * 15,
+		cmd:                cmd,
+		expiration:         time.Minute * 15,
+		notExistExpiration: time.Minute,
 	}
 }
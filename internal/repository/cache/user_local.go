@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+	"webook/internal/domain"
+	"webook/pkg/logger"
+)
+
+// userInvalidateChannel 用户缓存失效通知的频道，消息内容就是用户 id
+const userInvalidateChannel = "user:info:invalidate"
+
+// LocalUserCache 在 redis 缓存前面加一层本地缓存
+// 本地缓存过期时间很短，并且通过 redis 的发布订阅通知所有实例删除本地缓存
+type LocalUserCache struct {
+	// redis 背后的那一层缓存
+	redis UserCache
+	// cmd 用来发布和订阅失效通知
+	cmd   redis.Cmdable
+	local *lru.Cache
+	// expiration 本地缓存的过期时间
+	expiration time.Duration
+	l          logger.LoggerV1
+}
+
+func NewLocalUserCache(rc UserCache, cmd redis.Cmdable, local *lru.Cache, l logger.LoggerV1) *LocalUserCache {
+	return &LocalUserCache{
+		redis:      rc,
+		cmd:        cmd,
+		local:      local,
+		expiration: time.Second * 10,
+		l:          l,
+	}
+}
+
+func (c *LocalUserCache) Get(ctx context.Context, id int64) (domain.User, error) {
+	val, ok := c.local.Get(id)
+	if ok {
+		itm, ok := val.(userItem)
+		if ok && itm.expire.After(time.Now()) {
+			if itm.notExist {
+				return domain.User{}, ErrUserNotExist
+			}
+			return itm.u, nil
+		}
+	}
+	u, err := c.redis.Get(ctx, id)
+	switch err {
+	case nil:
+		c.local.Add(id, userItem{u: u, expire: time.Now().Add(c.expiration)})
+	case ErrUserNotExist:
+		c.local.Add(id, userItem{notExist: true, expire: time.Now().Add(c.expiration)})
+	}
+	return u, err
+}
+
+func (c *LocalUserCache) Set(ctx context.Context, du domain.User) error {
+	err := c.redis.Set(ctx, du)
+	if err != nil {
+		return err
+	}
+	c.local.Add(du.Id, userItem{u: du, expire: time.Now().Add(c.expiration)})
+	return nil
+}
+
+func (c *LocalUserCache) SetNotExist(ctx context.Context, id int64) error {
+	err := c.redis.SetNotExist(ctx, id)
+	if err != nil {
+		return err
+	}
+	c.local.Add(id, userItem{notExist: true, expire: time.Now().Add(c.expiration)})
+	return nil
+}
+
+func (c *LocalUserCache) Del(ctx context.Context, id int64) error {
+	// 本地的先删掉，就算 redis 出问题了，本实例也不会读到旧数据
+	c.local.Remove(id)
+	err := c.redis.Del(ctx, id)
+	if err != nil {
+		return err
+	}
+	// 通知其它实例删除本地缓存
+	return c.cmd.Publish(ctx, userInvalidateChannel, strconv.FormatInt(id, 10)).Err()
+}
+
+// Subscribe 订阅失效通知，收到通知就删除本地缓存
+// 会一直阻塞，直到 ctx 被取消
+func (c *LocalUserCache) Subscribe(ctx context.Context) error {
+	client, ok := c.cmd.(interface {
+		Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+	})
+	if !ok {
+		return errors.New("redis 客户端不支持订阅")
+	}
+	ps := client.Subscribe(ctx, userInvalidateChannel)
+	defer ps.Close()
+	ch := ps.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return errors.New("订阅已经关闭")
+			}
+			c.invalidate(msg.Payload)
+		}
+	}
+}
+
+func (c *LocalUserCache) invalidate(payload string) {
+	id, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		c.l.Error("用户缓存失效通知格式不对",
+			logger.String("payload", payload),
+			logger.Error(err))
+		return
+	}
+	c.local.Remove(id)
+}
+
+type userItem struct {
+	u        domain.User
+	notExist bool
+	expire   time.Time
+}
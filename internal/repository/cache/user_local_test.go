@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository/cache/redismocks"
+	"webook/pkg/logger"
+)
+
+// fakeUserCache 模拟 redis 那一层缓存，记录被调用的次数
+type fakeUserCache struct {
+	users    map[int64]domain.User
+	notExist map[int64]bool
+	getCnt   int
+	err      error
+}
+
+func (f *fakeUserCache) Get(ctx context.Context, id int64) (domain.User, error) {
+	f.getCnt++
+	if f.err != nil {
+		return domain.User{}, f.err
+	}
+	if f.notExist[id] {
+		return domain.User{}, ErrUserNotExist
+	}
+	u, ok := f.users[id]
+	if !ok {
+		return domain.User{}, ErrKeyNotExist
+	}
+	return u, nil
+}
+
+func (f *fakeUserCache) Set(ctx context.Context, du domain.User) error {
+	f.users[du.Id] = du
+	return f.err
+}
+
+func (f *fakeUserCache) SetNotExist(ctx context.Context, id int64) error {
+	f.notExist[id] = true
+	return f.err
+}
+
+func (f *fakeUserCache) Del(ctx context.Context, id int64) error {
+	delete(f.users, id)
+	delete(f.notExist, id)
+	return f.err
+}
+
+func TestLocalUserCache(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+		// 在 LocalUserCache 上执行的操作
+		run func(t *testing.T, c *LocalUserCache, rc *fakeUserCache)
+	}{
+		{
+			name: "第二次读取命中本地缓存",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				return redismocks.NewMockCmdable(ctrl)
+			},
+			run: func(t *testing.T, c *LocalUserCache, rc *fakeUserCache) {
+				rc.users[123] = domain.User{Id: 123, NickName: "nick_name"}
+				for i := 0; i < 3; i++ {
+					u, err := c.Get(context.Background(), 123)
+					assert.NoError(t, err)
+					assert.Equal(t, domain.User{Id: 123, NickName: "nick_name"}, u)
+				}
+				assert.Equal(t, 1, rc.getCnt)
+			},
+		},
+		{
+			name: "不存在的用户也缓存在本地",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				return redismocks.NewMockCmdable(ctrl)
+			},
+			run: func(t *testing.T, c *LocalUserCache, rc *fakeUserCache) {
+				err := c.SetNotExist(context.Background(), 123)
+				assert.NoError(t, err)
+				_, err = c.Get(context.Background(), 123)
+				assert.Equal(t, ErrUserNotExist, err)
+				assert.Equal(t, 0, rc.getCnt)
+			},
+		},
+		{
+			name: "本地缓存过期，回源 redis",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				return redismocks.NewMockCmdable(ctrl)
+			},
+			run: func(t *testing.T, c *LocalUserCache, rc *fakeUserCache) {
+				c.expiration = time.Millisecond * 10
+				err := c.Set(context.Background(), domain.User{Id: 123})
+				assert.NoError(t, err)
+				time.Sleep(time.Millisecond * 20)
+				_, err = c.Get(context.Background(), 123)
+				assert.NoError(t, err)
+				assert.Equal(t, 1, rc.getCnt)
+			},
+		},
+		{
+			name: "删除的时候通知其它实例",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewIntCmd(context.Background())
+				res.SetVal(1)
+				cmd.EXPECT().Publish(gomock.Any(), userInvalidateChannel, "123").Return(res)
+				return cmd
+			},
+			run: func(t *testing.T, c *LocalUserCache, rc *fakeUserCache) {
+				err := c.Set(context.Background(), domain.User{Id: 123})
+				assert.NoError(t, err)
+				err = c.Del(context.Background(), 123)
+				assert.NoError(t, err)
+				_, err = c.Get(context.Background(), 123)
+				assert.Equal(t, ErrKeyNotExist, err)
+				assert.Equal(t, 1, rc.getCnt)
+			},
+		},
+		{
+			name: "redis 删除失败，本地缓存也要删除",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				return redismocks.NewMockCmdable(ctrl)
+			},
+			run: func(t *testing.T, c *LocalUserCache, rc *fakeUserCache) {
+				c.local.Add(int64(123), userItem{u: domain.User{Id: 123}, expire: time.Now().Add(time.Minute)})
+				rc.err = errors.New("redis error")
+				err := c.Del(context.Background(), 123)
+				assert.Equal(t, errors.New("redis error"), err)
+				_, ok := c.local.Get(int64(123))
+				assert.False(t, ok)
+			},
+		},
+		{
+			name: "收到失效通知，删除本地缓存",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				return redismocks.NewMockCmdable(ctrl)
+			},
+			run: func(t *testing.T, c *LocalUserCache, rc *fakeUserCache) {
+				err := c.Set(context.Background(), domain.User{Id: 123})
+				assert.NoError(t, err)
+				c.invalidate("abc")
+				_, ok := c.local.Get(int64(123))
+				assert.True(t, ok)
+				c.invalidate("123")
+				_, ok = c.local.Get(int64(123))
+				assert.False(t, ok)
+			},
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			local, err := lru.New(10)
+			assert.NoError(t, err)
+			rc := &fakeUserCache{
+				users:    map[int64]domain.User{},
+				notExist: map[int64]bool{},
+			}
+			c := NewLocalUserCache(rc, tc.mock(ctrl), local, logger.NewNopLogger())
+			tc.run(t, c, rc)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository/dao"
+)
+
+var (
+	ErrNoJobToPreempt = dao.ErrRecordNotFound
+	ErrJobNotOwned    = dao.ErrJobNotOwned
+)
+
+type CronJobRepository interface {
+	Preempt(ctx context.Context, abandoned time.Duration) (domain.CronJob, error)
+	// Release UpdateUtime UpdateNextTime Stop 都要带上抢占时拿到的 version
+	// 任务已经被别的实例抢走了返回 ErrJobNotOwned
+	Release(ctx context.Context, id int64, version int) error
+	UpdateUtime(ctx context.Context, id int64, version int) error
+	UpdateNextTime(ctx context.Context, id int64, version int, next time.Time) error
+	Stop(ctx context.Context, id int64, version int) error
+	AddJob(ctx context.Context, j domain.CronJob) error
+}
+
+type PreemptCronJobRepository struct {
+	dao dao.JobDAO
+}
+
+func NewPreemptCronJobRepository(dao dao.JobDAO) CronJobRepository {
+	return &PreemptCronJobRepository{
+		dao: dao,
+	}
+}
+
+func (p *PreemptCronJobRepository) Preempt(ctx context.Context, abandoned time.Duration) (domain.CronJob, error) {
+	j, err := p.dao.Preempt(ctx, abandoned)
+	if err != nil {
+		return domain.CronJob{}, err
+	}
+	return p.toDomain(j), nil
+}
+
+func (p *PreemptCronJobRepository) Release(ctx context.Context, id int64, version int) error {
+	return p.dao.Release(ctx, id, version)
+}
+
+func (p *PreemptCronJobRepository) UpdateUtime(ctx context.Context, id int64, version int) error {
+	return p.dao.UpdateUtime(ctx, id, version)
+}
+
+func (p *PreemptCronJobRepository) UpdateNextTime(ctx context.Context, id int64, version int, next time.Time) error {
+	return p.dao.UpdateNextTime(ctx, id, version, next)
+}
+
+func (p *PreemptCronJobRepository) Stop(ctx context.Context, id int64, version int) error {
+	return p.dao.Stop(ctx, id, version)
+}
+
+func (p *PreemptCronJobRepository) AddJob(ctx context.Context, j domain.CronJob) error {
+	return p.dao.Insert(ctx, p.toEntity(j))
+}
+
+func (p *PreemptCronJobRepository) toDomain(j dao.Job) domain.CronJob {
+	return domain.CronJob{
+		Id:         j.Id,
+		Name:       j.Name,
+		Expression: j.Expression,
+		Executor:   j.Executor,
+		Cfg:        j.Cfg,
+		Version:    j.Version,
+		NextTime:   time.UnixMilli(j.NextTime),
+	}
+}
+
+func (p *PreemptCronJobRepository) toEntity(j domain.CronJob) dao.Job {
+	return dao.Job{
+		Id:         j.Id,
+		Name:       j.Name,
+		Expression: j.Expression,
+		Executor:   j.Executor,
+		Cfg:        j.Cfg,
+		NextTime:   j.NextTime.UnixMilli(),
+	}
+}
@@ -10,6 +10,14 @@ import (
 type ArticleDAO interface {
 	Insert(ctx context.Context, art Article) (int64, error)
 	UpdateById(ctx context.Context, art Article) error
+	GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error)
+	GetById(ctx context.Context, id int64) (Article, error)
+	GetPubById(ctx context.Context, id int64) (PublishedArticle, error)
+	SyncStatus(ctx context.Context, uid int64, id int64, status uint8) error
+	// UpdateStatusById 不校验作者，同时修改制作库和线上库的状态，给管理员用
+	UpdateStatusById(ctx context.Context, id int64, status uint8) error
+	// ListPub 按照更新时间倒序查询线上库中 start 之前更新的文章
+	ListPub(ctx context.Context, start time.Time, status uint8, offset int, limit int) ([]PublishedArticle, error)
 }
 
 type ArticleGORMDAO struct {
@@ -22,11 +30,91 @@ func NewArticleGORMDAO(db *gorm.DB) ArticleDAO {
 	}
 }
 
+func (a *ArticleGORMDAO) GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]Article, error) {
+	var arts []Article
+	err := a.db.WithContext(ctx).Where("author_id = ?", uid).
+		Offset(offset).Limit(limit).
+		// 按照更新时间倒序，最近修改的排在前面
+		Order("utime DESC").
+		Find(&arts).Error
+	return arts, err
+}
+
+func (a *ArticleGORMDAO) GetById(ctx context.Context, id int64) (Article, error) {
+	var art Article
+	err := a.db.WithContext(ctx).Where("id = ?", id).First(&art).Error
+	return art, err
+}
+
+func (a *ArticleGORMDAO) GetPubById(ctx context.Context, id int64) (PublishedArticle, error) {
+	var art PublishedArticle
+	err := a.db.WithContext(ctx).Where("id = ?", id).First(&art).Error
+	return art, err
+}
+
+func (a *ArticleGORMDAO) ListPub(ctx context.Context, start time.Time, status uint8, offset int, limit int) ([]PublishedArticle, error) {
+	var res []PublishedArticle
+	err := a.db.WithContext(ctx).
+		Where("utime < ? AND status = ?", start.UnixMilli(), status).
+		Order("utime DESC").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+// SyncStatus 在一个事务里面同时修改制作库和线上库的状态
+func (a *ArticleGORMDAO) SyncStatus(ctx context.Context, uid int64, id int64, status uint8) error {
+	now := time.Now().UnixMilli()
+	return a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Article{}).Where("id = ? AND author_id = ?", id, uid).
+			Updates(map[string]any{
+				"status": status,
+				"utime":  now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != 1 {
+			// 要么 id 不对，要么是别人的文章
+			return errors.New("ID 不对或者创作者不对")
+		}
+		return tx.Model(&PublishedArticle{}).Where("id = ? AND author_id = ?", id, uid).
+			Updates(map[string]any{
+				"status": status,
+				"utime":  now,
+			}).Error
+	})
+}
+
+func (a *ArticleGORMDAO) UpdateStatusById(ctx context.Context, id int64, status uint8) error {
+	now := time.Now().UnixMilli()
+	return a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Article{}).Where("id = ?", id).
+			Updates(map[string]any{
+				"status": status,
+				"utime":  now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != 1 {
+			return ErrRecordNotFound
+		}
+		// 还没有发表过的文章，线上库没有数据，更新不到也没关系
+		return tx.Model(&PublishedArticle{}).Where("id = ?", id).
+			Updates(map[string]any{
+				"status": status,
+				"utime":  now,
+			}).Error
+	})
+}
+
 func (a *ArticleGORMDAO) UpdateById(ctx context.Context, art Article) error {
 	now := time.Now().UnixMilli()
 	res := a.db.WithContext(ctx).Model(&Article{}).Where("id = ? AND author_id = ?", art.Id, art.AuthorId).Updates(map[string]any{
 		"title":   art.Title,
 		"content": art.Content,
+		"status":  art.Status,
 		"utime":   now,
 	})
 	err := res.Error
@@ -52,6 +140,7 @@ type Article struct {
 	Title    string `gorm:"type=varchar(4096)"`
 	Content  string `gorm:"type=BLOB"`
 	AuthorId int64  `gorm:"index"`
+	Status   uint8
 	Ctime    int64
 	Utime    int64
 }
@@ -2,7 +2,9 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"gorm.io/gorm"
+	"time"
 )
 
 type ArticleAuthorDAO interface {
@@ -15,13 +17,29 @@ type ArticleGORMAuthorDAO struct {
 }
 
 func (a *ArticleGORMAuthorDAO) Create(ctx context.Context, art Article) (int64, error) {
-	//TODO implement me
-	panic("implement me")
+	now := time.Now().UnixMilli()
+	art.Ctime = now
+	art.Utime = now
+	err := a.db.WithContext(ctx).Create(&art).Error
+	return art.Id, err
 }
 
 func (a *ArticleGORMAuthorDAO) Update(ctx context.Context, art Article) error {
-	//TODO implement me
-	panic("implement me")
+	now := time.Now().UnixMilli()
+	res := a.db.WithContext(ctx).Model(&Article{}).Where("id = ? AND author_id = ?", art.Id, art.AuthorId).Updates(map[string]any{
+		"title":   art.Title,
+		"content": art.Content,
+		"status":  art.Status,
+		"utime":   now,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		// 要么 id 不对，要么是别人的文章
+		return errors.New("更新数据失败")
+	}
+	return nil
 }
 
 func NewArticleGORMAuthorDAO(db *gorm.DB) ArticleAuthorDAO {
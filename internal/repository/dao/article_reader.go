@@ -3,6 +3,8 @@ package dao
 import (
 	"context"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type ArticleReaderDAO interface {
@@ -14,14 +16,24 @@ type ArticleReaderGORMDAO struct {
 	db *gorm.DB
 }
 
+// UpsertV2 利用 MySQL 的 INSERT ... ON DUPLICATE KEY UPDATE，
+// 线上库没有这篇文章就插入，有就更新
 func (a *ArticleReaderGORMDAO) UpsertV2(ctx context.Context, art PublishedArticle) error {
-	//TODO implement me
-	panic("implement me")
+	now := time.Now().UnixMilli()
+	art.Ctime = now
+	art.Utime = now
+	return a.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{
+			"title":   art.Title,
+			"content": art.Content,
+			"status":  art.Status,
+			"utime":   now,
+		}),
+	}).Create(&art).Error
 }
 
 func (a *ArticleReaderGORMDAO) Upsert(ctx context.Context, art Article) error {
-	//TODO implement me
-	panic("implement me")
+	return a.UpsertV2(ctx, PublishedArticle(art))
 }
 
 func NewArticleReaderGORMDAO(db *gorm.DB) ArticleReaderDAO {
@@ -0,0 +1,72 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestArticleReaderGORMDAO_UpsertV2(t *testing.T) {
+	testCase := []struct {
+		name    string
+		mock    func(t *testing.T) *sql.DB
+		art     PublishedArticle
+		wantErr error
+	}{
+		{
+			name: "插入或者更新成功",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("INSERT INTO `published_articles` .* ON DUPLICATE KEY UPDATE .*").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				return db
+			},
+			art: PublishedArticle{
+				Id:       1,
+				Title:    "我的标题",
+				Content:  "我的内容",
+				AuthorId: 123,
+			},
+		},
+		{
+			name: "数据库错误",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("INSERT INTO `published_articles` .*").
+					WillReturnError(errors.New("数据库错误"))
+				return db
+			},
+			art: PublishedArticle{
+				Id:       1,
+				Title:    "我的标题",
+				Content:  "我的内容",
+				AuthorId: 123,
+			},
+			wantErr: errors.New("数据库错误"),
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlDB := tc.mock(t)
+			db, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      sqlDB,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			assert.NoError(t, err)
+			dao := NewArticleReaderGORMDAO(db)
+			err = dao.UpsertV2(context.Background(), tc.art)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ErrJobNotOwned 任务已经被别的实例抢占了，version 对不上
+var ErrJobNotOwned = errors.New("任务已经被别的实例抢占了")
+
+const (
+	// jobStatusWaiting 等待被抢占
+	jobStatusWaiting = iota
+	// jobStatusRunning 已经被某个实例抢占了
+	jobStatusRunning
+	// jobStatusPaused 暂停调度
+	jobStatusPaused
+)
+
+type JobDAO interface {
+	// Preempt 返回的 Job 带着抢占之后的 version，后面续约、释放都要带上
+	// version 对不上说明持有者卡住太久，任务已经被别的实例抢走了，返回 ErrJobNotOwned
+	Preempt(ctx context.Context, abandoned time.Duration) (Job, error)
+	Release(ctx context.Context, id int64, version int) error
+	UpdateUtime(ctx context.Context, id int64, version int) error
+	UpdateNextTime(ctx context.Context, id int64, version int, next time.Time) error
+	Stop(ctx context.Context, id int64, version int) error
+	Insert(ctx context.Context, j Job) error
+}
+
+type GORMJobDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMJobDAO(db *gorm.DB) JobDAO {
+	return &GORMJobDAO{
+		db: db,
+	}
+}
+
+// Preempt 抢占一个到期的任务。持有者超过 abandoned 没有续约的任务，
+// 说明持有者已经崩溃了，也可以被抢占。
+// 用 version 做乐观锁，多个实例同时抢同一个任务只有一个能成功
+func (dao *GORMJobDAO) Preempt(ctx context.Context, abandoned time.Duration) (Job, error) {
+	db := dao.db.WithContext(ctx)
+	for {
+		now := time.Now()
+		var j Job
+		err := db.Where("(status = ? AND next_time <= ?) OR (status = ? AND utime < ?)",
+			jobStatusWaiting, now.UnixMilli(),
+			jobStatusRunning, now.Add(-abandoned).UnixMilli()).
+			First(&j).Error
+		if err != nil {
+			return Job{}, err
+		}
+		res := db.Model(&Job{}).
+			Where("id = ? AND version = ?", j.Id, j.Version).
+			Updates(map[string]any{
+				"status":  jobStatusRunning,
+				"version": j.Version + 1,
+				"utime":   now.UnixMilli(),
+			})
+		if res.Error != nil {
+			return Job{}, res.Error
+		}
+		if res.RowsAffected == 0 {
+			// 被别人抢走了，继续抢下一个
+			continue
+		}
+		j.Version++
+		return j, nil
+	}
+}
+
+func (dao *GORMJobDAO) Release(ctx context.Context, id int64, version int) error {
+	return dao.updateOwned(ctx, id, version, map[string]any{
+		"status": jobStatusWaiting,
+		"utime":  time.Now().UnixMilli(),
+	})
+}
+
+// UpdateUtime 续约，证明持有者还活着
+func (dao *GORMJobDAO) UpdateUtime(ctx context.Context, id int64, version int) error {
+	return dao.updateOwned(ctx, id, version, map[string]any{
+		"utime": time.Now().UnixMilli(),
+	})
+}
+
+func (dao *GORMJobDAO) UpdateNextTime(ctx context.Context, id int64, version int, next time.Time) error {
+	return dao.updateOwned(ctx, id, version, map[string]any{
+		"next_time": next.UnixMilli(),
+		"utime":     time.Now().UnixMilli(),
+	})
+}
+
+// updateOwned 只有还持有任务的时候才能更新
+func (dao *GORMJobDAO) updateOwned(ctx context.Context, id int64, version int, updates map[string]any) error {
+	res := dao.db.WithContext(ctx).Model(&Job{}).
+		Where("id = ? AND status = ? AND version = ?", id, jobStatusRunning, version).
+		Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobNotOwned
+	}
+	return nil
+}
+
+// Stop 任务没有下一次了，暂停掉。和续约一样，只有还持有任务的时候才能暂停
+func (dao *GORMJobDAO) Stop(ctx context.Context, id int64, version int) error {
+	return dao.updateOwned(ctx, id, version, map[string]any{
+		"status": jobStatusPaused,
+		"utime":  time.Now().UnixMilli(),
+	})
+}
+
+func (dao *GORMJobDAO) Insert(ctx context.Context, j Job) error {
+	now := time.Now().UnixMilli()
+	j.Ctime = now
+	j.Utime = now
+	return dao.db.WithContext(ctx).Create(&j).Error
+}
+
+type Job struct {
+	Id         int64  `gorm:"primaryKey,autoIncrement"`
+	Name       string `gorm:"type:varchar(128);unique"`
+	Executor   string
+	Expression string
+	Cfg        string
+	Status     int
+	// Version 乐观锁
+	Version  int
+	NextTime int64 `gorm:"index"`
+	Ctime    int64
+	Utime    int64
+}
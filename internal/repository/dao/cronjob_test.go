@@ -0,0 +1,199 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestGORMJobDAO_Preempt(t *testing.T) {
+	testCase := []struct {
+		name    string
+		mock    func(t *testing.T) *sql.DB
+		wantJob Job
+		wantErr error
+	}{
+		{
+			name: "抢占成功",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				rows := sqlmock.NewRows([]string{"id", "name", "version"}).AddRow(1, "test_job", 3)
+				mock.ExpectQuery("SELECT .* FROM `jobs` .*").WillReturnRows(rows)
+				mock.ExpectExec("UPDATE `jobs` .*").WillReturnResult(sqlmock.NewResult(0, 1))
+				return db
+			},
+			wantJob: Job{Id: 1, Name: "test_job", Version: 4},
+		},
+		{
+			name: "被别人抢走了，抢下一个",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				rows := sqlmock.NewRows([]string{"id", "name", "version"}).AddRow(1, "test_job", 3)
+				mock.ExpectQuery("SELECT .* FROM `jobs` .*").WillReturnRows(rows)
+				// version 变了，更新不到
+				mock.ExpectExec("UPDATE `jobs` .*").WillReturnResult(sqlmock.NewResult(0, 0))
+				rows = sqlmock.NewRows([]string{"id", "name", "version"}).AddRow(2, "other_job", 1)
+				mock.ExpectQuery("SELECT .* FROM `jobs` .*").WillReturnRows(rows)
+				mock.ExpectExec("UPDATE `jobs` .*").WillReturnResult(sqlmock.NewResult(0, 1))
+				return db
+			},
+			wantJob: Job{Id: 2, Name: "other_job", Version: 2},
+		},
+		{
+			name: "没有任务可以抢",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery("SELECT .* FROM `jobs` .*").WillReturnError(gorm.ErrRecordNotFound)
+				return db
+			},
+			wantErr: gorm.ErrRecordNotFound,
+		},
+		{
+			name: "数据库错误",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				rows := sqlmock.NewRows([]string{"id", "name", "version"}).AddRow(1, "test_job", 3)
+				mock.ExpectQuery("SELECT .* FROM `jobs` .*").WillReturnRows(rows)
+				mock.ExpectExec("UPDATE `jobs` .*").WillReturnError(errors.New("数据库错误"))
+				return db
+			},
+			wantErr: errors.New("数据库错误"),
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlDB := tc.mock(t)
+			db, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      sqlDB,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			assert.NoError(t, err)
+			dao := NewGORMJobDAO(db)
+			j, err := dao.Preempt(context.Background(), time.Minute)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantJob, j)
+		})
+	}
+}
+
+func TestGORMJobDAO_UpdateUtime(t *testing.T) {
+	testCase := []struct {
+		name    string
+		mock    func(t *testing.T) *sql.DB
+		wantErr error
+	}{
+		{
+			name: "续约成功",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE `jobs` SET `utime`=\\? WHERE id = \\? AND status = \\? AND version = \\?").
+					WithArgs(sqlmock.AnyArg(), 1, jobStatusRunning, 4).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				return db
+			},
+		},
+		{
+			name: "任务已经被别人抢走了",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE `jobs` .*").
+					WithArgs(sqlmock.AnyArg(), 1, jobStatusRunning, 4).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				return db
+			},
+			wantErr: ErrJobNotOwned,
+		},
+		{
+			name: "数据库错误",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE `jobs` .*").WillReturnError(errors.New("数据库错误"))
+				return db
+			},
+			wantErr: errors.New("数据库错误"),
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlDB := tc.mock(t)
+			db, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      sqlDB,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			assert.NoError(t, err)
+			dao := NewGORMJobDAO(db)
+			err = dao.UpdateUtime(context.Background(), 1, 4)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestGORMJobDAO_Stop(t *testing.T) {
+	testCase := []struct {
+		name    string
+		mock    func(t *testing.T) *sql.DB
+		wantErr error
+	}{
+		{
+			name: "暂停成功",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE `jobs` SET `status`=\\?,`utime`=\\? WHERE id = \\? AND status = \\? AND version = \\?").
+					WithArgs(jobStatusPaused, sqlmock.AnyArg(), 1, jobStatusRunning, 4).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				return db
+			},
+		},
+		{
+			name: "任务已经被别人抢走了，不能暂停",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE `jobs` .*").
+					WithArgs(jobStatusPaused, sqlmock.AnyArg(), 1, jobStatusRunning, 4).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				return db
+			},
+			wantErr: ErrJobNotOwned,
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlDB := tc.mock(t)
+			db, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      sqlDB,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			assert.NoError(t, err)
+			dao := NewGORMJobDAO(db)
+			err = dao.Stop(context.Background(), 1, 4)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
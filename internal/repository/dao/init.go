@@ -3,5 +3,8 @@ package dao
 import "gorm.io/gorm"
 
 func InitTables(db *gorm.DB) error {
-	return db.AutoMigrate(&User{}, &Article{})
+	return db.AutoMigrate(&User{}, &Article{}, &PublishedArticle{},
+		&Interactive{}, &UserLikeBiz{}, &UserCollectionBiz{},
+		&Job{}, &UserMergeLog{},
+		&UserTOTP{}, &UserRecoveryCode{})
 }
@@ -0,0 +1,252 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	// ErrCollectionExists 已经收藏过了
+	ErrCollectionExists = errors.New("已经收藏过了")
+	// ErrLikeExists 已经点过赞了
+	ErrLikeExists = errors.New("已经点过赞了")
+	// ErrLikeNotExists 没有点过赞，或者已经取消了
+	ErrLikeNotExists = errors.New("没有点过赞")
+)
+
+type InteractiveDAO interface {
+	IncrReadCnt(ctx context.Context, biz string, bizId int64) error
+	// BatchIncrReadCnt cnts 是 bizId => 增加的阅读数
+	BatchIncrReadCnt(ctx context.Context, biz string, cnts map[int64]int64) error
+	InsertLikeInfo(ctx context.Context, biz string, bizId, uid int64) error
+	DeleteLikeInfo(ctx context.Context, biz string, bizId, uid int64) error
+	InsertCollectionBiz(ctx context.Context, cb UserCollectionBiz) error
+	GetLikeInfo(ctx context.Context, biz string, bizId, uid int64) (UserLikeBiz, error)
+	GetCollectInfo(ctx context.Context, biz string, bizId, uid int64) (UserCollectionBiz, error)
+	Get(ctx context.Context, biz string, bizId int64) (Interactive, error)
+	GetByIds(ctx context.Context, biz string, ids []int64) ([]Interactive, error)
+}
+
+type GORMInteractiveDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMInteractiveDAO(db *gorm.DB) InteractiveDAO {
+	return &GORMInteractiveDAO{
+		db: db,
+	}
+}
+
+// IncrReadCnt 利用 upsert，第一次阅读的时候插入，后面就是 read_cnt + 1
+func (dao *GORMInteractiveDAO) IncrReadCnt(ctx context.Context, biz string, bizId int64) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{
+			"read_cnt": gorm.Expr("`read_cnt` + 1"),
+			"utime":    now,
+		}),
+	}).Create(&Interactive{
+		Biz:     biz,
+		BizId:   bizId,
+		ReadCnt: 1,
+		Ctime:   now,
+		Utime:   now,
+	}).Error
+}
+
+func (dao *GORMInteractiveDAO) BatchIncrReadCnt(ctx context.Context, biz string, cnts map[int64]int64) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for bizId, cnt := range cnts {
+			err := tx.Clauses(clause.OnConflict{
+				DoUpdates: clause.Assignments(map[string]any{
+					"read_cnt": gorm.Expr("`read_cnt` + ?", cnt),
+					"utime":    now,
+				}),
+			}).Create(&Interactive{
+				Biz:     biz,
+				BizId:   bizId,
+				ReadCnt: cnt,
+				Ctime:   now,
+				Utime:   now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// InsertLikeInfo 插入点赞记录并且增加点赞数，两个操作在同一个事务里面
+// 已经点过赞了返回 ErrLikeExists，点赞数不会多加
+func (dao *GORMInteractiveDAO) InsertLikeInfo(ctx context.Context, biz string, bizId, uid int64) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 用户点过赞又取消了，这时候是把 status 改回来
+		res := tx.Model(&UserLikeBiz{}).
+			Where("uid = ? AND biz_id = ? AND biz = ? AND status = ?", uid, bizId, biz, 0).
+			Updates(map[string]any{
+				"status": 1,
+				"utime":  now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// 要么是第一次点赞，要么是已经点过赞了
+			res = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&UserLikeBiz{
+				Uid:    uid,
+				Biz:    biz,
+				BizId:  bizId,
+				Status: 1,
+				Ctime:  now,
+				Utime:  now,
+			})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return ErrLikeExists
+			}
+		}
+		return tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]any{
+				"like_cnt": gorm.Expr("`like_cnt` + 1"),
+				"utime":    now,
+			}),
+		}).Create(&Interactive{
+			Biz:     biz,
+			BizId:   bizId,
+			LikeCnt: 1,
+			Ctime:   now,
+			Utime:   now,
+		}).Error
+	})
+}
+
+// DeleteLikeInfo 软删除点赞记录并且减少点赞数
+// 本来就没点赞返回 ErrLikeNotExists，点赞数不会多减
+func (dao *GORMInteractiveDAO) DeleteLikeInfo(ctx context.Context, biz string, bizId, uid int64) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&UserLikeBiz{}).
+			Where("uid = ? AND biz_id = ? AND biz = ? AND status = ?", uid, bizId, biz, 1).
+			Updates(map[string]any{
+				"status": 0,
+				"utime":  now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrLikeNotExists
+		}
+		return tx.Model(&Interactive{}).
+			Where("biz = ? AND biz_id = ?", biz, bizId).
+			Updates(map[string]any{
+				"like_cnt": gorm.Expr("`like_cnt` - 1"),
+				"utime":    now,
+			}).Error
+	})
+}
+
+// InsertCollectionBiz 插入收藏记录并且增加收藏数
+// 已经收藏过了返回 ErrCollectionExists，收藏数不会多加
+func (dao *GORMInteractiveDAO) InsertCollectionBiz(ctx context.Context, cb UserCollectionBiz) error {
+	now := time.Now().UnixMilli()
+	cb.Ctime = now
+	cb.Utime = now
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&cb)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrCollectionExists
+		}
+		return tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]any{
+				"collect_cnt": gorm.Expr("`collect_cnt` + 1"),
+				"utime":       now,
+			}),
+		}).Create(&Interactive{
+			Biz:        cb.Biz,
+			BizId:      cb.BizId,
+			CollectCnt: 1,
+			Ctime:      now,
+			Utime:      now,
+		}).Error
+	})
+}
+
+func (dao *GORMInteractiveDAO) GetLikeInfo(ctx context.Context, biz string, bizId, uid int64) (UserLikeBiz, error) {
+	var res UserLikeBiz
+	err := dao.db.WithContext(ctx).
+		Where("uid = ? AND biz_id = ? AND biz = ? AND status = ?", uid, bizId, biz, 1).
+		First(&res).Error
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) GetCollectInfo(ctx context.Context, biz string, bizId, uid int64) (UserCollectionBiz, error) {
+	var res UserCollectionBiz
+	err := dao.db.WithContext(ctx).
+		Where("uid = ? AND biz_id = ? AND biz = ?", uid, bizId, biz).
+		First(&res).Error
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) Get(ctx context.Context, biz string, bizId int64) (Interactive, error) {
+	var res Interactive
+	err := dao.db.WithContext(ctx).
+		Where("biz = ? AND biz_id = ?", biz, bizId).
+		First(&res).Error
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) GetByIds(ctx context.Context, biz string, ids []int64) ([]Interactive, error) {
+	var res []Interactive
+	err := dao.db.WithContext(ctx).
+		Where("biz = ? AND biz_id IN ?", biz, ids).
+		Find(&res).Error
+	return res, err
+}
+
+// Interactive 计数表，一个资源一行
+type Interactive struct {
+	Id int64 `gorm:"primaryKey,autoIncrement"`
+	// <bizid, biz> 联合唯一索引
+	BizId      int64  `gorm:"uniqueIndex:biz_type_id"`
+	Biz        string `gorm:"type:varchar(128);uniqueIndex:biz_type_id"`
+	ReadCnt    int64
+	LikeCnt    int64
+	CollectCnt int64
+	Ctime      int64
+	Utime      int64
+}
+
+// UserLikeBiz 用户点赞记录，一个用户对一个资源只能点赞一次
+type UserLikeBiz struct {
+	Id    int64  `gorm:"primaryKey,autoIncrement"`
+	Uid   int64  `gorm:"uniqueIndex:uid_biz_type_id"`
+	BizId int64  `gorm:"uniqueIndex:uid_biz_type_id"`
+	Biz   string `gorm:"type:varchar(128);uniqueIndex:uid_biz_type_id"`
+	// 1 表示点赞，0 表示取消点赞，软删除
+	Status uint8
+	Ctime  int64
+	Utime  int64
+}
+
+// UserCollectionBiz 用户收藏记录，Cid 是收藏夹的 ID
+type UserCollectionBiz struct {
+	Id    int64  `gorm:"primaryKey,autoIncrement"`
+	Cid   int64  `gorm:"index"`
+	Uid   int64  `gorm:"uniqueIndex:uid_biz_type_id"`
+	BizId int64  `gorm:"uniqueIndex:uid_biz_type_id"`
+	Biz   string `gorm:"type:varchar(128);uniqueIndex:uid_biz_type_id"`
+	Ctime int64
+	Utime int64
+}
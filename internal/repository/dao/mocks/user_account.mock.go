@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/repository/dao/user_account.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/repository/dao/user_account.go -package=daomocks -destination=./internal/repository/dao/mocks/user_account.mock.go
+//
+
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	dao "webook/internal/repository/dao"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserAccountDAO is a mock of UserAccountDAO interface.
+type MockUserAccountDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserAccountDAOMockRecorder
+}
+
+// MockUserAccountDAOMockRecorder is the mock recorder for MockUserAccountDAO.
+type MockUserAccountDAOMockRecorder struct {
+	mock *MockUserAccountDAO
+}
+
+// NewMockUserAccountDAO creates a new mock instance.
+func NewMockUserAccountDAO(ctrl *gomock.Controller) *MockUserAccountDAO {
+	mock := &MockUserAccountDAO{ctrl: ctrl}
+	mock.recorder = &MockUserAccountDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserAccountDAO) EXPECT() *MockUserAccountDAOMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockUserAccountDAO) Delete(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockUserAccountDAOMockRecorder) Delete(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserAccountDAO)(nil).Delete), ctx, uid)
+}
+
+// Export mocks base method.
+func (m *MockUserAccountDAO) Export(ctx context.Context, uid int64) (dao.UserArchive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", ctx, uid)
+	ret0, _ := ret[0].(dao.UserArchive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockUserAccountDAOMockRecorder) Export(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockUserAccountDAO)(nil).Export), ctx, uid)
+}
@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/repository/dao/user_merge.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/repository/dao/user_merge.go -package=daomocks -destination=./internal/repository/dao/mocks/user_merge.mock.go
+//
+
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	dao "webook/internal/repository/dao"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserMergeDAO is a mock of UserMergeDAO interface.
+type MockUserMergeDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserMergeDAOMockRecorder
+}
+
+// MockUserMergeDAOMockRecorder is the mock recorder for MockUserMergeDAO.
+type MockUserMergeDAOMockRecorder struct {
+	mock *MockUserMergeDAO
+}
+
+// NewMockUserMergeDAO creates a new mock instance.
+func NewMockUserMergeDAO(ctrl *gomock.Controller) *MockUserMergeDAO {
+	mock := &MockUserMergeDAO{ctrl: ctrl}
+	mock.recorder = &MockUserMergeDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserMergeDAO) EXPECT() *MockUserMergeDAOMockRecorder {
+	return m.recorder
+}
+
+// Merge mocks base method.
+func (m *MockUserMergeDAO) Merge(ctx context.Context, sourceId, targetId, operator int64) (dao.MergeDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", ctx, sourceId, targetId, operator)
+	ret0, _ := ret[0].(dao.MergeDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Merge indicates an expected call of Merge.
+func (mr *MockUserMergeDAOMockRecorder) Merge(ctx, sourceId, targetId, operator any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockUserMergeDAO)(nil).Merge), ctx, sourceId, targetId, operator)
+}
@@ -0,0 +1,126 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/repository/dao/user_totp.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/repository/dao/user_totp.go -package=daomocks -destination=./internal/repository/dao/mocks/user_totp.mock.go
+//
+
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	dao "webook/internal/repository/dao"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserTOTPDAO is a mock of UserTOTPDAO interface.
+type MockUserTOTPDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserTOTPDAOMockRecorder
+}
+
+// MockUserTOTPDAOMockRecorder is the mock recorder for MockUserTOTPDAO.
+type MockUserTOTPDAOMockRecorder struct {
+	mock *MockUserTOTPDAO
+}
+
+// NewMockUserTOTPDAO creates a new mock instance.
+func NewMockUserTOTPDAO(ctrl *gomock.Controller) *MockUserTOTPDAO {
+	mock := &MockUserTOTPDAO{ctrl: ctrl}
+	mock.recorder = &MockUserTOTPDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserTOTPDAO) EXPECT() *MockUserTOTPDAOMockRecorder {
+	return m.recorder
+}
+
+// Disable mocks base method.
+func (m *MockUserTOTPDAO) Disable(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Disable", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Disable indicates an expected call of Disable.
+func (mr *MockUserTOTPDAOMockRecorder) Disable(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disable", reflect.TypeOf((*MockUserTOTPDAO)(nil).Disable), ctx, uid)
+}
+
+// Enable mocks base method.
+func (m *MockUserTOTPDAO) Enable(ctx context.Context, uid, step int64, codeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enable", ctx, uid, step, codeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Enable indicates an expected call of Enable.
+func (mr *MockUserTOTPDAOMockRecorder) Enable(ctx, uid, step, codeHashes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enable", reflect.TypeOf((*MockUserTOTPDAO)(nil).Enable), ctx, uid, step, codeHashes)
+}
+
+// FindByUid mocks base method.
+func (m *MockUserTOTPDAO) FindByUid(ctx context.Context, uid int64) (dao.UserTOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByUid", ctx, uid)
+	ret0, _ := ret[0].(dao.UserTOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByUid indicates an expected call of FindByUid.
+func (mr *MockUserTOTPDAOMockRecorder) FindByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUid", reflect.TypeOf((*MockUserTOTPDAO)(nil).FindByUid), ctx, uid)
+}
+
+// Upsert mocks base method.
+func (m *MockUserTOTPDAO) Upsert(ctx context.Context, t dao.UserTOTP) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upsert", ctx, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Upsert indicates an expected call of Upsert.
+func (mr *MockUserTOTPDAOMockRecorder) Upsert(ctx, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upsert", reflect.TypeOf((*MockUserTOTPDAO)(nil).Upsert), ctx, t)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockUserTOTPDAO) UseRecoveryCode(ctx context.Context, uid int64, codeHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, uid, codeHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockUserTOTPDAOMockRecorder) UseRecoveryCode(ctx, uid, codeHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockUserTOTPDAO)(nil).UseRecoveryCode), ctx, uid, codeHash)
+}
+
+// UseStep mocks base method.
+func (m *MockUserTOTPDAO) UseStep(ctx context.Context, uid, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseStep", ctx, uid, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseStep indicates an expected call of UseStep.
+func (mr *MockUserTOTPDAOMockRecorder) UseStep(ctx, uid, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseStep", reflect.TypeOf((*MockUserTOTPDAO)(nil).UseStep), ctx, uid, step)
+}
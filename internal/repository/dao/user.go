@@ -12,15 +12,35 @@ import (
 var (
 	ErrDuplicateEmail = errors.New("邮箱冲突")
 	ErrRecordNotFound = gorm.ErrRecordNotFound
+	// ErrUserStatusFrozen 已经注销或者被合并的账号，状态不能再改了
+	ErrUserStatusFrozen = errors.New("账号已经注销或者被合并")
+)
+
+// 和 domain.UserStatus 保持一致
+const (
+	userStatusActive uint8 = 0
+	userStatusBanned uint8 = 1
 )
 
 type UserDAO interface {
-	Insert(ctx context.Context, u User) error
+	// Insert 返回新用户的 id
+	Insert(ctx context.Context, u User) (int64, error)
 	FindByName(ctx context.Context, email string) (User, error)
 	Edit(ctx context.Context, user User) error
 	FindById(ctx context.Context, id int64) (User, error)
 	FindByPhone(ctx context.Context, phone string) (User, error)
 	FindByWeChat(ctx context.Context, openId string) (User, error)
+	// List 按照 id 顺序分页查询
+	List(ctx context.Context, offset int, limit int) ([]User, error)
+	// UpdateStatus 只能在正常和封禁之间切换
+	// 已经注销或者被合并的账号返回 ErrUserStatusFrozen
+	UpdateStatus(ctx context.Context, id int64, status uint8) error
+	// UpdatePassword password 是已经加密过的密码
+	UpdatePassword(ctx context.Context, id int64, password string) error
+	// VerifyEmail 邮箱要和验证的时候一致，防止验证期间邮箱被改掉
+	VerifyEmail(ctx context.Context, id int64, email string) error
+	// UpdateIdentity 覆盖邮箱、手机号和微信这些登录方式相关的字段
+	UpdateIdentity(ctx context.Context, u User) error
 }
 
 type GORMUserDAO struct {
@@ -39,7 +59,7 @@ func NewUserDAO(db *gorm.DB) UserDAO {
 	}
 }
 
-func (dao *GORMUserDAO) Insert(ctx context.Context, u User) error {
+func (dao *GORMUserDAO) Insert(ctx context.Context, u User) (int64, error) {
 	now := time.Now().UnixMilli()
 	u.Ctime = now
 	u.Utime = now
@@ -49,10 +69,10 @@ func (dao *GORMUserDAO) Insert(ctx context.Context, u User) error {
 		const duplicateErr uint16 = 1062
 		if me.Number == duplicateErr {
 			//用户冲突，邮箱冲突
-			return ErrDuplicateEmail
+			return 0, ErrDuplicateEmail
 		}
 	}
-	return err
+	return u.Id, err
 }
 
 func (dao *GORMUserDAO) FindByName(ctx context.Context, email string) (User, error) {
@@ -76,6 +96,92 @@ func (dao *GORMUserDAO) FindById(ctx context.Context, id int64) (User, error) {
 	return u, err
 }
 
+func (dao *GORMUserDAO) List(ctx context.Context, offset int, limit int) ([]User, error) {
+	var res []User
+	err := dao.db.WithContext(ctx).Order("id").Offset(offset).Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMUserDAO) UpdateStatus(ctx context.Context, id int64, status uint8) error {
+	db := dao.db.WithContext(ctx)
+	res := db.Model(&User{}).
+		Where("id=? AND status IN (?,?)", id, userStatusActive, userStatusBanned).
+		Updates(map[string]any{
+			"utime":  time.Now().UnixMilli(),
+			"status": status,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+	// 区分一下是不存在还是不能改
+	var cnt int64
+	err := db.Model(&User{}).Where("id=?", id).Count(&cnt).Error
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return ErrRecordNotFound
+	}
+	return ErrUserStatusFrozen
+}
+
+func (dao *GORMUserDAO) UpdatePassword(ctx context.Context, id int64, password string) error {
+	res := dao.db.WithContext(ctx).Model(&User{}).Where("id=?", id).Updates(map[string]any{
+		"utime":    time.Now().UnixMilli(),
+		"password": password,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (dao *GORMUserDAO) VerifyEmail(ctx context.Context, id int64, email string) error {
+	res := dao.db.WithContext(ctx).Model(&User{}).Where("id=? AND email=?", id, email).Updates(map[string]any{
+		"utime":          time.Now().UnixMilli(),
+		"email_verified": true,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (dao *GORMUserDAO) UpdateIdentity(ctx context.Context, u User) error {
+	res := dao.db.WithContext(ctx).Model(&User{}).Where("id=?", u.Id).Updates(map[string]any{
+		"utime":           time.Now().UnixMilli(),
+		"email":           u.Email,
+		"email_verified":  u.EmailVerified,
+		"phone":           u.Phone,
+		"wechat_open_id":  u.WechatOpenId,
+		"wechat_union_id": u.WechatUnionId,
+	})
+	var me *mysql.MySQLError
+	if errors.As(res.Error, &me) {
+		const duplicateErr uint16 = 1062
+		if me.Number == duplicateErr {
+			// 要绑定的身份已经属于别的用户了
+			return ErrDuplicateEmail
+		}
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
 func (dao *GORMUserDAO) FindByPhone(ctx context.Context, phone string) (User, error) {
 	var u User
 	err := dao.db.WithContext(ctx).Where("phone=?", phone).First(&u).Error
@@ -86,14 +192,18 @@ type User struct {
 	Id int64 `gorm:"primaryKey,autoIncrement"`
 	// 这是一个可以为NULL的列
 	// Email   *string
-	Email    sql.NullString `gorm:"unique"`
-	Password string
-	NickName string
-	Birthday int64
-	AboutMe  string
-	Phone    sql.NullString `gorm:"unique"`
-	Ctime    int64
-	Utime    int64
+	Email         sql.NullString `gorm:"unique"`
+	EmailVerified bool
+	Password      string
+	NickName      string
+	Birthday      int64
+	AboutMe       string
+	Phone         sql.NullString `gorm:"unique"`
+	// Roles 逗号分隔的角色
+	Roles  string
+	Status uint8
+	Ctime  int64
+	Utime  int64
 
 	WechatOpenId  sql.NullString `gorm:"unique"`
 	WechatUnionId sql.NullString
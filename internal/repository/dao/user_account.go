@@ -0,0 +1,126 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// userStatusDeleted 和 domain.UserStatusDeleted 保持一致
+const userStatusDeleted uint8 = 3
+
+// 和 domain.ArticleStatus 保持一致
+const (
+	articleStatusPublished uint8 = 2
+	articleStatusPrivate   uint8 = 3
+)
+
+// UserAccountDAO 用户自己管理账号数据，注销和导出
+type UserAccountDAO interface {
+	// Delete 在一个事务里面注销账号：抹掉个人信息，撤回已经发表的文章
+	// 已经注销或者被合并的账号返回 ErrRecordNotFound
+	Delete(ctx context.Context, uid int64) error
+	// Export 查询用户的资料、文章、点赞和收藏
+	Export(ctx context.Context, uid int64) (UserArchive, error)
+}
+
+type GORMUserAccountDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMUserAccountDAO(db *gorm.DB) UserAccountDAO {
+	return &GORMUserAccountDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMUserAccountDAO) Delete(ctx context.Context, uid int64) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UnixMilli()
+		var u User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", uid).First(&u).Error
+		if err != nil {
+			return err
+		}
+		if u.Status == userStatusDeleted || u.Status == userStatusMerged {
+			return ErrRecordNotFound
+		}
+		// 行留着当墓碑，文章之类的数据还指向它
+		// 邮箱、手机号和微信置为 NULL，唯一索引就腾出来了，以后还可以用来注册新账号
+		err = tx.Model(&User{}).Where("id = ?", uid).Updates(map[string]any{
+			"email":           sql.NullString{},
+			"email_verified":  false,
+			"password":        "",
+			"nick_name":       "",
+			"birthday":        0,
+			"about_me":        "",
+			"phone":           sql.NullString{},
+			"wechat_open_id":  sql.NullString{},
+			"wechat_union_id": sql.NullString{},
+			"roles":           "",
+			"status":          userStatusDeleted,
+			"utime":           now,
+		}).Error
+		if err != nil {
+			return err
+		}
+		// 已经发表的文章撤回，读者就看不到了
+		updates := map[string]any{
+			"status": articleStatusPrivate,
+			"utime":  now,
+		}
+		err = tx.Model(&Article{}).
+			Where("author_id = ? AND status = ?", uid, articleStatusPublished).
+			Updates(updates).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&PublishedArticle{}).
+			Where("author_id = ? AND status = ?", uid, articleStatusPublished).
+			Updates(updates).Error
+		if err != nil {
+			return err
+		}
+		// 两步验证的密钥和恢复码也没用了
+		err = tx.Where("uid = ?", uid).Delete(&UserTOTP{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("uid = ?", uid).Delete(&UserRecoveryCode{}).Error
+	})
+}
+
+func (dao *GORMUserAccountDAO) Export(ctx context.Context, uid int64) (UserArchive, error) {
+	var res UserArchive
+	db := dao.db.WithContext(ctx)
+	err := db.Where("id = ?", uid).First(&res.User).Error
+	if err != nil {
+		return UserArchive{}, err
+	}
+	// 制作库里面是全部的文章，包括没有发表的
+	err = db.Where("author_id = ?", uid).Order("id").Find(&res.Articles).Error
+	if err != nil {
+		return UserArchive{}, err
+	}
+	// 取消了的点赞不算
+	err = db.Where("uid = ? AND status = ?", uid, 1).Order("id").Find(&res.Likes).Error
+	if err != nil {
+		return UserArchive{}, err
+	}
+	err = db.Where("uid = ?", uid).Order("id").Find(&res.Collects).Error
+	if err != nil {
+		return UserArchive{}, err
+	}
+	return res, nil
+}
+
+// UserArchive 用户的个人数据
+type UserArchive struct {
+	User     User
+	Articles []Article
+	Likes    []UserLikeBiz
+	Collects []UserCollectionBiz
+}
@@ -0,0 +1,95 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestGORMUserAccountDAO_Delete(t *testing.T) {
+	userCols := []string{"id", "email", "phone", "status"}
+	testCases := []struct {
+		name string
+		mock func(t *testing.T) *sql.DB
+
+		wantErr error
+	}{
+		{
+			name: "注销成功",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT .* FOR UPDATE").WillReturnRows(
+					sqlmock.NewRows(userCols).AddRow(1, "123@qq.com", "15811111111", 0))
+				// 个人信息都抹掉，邮箱、手机号和微信置为 NULL
+				mock.ExpectExec("UPDATE `users` SET .* WHERE id = \\?").
+					WithArgs("", 0, nil, false, "", "", nil, "", userStatusDeleted,
+						sqlmock.AnyArg(), nil, nil, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE `articles` SET .* WHERE author_id = \\? AND status = \\?").
+					WithArgs(articleStatusPrivate, sqlmock.AnyArg(), 1, articleStatusPublished).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("UPDATE `published_articles` SET .* WHERE author_id = \\? AND status = \\?").
+					WithArgs(articleStatusPrivate, sqlmock.AnyArg(), 1, articleStatusPublished).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("DELETE FROM `user_totps` WHERE uid = \\?").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM `user_recovery_codes` WHERE uid = \\?").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 10))
+				mock.ExpectCommit()
+				return db
+			},
+		},
+		{
+			name: "用户不存在",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT .* FOR UPDATE").
+					WillReturnRows(sqlmock.NewRows(userCols))
+				mock.ExpectRollback()
+				return db
+			},
+			wantErr: ErrRecordNotFound,
+		},
+		{
+			name: "已经注销过了",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT .* FOR UPDATE").WillReturnRows(
+					sqlmock.NewRows(userCols).AddRow(1, nil, nil, userStatusDeleted))
+				mock.ExpectRollback()
+				return db
+			},
+			wantErr: ErrRecordNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlDB := tc.mock(t)
+			db, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      sqlDB,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			dao := NewGORMUserAccountDAO(db)
+			err = dao.Delete(context.Background(), 1)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// userStatusMerged 和 domain.UserStatusMerged 保持一致
+const userStatusMerged uint8 = 2
+
+var ErrUserMerged = errors.New("用户已经被合并")
+
+type UserMergeDAO interface {
+	// Merge 在一个事务里面把 source 合并到 target，并且记录审计日志
+	// 返回合并了哪些数据，已经注销的账号当作不存在，返回 ErrRecordNotFound
+	Merge(ctx context.Context, sourceId int64, targetId int64, operator int64) (MergeDetail, error)
+}
+
+type GORMUserMergeDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMUserMergeDAO(db *gorm.DB) UserMergeDAO {
+	return &GORMUserMergeDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMUserMergeDAO) Merge(ctx context.Context, sourceId int64, targetId int64, operator int64) (MergeDetail, error) {
+	var detail MergeDetail
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UnixMilli()
+		// 按照 id 顺序加锁，避免两个方向同时合并的时候死锁
+		var users []User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []int64{sourceId, targetId}).
+			Order("id").Find(&users).Error
+		if err != nil {
+			return err
+		}
+		if len(users) != 2 {
+			return ErrRecordNotFound
+		}
+		source, target := users[0], users[1]
+		if source.Id != sourceId {
+			source, target = target, source
+		}
+		if source.Status == userStatusMerged || target.Status == userStatusMerged {
+			return ErrUserMerged
+		}
+		// 注销的账号只剩墓碑，合并进去的话邮箱和密码又能登录了
+		if source.Status == userStatusDeleted || target.Status == userStatusDeleted {
+			return ErrRecordNotFound
+		}
+
+		detail, err = dao.mergeIdentity(tx, source, target, now)
+		if err != nil {
+			return err
+		}
+		detail.Articles, err = dao.mergeArticles(tx, sourceId, targetId, now)
+		if err != nil {
+			return err
+		}
+		var keys []InteractiveKey
+		detail.Likes, keys, err = dao.mergeLikes(tx, sourceId, targetId, now)
+		if err != nil {
+			return err
+		}
+		detail.Interactives = append(detail.Interactives, keys...)
+		detail.Collects, keys, err = dao.mergeCollects(tx, sourceId, targetId, now)
+		if err != nil {
+			return err
+		}
+		detail.Interactives = append(detail.Interactives, keys...)
+
+		data, err := json.Marshal(detail)
+		if err != nil {
+			return err
+		}
+		return tx.Create(&UserMergeLog{
+			SourceId: sourceId,
+			TargetId: targetId,
+			Operator: operator,
+			Detail:   string(data),
+			Ctime:    now,
+		}).Error
+	})
+	return detail, err
+}
+
+// mergeIdentity target 没有的登录方式从 source 拿过来，source 变成墓碑
+// 两边都有的时候保留 target 的
+func (dao *GORMUserMergeDAO) mergeIdentity(tx *gorm.DB, source User, target User, now int64) (MergeDetail, error) {
+	var detail MergeDetail
+	updates := map[string]any{"utime": now}
+	if !target.Email.Valid && source.Email.Valid {
+		updates["email"] = source.Email
+		updates["email_verified"] = source.EmailVerified
+		// 邮箱要配合密码才能登录
+		if target.Password == "" {
+			updates["password"] = source.Password
+		}
+		detail.Email = true
+	}
+	if !target.Phone.Valid && source.Phone.Valid {
+		updates["phone"] = source.Phone
+		detail.Phone = true
+	}
+	if !target.WechatOpenId.Valid && source.WechatOpenId.Valid {
+		updates["wechat_open_id"] = source.WechatOpenId
+		updates["wechat_union_id"] = source.WechatUnionId
+		detail.Wechat = true
+	}
+	// 先把 source 的唯一索引腾出来，再更新 target
+	err := tx.Model(&User{}).Where("id = ?", source.Id).Updates(map[string]any{
+		"email":           sql.NullString{},
+		"phone":           sql.NullString{},
+		"wechat_open_id":  sql.NullString{},
+		"wechat_union_id": sql.NullString{},
+		"status":          userStatusMerged,
+		"utime":           now,
+	}).Error
+	if err != nil {
+		return detail, err
+	}
+	return detail, tx.Model(&User{}).Where("id = ?", target.Id).Updates(updates).Error
+}
+
+// mergeArticles 制作库和线上库的作者都要改
+func (dao *GORMUserMergeDAO) mergeArticles(tx *gorm.DB, sourceId int64, targetId int64, now int64) (int64, error) {
+	res := tx.Model(&Article{}).Where("author_id = ?", sourceId).Updates(map[string]any{
+		"author_id": targetId,
+		"utime":     now,
+	})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	err := tx.Model(&PublishedArticle{}).Where("author_id = ?", sourceId).Updates(map[string]any{
+		"author_id": targetId,
+		"utime":     now,
+	}).Error
+	return res.RowsAffected, err
+}
+
+// mergeLikes 两个账号都点赞了同一个资源，合并之后只算一次
+func (dao *GORMUserMergeDAO) mergeLikes(tx *gorm.DB, sourceId int64, targetId int64, now int64) (int64, []InteractiveKey, error) {
+	var likes []UserLikeBiz
+	err := tx.Where("uid = ?", sourceId).Find(&likes).Error
+	if err != nil {
+		return 0, nil, err
+	}
+	var cnt int64
+	keys := make([]InteractiveKey, 0, len(likes))
+	for _, l := range likes {
+		keys = append(keys, InteractiveKey{Biz: l.Biz, BizId: l.BizId})
+		var exist UserLikeBiz
+		err = tx.Where("uid = ? AND biz = ? AND biz_id = ?", targetId, l.Biz, l.BizId).
+			First(&exist).Error
+		switch {
+		case err == nil:
+			// 冲突了，source 的记录删掉
+			err = tx.Delete(&UserLikeBiz{}, l.Id).Error
+			if err != nil {
+				return 0, nil, err
+			}
+			if l.Status != 1 {
+				continue
+			}
+			if exist.Status == 1 {
+				// 两个都点赞了，点赞数多算了一次
+				err = tx.Model(&Interactive{}).Where("biz = ? AND biz_id = ?", l.Biz, l.BizId).
+					Updates(map[string]any{
+						"like_cnt": gorm.Expr("`like_cnt` - 1"),
+						"utime":    now,
+					}).Error
+			} else {
+				// target 取消过点赞，用 source 的点赞，点赞数不变
+				err = tx.Model(&UserLikeBiz{}).Where("id = ?", exist.Id).Updates(map[string]any{
+					"status": 1,
+					"utime":  now,
+				}).Error
+			}
+		case errors.Is(err, ErrRecordNotFound):
+			err = tx.Model(&UserLikeBiz{}).Where("id = ?", l.Id).Updates(map[string]any{
+				"uid":   targetId,
+				"utime": now,
+			}).Error
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		cnt++
+	}
+	return cnt, keys, nil
+}
+
+// mergeCollects 两个账号都收藏了同一个资源，保留 target 的收藏夹
+func (dao *GORMUserMergeDAO) mergeCollects(tx *gorm.DB, sourceId int64, targetId int64, now int64) (int64, []InteractiveKey, error) {
+	var collects []UserCollectionBiz
+	err := tx.Where("uid = ?", sourceId).Find(&collects).Error
+	if err != nil {
+		return 0, nil, err
+	}
+	var cnt int64
+	keys := make([]InteractiveKey, 0, len(collects))
+	for _, c := range collects {
+		keys = append(keys, InteractiveKey{Biz: c.Biz, BizId: c.BizId})
+		var exist UserCollectionBiz
+		err = tx.Where("uid = ? AND biz = ? AND biz_id = ?", targetId, c.Biz, c.BizId).
+			First(&exist).Error
+		switch {
+		case err == nil:
+			err = tx.Delete(&UserCollectionBiz{}, c.Id).Error
+			if err != nil {
+				return 0, nil, err
+			}
+			err = tx.Model(&Interactive{}).Where("biz = ? AND biz_id = ?", c.Biz, c.BizId).
+				Updates(map[string]any{
+					"collect_cnt": gorm.Expr("`collect_cnt` - 1"),
+					"utime":       now,
+				}).Error
+		case errors.Is(err, ErrRecordNotFound):
+			err = tx.Model(&UserCollectionBiz{}).Where("id = ?", c.Id).Updates(map[string]any{
+				"uid":   targetId,
+				"utime": now,
+			}).Error
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		cnt++
+	}
+	return cnt, keys, nil
+}
+
+// MergeDetail 合并了哪些数据，会以 JSON 的形式记录在审计日志里面
+type MergeDetail struct {
+	Email    bool  `json:"email"`
+	Phone    bool  `json:"phone"`
+	Wechat   bool  `json:"wechat"`
+	Articles int64 `json:"articles"`
+	Likes    int64 `json:"likes"`
+	Collects int64 `json:"collects"`
+	// Interactives 点赞和收藏被挪过来的资源，计数可能变了，不记到审计日志里面
+	Interactives []InteractiveKey `json:"-"`
+}
+
+// InteractiveKey 一个被点赞或者收藏的资源
+type InteractiveKey struct {
+	Biz   string
+	BizId int64
+}
+
+// UserMergeLog 账号合并的审计日志
+type UserMergeLog struct {
+	Id       int64 `gorm:"primaryKey,autoIncrement"`
+	SourceId int64 `gorm:"index"`
+	TargetId int64 `gorm:"index"`
+	// Operator 操作的管理员
+	Operator int64
+	Detail   string `gorm:"type:varchar(1024)"`
+	Ctime    int64
+}
@@ -0,0 +1,153 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestGORMUserMergeDAO_Merge(t *testing.T) {
+	userCols := []string{"id", "email", "phone", "wechat_open_id", "password", "status"}
+	testCases := []struct {
+		name string
+		mock func(t *testing.T) *sql.DB
+
+		wantDetail MergeDetail
+		wantErr    error
+	}{
+		{
+			name: "合并成功",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				// source 只有手机号，target 只有邮箱
+				mock.ExpectQuery("SELECT .* FOR UPDATE").WillReturnRows(
+					sqlmock.NewRows(userCols).
+						AddRow(1, nil, "15811111111", nil, "", 0).
+						AddRow(2, "123@qq.com", nil, nil, "hash", 0))
+				// 先腾出 source 的唯一索引，再更新 target
+				mock.ExpectExec("UPDATE `users` SET .* WHERE id = ?").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE `users` SET .*`phone`.* WHERE id = ?").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE `articles` SET").
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec("UPDATE `published_articles` SET").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				// source 点赞了两篇文章，其中 11 target 也点赞了
+				mock.ExpectQuery("SELECT .* FROM `user_like_bizs`").WillReturnRows(
+					sqlmock.NewRows([]string{"id", "uid", "biz_id", "biz", "status"}).
+						AddRow(100, 1, 10, "article", 1).
+						AddRow(101, 1, 11, "article", 1))
+				mock.ExpectQuery("SELECT .* FROM `user_like_bizs`").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectExec("UPDATE `user_like_bizs` SET").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT .* FROM `user_like_bizs`").WillReturnRows(
+					sqlmock.NewRows([]string{"id", "uid", "biz_id", "biz", "status"}).
+						AddRow(200, 2, 11, "article", 1))
+				mock.ExpectExec("DELETE FROM `user_like_bizs`").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE `interactives` SET `like_cnt`=`like_cnt` - 1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT .* FROM `user_collection_bizs`").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectExec("INSERT INTO `user_merge_logs`").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+				return db
+			},
+			wantDetail: MergeDetail{
+				Phone:    true,
+				Articles: 3,
+				Likes:    2,
+				Interactives: []InteractiveKey{
+					{Biz: "article", BizId: 10},
+					{Biz: "article", BizId: 11},
+				},
+			},
+		},
+		{
+			name: "用户不存在",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT .* FOR UPDATE").WillReturnRows(
+					sqlmock.NewRows(userCols).AddRow(2, "123@qq.com", nil, nil, "hash", 0))
+				mock.ExpectRollback()
+				return db
+			},
+			wantErr: ErrRecordNotFound,
+		},
+		{
+			name: "已经被合并过了",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT .* FOR UPDATE").WillReturnRows(
+					sqlmock.NewRows(userCols).
+						AddRow(1, nil, nil, nil, "", userStatusMerged).
+						AddRow(2, "123@qq.com", nil, nil, "hash", 0))
+				mock.ExpectRollback()
+				return db
+			},
+			wantErr: ErrUserMerged,
+		},
+		{
+			name: "合并到已经注销的账号",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT .* FOR UPDATE").WillReturnRows(
+					sqlmock.NewRows(userCols).
+						AddRow(1, "123@qq.com", nil, nil, "hash", 0).
+						AddRow(2, nil, nil, nil, "", userStatusDeleted))
+				mock.ExpectRollback()
+				return db
+			},
+			wantErr: ErrRecordNotFound,
+		},
+		{
+			name: "合并已经注销的账号",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT .* FOR UPDATE").WillReturnRows(
+					sqlmock.NewRows(userCols).
+						AddRow(1, nil, nil, nil, "", userStatusDeleted).
+						AddRow(2, "123@qq.com", nil, nil, "hash", 0))
+				mock.ExpectRollback()
+				return db
+			},
+			wantErr: ErrRecordNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlDB := tc.mock(t)
+			db, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      sqlDB,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			dao := NewGORMUserMergeDAO(db)
+			detail, err := dao.Merge(context.Background(), 1, 2, 99)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantDetail, detail)
+		})
+	}
+}
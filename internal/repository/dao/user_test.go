@@ -18,6 +18,7 @@ func TestGORMUserDAO_Insert(t *testing.T) {
 		mock    func(t *testing.T) *sql.DB
 		ctx     context.Context
 		user    User
+		wantId  int64
 		wantErr error
 	}{
 		{
@@ -35,6 +36,7 @@ func TestGORMUserDAO_Insert(t *testing.T) {
 			user: User{
 				NickName: "Tom",
 			},
+			wantId:  1,
 			wantErr: nil,
 		},
 		{
@@ -85,7 +87,71 @@ func TestGORMUserDAO_Insert(t *testing.T) {
 			})
 			assert.NoError(t, err)
 			dao := NewUserDAO(db)
-			err = dao.Insert(tc.ctx, tc.user)
+			id, err := dao.Insert(tc.ctx, tc.user)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantId, id)
+		})
+	}
+}
+
+func TestGORMUserDAO_UpdateStatus(t *testing.T) {
+	testCase := []struct {
+		name    string
+		mock    func(t *testing.T) *sql.DB
+		wantErr error
+	}{
+		{
+			name: "修改成功",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE `users` SET .* WHERE id=\\? AND status IN \\(\\?,\\?\\)").
+					WithArgs(userStatusBanned, sqlmock.AnyArg(), 123, userStatusActive, userStatusBanned).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				return db
+			},
+		},
+		{
+			name: "已经被合并或者注销了",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE `users` .*").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users` WHERE id=\\?").
+					WithArgs(123).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				return db
+			},
+			wantErr: ErrUserStatusFrozen,
+		},
+		{
+			name: "用户不存在",
+			mock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE `users` .*").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users` WHERE id=\\?").
+					WithArgs(123).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				return db
+			},
+			wantErr: ErrRecordNotFound,
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlDB := tc.mock(t)
+			db, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      sqlDB,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			assert.NoError(t, err)
+			dao := NewUserDAO(db)
+			err = dao.UpdateStatus(context.Background(), 123, userStatusBanned)
 			assert.Equal(t, tc.wantErr, err)
 		})
 	}
@@ -0,0 +1,150 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var ErrTOTPStepUsed = errors.New("验证码已经用过了")
+
+type UserTOTPDAO interface {
+	// Upsert 开始绑定，还没有启用的时候可以反复覆盖密钥，已经启用了就什么都不做
+	Upsert(ctx context.Context, t UserTOTP) error
+	FindByUid(ctx context.Context, uid int64) (UserTOTP, error)
+	// Enable 启用两步验证，同时替换掉所有的恢复码
+	// step 是启用的时候用掉的周期
+	Enable(ctx context.Context, uid int64, step int64, codeHashes []string) error
+	// Disable 删掉密钥和恢复码
+	Disable(ctx context.Context, uid int64) error
+	// UseStep 记录用掉的周期，比记录的周期小或者相等就说明验证码被重复使用了
+	UseStep(ctx context.Context, uid int64, step int64) error
+	// UseRecoveryCode 每个恢复码只能用一次，不存在或者用过了返回 ErrRecordNotFound
+	UseRecoveryCode(ctx context.Context, uid int64, codeHash string) error
+}
+
+type GORMUserTOTPDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMUserTOTPDAO(db *gorm.DB) UserTOTPDAO {
+	return &GORMUserTOTPDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMUserTOTPDAO) Upsert(ctx context.Context, t UserTOTP) error {
+	now := time.Now().UnixMilli()
+	t.Enabled = false
+	t.Ctime = now
+	t.Utime = now
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{
+			// 已经启用了就不能覆盖，不然别人拿到会话就能把两步验证换掉
+			"secret": gorm.Expr("IF(`enabled`, `secret`, VALUES(`secret`))"),
+			"utime":  now,
+		}),
+	}).Create(&t).Error
+}
+
+func (dao *GORMUserTOTPDAO) FindByUid(ctx context.Context, uid int64) (UserTOTP, error) {
+	var t UserTOTP
+	err := dao.db.WithContext(ctx).Where("uid = ?", uid).First(&t).Error
+	return t, err
+}
+
+func (dao *GORMUserTOTPDAO) Enable(ctx context.Context, uid int64, step int64, codeHashes []string) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&UserTOTP{}).Where("uid = ? AND enabled = ?", uid, false).
+			Updates(map[string]any{
+				"enabled":   true,
+				"last_step": step,
+				"utime":     now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// 没有开始绑定，或者已经启用了
+			return ErrRecordNotFound
+		}
+		err := tx.Where("uid = ?", uid).Delete(&UserRecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+		codes := make([]UserRecoveryCode, 0, len(codeHashes))
+		for _, h := range codeHashes {
+			codes = append(codes, UserRecoveryCode{
+				Uid:      uid,
+				CodeHash: h,
+				Ctime:    now,
+			})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (dao *GORMUserTOTPDAO) Disable(ctx context.Context, uid int64) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("uid = ?", uid).Delete(&UserTOTP{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("uid = ?", uid).Delete(&UserRecoveryCode{}).Error
+	})
+}
+
+func (dao *GORMUserTOTPDAO) UseStep(ctx context.Context, uid int64, step int64) error {
+	res := dao.db.WithContext(ctx).Model(&UserTOTP{}).
+		Where("uid = ? AND last_step < ?", uid, step).
+		Updates(map[string]any{
+			"last_step": step,
+			"utime":     time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTOTPStepUsed
+	}
+	return nil
+}
+
+func (dao *GORMUserTOTPDAO) UseRecoveryCode(ctx context.Context, uid int64, codeHash string) error {
+	// 直接删除，并发使用同一个恢复码的时候只有一个能删掉
+	res := dao.db.WithContext(ctx).
+		Where("uid = ? AND code_hash = ?", uid, codeHash).
+		Delete(&UserRecoveryCode{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// UserTOTP 用户的 TOTP 密钥
+type UserTOTP struct {
+	Id     int64  `gorm:"primaryKey,autoIncrement"`
+	Uid    int64  `gorm:"uniqueIndex"`
+	Secret string `gorm:"type:varchar(64)"`
+	// Enabled 扫码之后输入过一次正确的验证码才算启用
+	Enabled bool
+	// LastStep 最后一次用掉的周期
+	LastStep int64
+	Ctime    int64
+	Utime    int64
+}
+
+// UserRecoveryCode 丢了手机的时候用来代替验证码，每个只能用一次
+type UserRecoveryCode struct {
+	Id  int64 `gorm:"primaryKey,autoIncrement"`
+	Uid int64 `gorm:"uniqueIndex:uid_code_hash"`
+	// CodeHash 恢复码的 SHA-256，恢复码是随机生成的，熵足够，不需要加盐
+	CodeHash string `gorm:"type:char(64);uniqueIndex:uid_code_hash"`
+	Ctime    int64
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"github.com/ecodeclub/ekit/slice"
+	"webook/internal/domain"
+	"webook/internal/repository/cache"
+	"webook/internal/repository/dao"
+	"webook/pkg/logger"
+)
+
+type InteractiveRepository interface {
+	IncrReadCnt(ctx context.Context, biz string, bizId int64) error
+	BatchIncrReadCnt(ctx context.Context, biz string, cnts map[int64]int64) error
+	IncrLike(ctx context.Context, biz string, bizId, uid int64) error
+	DecrLike(ctx context.Context, biz string, bizId, uid int64) error
+	AddCollectionItem(ctx context.Context, biz string, bizId, cid, uid int64) error
+	Get(ctx context.Context, biz string, bizId int64) (domain.Interactive, error)
+	Liked(ctx context.Context, biz string, bizId, uid int64) (bool, error)
+	Collected(ctx context.Context, biz string, bizId, uid int64) (bool, error)
+	GetByIds(ctx context.Context, biz string, ids []int64) ([]domain.Interactive, error)
+}
+
+type CachedInteractiveRepository struct {
+	dao   dao.InteractiveDAO
+	cache cache.InteractiveCache
+	l     logger.LoggerV1
+}
+
+func NewCachedInteractiveRepository(dao dao.InteractiveDAO, cache cache.InteractiveCache, l logger.LoggerV1) InteractiveRepository {
+	return &CachedInteractiveRepository{
+		dao:   dao,
+		cache: cache,
+		l:     l,
+	}
+}
+
+// IncrReadCnt 先写数据库，再更新缓存。缓存更新失败也没关系，阅读数不要求精确
+func (c *CachedInteractiveRepository) IncrReadCnt(ctx context.Context, biz string, bizId int64) error {
+	err := c.dao.IncrReadCnt(ctx, biz, bizId)
+	if err != nil {
+		return err
+	}
+	err = c.cache.IncrReadCntIfPresent(ctx, biz, bizId)
+	if err != nil {
+		c.l.Error("更新缓存的阅读数失败", logger.String("biz", biz), logger.Int64("bizId", bizId), logger.Error(err))
+	}
+	return nil
+}
+
+// BatchIncrReadCnt 和 IncrReadCnt 一样，缓存更新失败只记日志。
+// 返回错误的话调用方会重试，数据库里的阅读数就加了两次
+func (c *CachedInteractiveRepository) BatchIncrReadCnt(ctx context.Context, biz string, cnts map[int64]int64) error {
+	err := c.dao.BatchIncrReadCnt(ctx, biz, cnts)
+	if err != nil {
+		return err
+	}
+	err = c.cache.BatchIncrReadCntIfPresent(ctx, biz, cnts)
+	if err != nil {
+		c.l.Error("批量更新缓存的阅读数失败", logger.String("biz", biz), logger.Error(err))
+	}
+	return nil
+}
+
+func (c *CachedInteractiveRepository) IncrLike(ctx context.Context, biz string, bizId, uid int64) error {
+	err := c.dao.InsertLikeInfo(ctx, biz, bizId, uid)
+	if err == dao.ErrLikeExists {
+		// 重复点赞当作成功，缓存里的点赞数也不能再加
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return c.cache.IncrLikeCntIfPresent(ctx, biz, bizId)
+}
+
+func (c *CachedInteractiveRepository) DecrLike(ctx context.Context, biz string, bizId, uid int64) error {
+	err := c.dao.DeleteLikeInfo(ctx, biz, bizId, uid)
+	if err == dao.ErrLikeNotExists {
+		// 本来就没点赞，缓存里的点赞数也不能减
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return c.cache.DecrLikeCntIfPresent(ctx, biz, bizId)
+}
+
+func (c *CachedInteractiveRepository) AddCollectionItem(ctx context.Context, biz string, bizId, cid, uid int64) error {
+	err := c.dao.InsertCollectionBiz(ctx, dao.UserCollectionBiz{
+		Cid:   cid,
+		Uid:   uid,
+		Biz:   biz,
+		BizId: bizId,
+	})
+	if err == dao.ErrCollectionExists {
+		// 重复收藏当作成功，缓存里的收藏数也不能再加
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return c.cache.IncrCollectCntIfPresent(ctx, biz, bizId)
+}
+
+func (c *CachedInteractiveRepository) Get(ctx context.Context, biz string, bizId int64) (domain.Interactive, error) {
+	intr, err := c.cache.Get(ctx, biz, bizId)
+	if err == nil {
+		return intr, nil
+	}
+	ie, err := c.dao.Get(ctx, biz, bizId)
+	if err == dao.ErrRecordNotFound {
+		// 还没有人阅读、点赞或者收藏过
+		return domain.Interactive{Biz: biz, BizId: bizId}, nil
+	}
+	if err != nil {
+		return domain.Interactive{}, err
+	}
+	intr = c.toDomain(ie)
+	err = c.cache.Set(ctx, biz, bizId, intr)
+	if err != nil {
+		c.l.Error("回写缓存失败", logger.String("biz", biz), logger.Int64("bizId", bizId), logger.Error(err))
+	}
+	return intr, nil
+}
+
+func (c *CachedInteractiveRepository) Liked(ctx context.Context, biz string, bizId, uid int64) (bool, error) {
+	_, err := c.dao.GetLikeInfo(ctx, biz, bizId, uid)
+	switch err {
+	case nil:
+		return true, nil
+	case dao.ErrRecordNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (c *CachedInteractiveRepository) Collected(ctx context.Context, biz string, bizId, uid int64) (bool, error) {
+	_, err := c.dao.GetCollectInfo(ctx, biz, bizId, uid)
+	switch err {
+	case nil:
+		return true, nil
+	case dao.ErrRecordNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// GetByIds 批量查询直接走数据库，目前只有榜单计算这种后台任务会用
+func (c *CachedInteractiveRepository) GetByIds(ctx context.Context, biz string, ids []int64) ([]domain.Interactive, error) {
+	intrs, err := c.dao.GetByIds(ctx, biz, ids)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[dao.Interactive, domain.Interactive](intrs, func(idx int, src dao.Interactive) domain.Interactive {
+		return c.toDomain(src)
+	}), nil
+}
+
+func (c *CachedInteractiveRepository) toDomain(ie dao.Interactive) domain.Interactive {
+	return domain.Interactive{
+		Biz:        ie.Biz,
+		BizId:      ie.BizId,
+		ReadCnt:    ie.ReadCnt,
+		LikeCnt:    ie.LikeCnt,
+		CollectCnt: ie.CollectCnt,
+	}
+}
@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"webook/internal/domain"
+	"webook/internal/repository/cache"
+	cachemocks "webook/internal/repository/cache/mocks"
+	"webook/internal/repository/dao"
+	daomocks "webook/internal/repository/dao/mocks"
+	"webook/pkg/logger"
+)
+
+func TestCachedInteractiveRepository_Get(t *testing.T) {
+	testCase := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache)
+		biz      string
+		bizId    int64
+		wantIntr domain.Interactive
+		wantErr  error
+	}{
+		{
+			name: "缓存命中",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				c.EXPECT().Get(gomock.Any(), "article", int64(1)).Return(domain.Interactive{
+					Biz:        "article",
+					BizId:      1,
+					ReadCnt:    10,
+					LikeCnt:    2,
+					CollectCnt: 3,
+				}, nil)
+				return d, c
+			},
+			biz:   "article",
+			bizId: 1,
+			wantIntr: domain.Interactive{
+				Biz:        "article",
+				BizId:      1,
+				ReadCnt:    10,
+				LikeCnt:    2,
+				CollectCnt: 3,
+			},
+		},
+		{
+			name: "缓存未命中，回写缓存",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				c.EXPECT().Get(gomock.Any(), "article", int64(1)).Return(domain.Interactive{}, cache.ErrKeyNotExist)
+				d.EXPECT().Get(gomock.Any(), "article", int64(1)).Return(dao.Interactive{
+					Id:         11,
+					Biz:        "article",
+					BizId:      1,
+					ReadCnt:    10,
+					LikeCnt:    2,
+					CollectCnt: 3,
+					Ctime:      101,
+					Utime:      102,
+				}, nil)
+				c.EXPECT().Set(gomock.Any(), "article", int64(1), domain.Interactive{
+					Biz:        "article",
+					BizId:      1,
+					ReadCnt:    10,
+					LikeCnt:    2,
+					CollectCnt: 3,
+				}).Return(nil)
+				return d, c
+			},
+			biz:   "article",
+			bizId: 1,
+			wantIntr: domain.Interactive{
+				Biz:        "article",
+				BizId:      1,
+				ReadCnt:    10,
+				LikeCnt:    2,
+				CollectCnt: 3,
+			},
+		},
+		{
+			name: "数据库没有记录",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				c.EXPECT().Get(gomock.Any(), "article", int64(1)).Return(domain.Interactive{}, cache.ErrKeyNotExist)
+				d.EXPECT().Get(gomock.Any(), "article", int64(1)).Return(dao.Interactive{}, dao.ErrRecordNotFound)
+				return d, c
+			},
+			biz:   "article",
+			bizId: 1,
+			wantIntr: domain.Interactive{
+				Biz:   "article",
+				BizId: 1,
+			},
+		},
+		{
+			name: "数据库错误",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				c.EXPECT().Get(gomock.Any(), "article", int64(1)).Return(domain.Interactive{}, cache.ErrKeyNotExist)
+				d.EXPECT().Get(gomock.Any(), "article", int64(1)).Return(dao.Interactive{}, errors.New("mock db error"))
+				return d, c
+			},
+			biz:     "article",
+			bizId:   1,
+			wantErr: errors.New("mock db error"),
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c := tc.mock(ctrl)
+			repo := NewCachedInteractiveRepository(d, c, logger.NewNopLogger())
+			intr, err := repo.Get(context.Background(), tc.biz, tc.bizId)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantIntr, intr)
+		})
+	}
+}
+
+func TestCachedInteractiveRepository_IncrReadCnt(t *testing.T) {
+	testCase := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache)
+		wantErr error
+	}{
+		{
+			name: "更新成功",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().IncrReadCnt(gomock.Any(), "article", int64(1)).Return(nil)
+				c.EXPECT().IncrReadCntIfPresent(gomock.Any(), "article", int64(1)).Return(nil)
+				return d, c
+			},
+		},
+		{
+			name: "缓存更新失败不影响结果",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().IncrReadCnt(gomock.Any(), "article", int64(1)).Return(nil)
+				c.EXPECT().IncrReadCntIfPresent(gomock.Any(), "article", int64(1)).
+					Return(errors.New("mock redis error"))
+				return d, c
+			},
+		},
+		{
+			name: "数据库错误",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().IncrReadCnt(gomock.Any(), "article", int64(1)).Return(errors.New("mock db error"))
+				return d, c
+			},
+			wantErr: errors.New("mock db error"),
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c := tc.mock(ctrl)
+			repo := NewCachedInteractiveRepository(d, c, logger.NewNopLogger())
+			err := repo.IncrReadCnt(context.Background(), "article", 1)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestCachedInteractiveRepository_AddCollectionItem(t *testing.T) {
+	testCase := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache)
+		wantErr error
+	}{
+		{
+			name: "收藏成功",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().InsertCollectionBiz(gomock.Any(), gomock.Any()).Return(nil)
+				c.EXPECT().IncrCollectCntIfPresent(gomock.Any(), "article", int64(1)).Return(nil)
+				return d, c
+			},
+		},
+		{
+			name: "重复收藏，缓存不变",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().InsertCollectionBiz(gomock.Any(), gomock.Any()).Return(dao.ErrCollectionExists)
+				return d, c
+			},
+		},
+		{
+			name: "数据库错误",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().InsertCollectionBiz(gomock.Any(), gomock.Any()).Return(errors.New("mock db error"))
+				return d, c
+			},
+			wantErr: errors.New("mock db error"),
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c := tc.mock(ctrl)
+			repo := NewCachedInteractiveRepository(d, c, logger.NewNopLogger())
+			err := repo.AddCollectionItem(context.Background(), "article", 1, 2, 123)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestCachedInteractiveRepository_IncrLike(t *testing.T) {
+	testCase := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache)
+		wantErr error
+	}{
+		{
+			name: "点赞成功",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().InsertLikeInfo(gomock.Any(), "article", int64(1), int64(123)).Return(nil)
+				c.EXPECT().IncrLikeCntIfPresent(gomock.Any(), "article", int64(1)).Return(nil)
+				return d, c
+			},
+		},
+		{
+			name: "重复点赞，点赞数不变",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().InsertLikeInfo(gomock.Any(), "article", int64(1), int64(123)).Return(dao.ErrLikeExists)
+				return d, c
+			},
+		},
+		{
+			name: "数据库错误",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().InsertLikeInfo(gomock.Any(), "article", int64(1), int64(123)).Return(errors.New("mock db error"))
+				return d, c
+			},
+			wantErr: errors.New("mock db error"),
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c := tc.mock(ctrl)
+			repo := NewCachedInteractiveRepository(d, c, logger.NewNopLogger())
+			err := repo.IncrLike(context.Background(), "article", 1, 123)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestCachedInteractiveRepository_DecrLike(t *testing.T) {
+	testCase := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache)
+		wantErr error
+	}{
+		{
+			name: "取消点赞成功",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().DeleteLikeInfo(gomock.Any(), "article", int64(1), int64(123)).Return(nil)
+				c.EXPECT().DecrLikeCntIfPresent(gomock.Any(), "article", int64(1)).Return(nil)
+				return d, c
+			},
+		},
+		{
+			name: "本来就没点赞，点赞数不变",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().DeleteLikeInfo(gomock.Any(), "article", int64(1), int64(123)).Return(dao.ErrLikeNotExists)
+				return d, c
+			},
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c := tc.mock(ctrl)
+			repo := NewCachedInteractiveRepository(d, c, logger.NewNopLogger())
+			err := repo.DecrLike(context.Background(), "article", 1, 123)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository/cache"
+)
+
+type LoginAttemptRepository interface {
+	LockTTL(ctx context.Context, typ string, id string) (time.Duration, error)
+	Fail(ctx context.Context, typ string, id string, p domain.LoginLockPolicy) (time.Duration, error)
+	Reset(ctx context.Context, typ string, id string) error
+}
+
+type CachedLoginAttemptRepository struct {
+	cache cache.LoginAttemptCache
+}
+
+func NewLoginAttemptRepository(c cache.LoginAttemptCache) LoginAttemptRepository {
+	return &CachedLoginAttemptRepository{
+		cache: c,
+	}
+}
+
+func (repo *CachedLoginAttemptRepository) LockTTL(ctx context.Context, typ string, id string) (time.Duration, error) {
+	return repo.cache.LockTTL(ctx, typ, id)
+}
+
+func (repo *CachedLoginAttemptRepository) Fail(ctx context.Context, typ string, id string, p domain.LoginLockPolicy) (time.Duration, error) {
+	return repo.cache.Fail(ctx, typ, id, p)
+}
+
+func (repo *CachedLoginAttemptRepository) Reset(ctx context.Context, typ string, id string) error {
+	return repo.cache.Reset(ctx, typ, id)
+}
@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/repository/user_account.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/repository/user_account.go -package=repomocks -destination=./internal/repository/mocks/user_account.mock.go
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	domain "webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserAccountRepository is a mock of UserAccountRepository interface.
+type MockUserAccountRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserAccountRepositoryMockRecorder
+}
+
+// MockUserAccountRepositoryMockRecorder is the mock recorder for MockUserAccountRepository.
+type MockUserAccountRepositoryMockRecorder struct {
+	mock *MockUserAccountRepository
+}
+
+// NewMockUserAccountRepository creates a new mock instance.
+func NewMockUserAccountRepository(ctrl *gomock.Controller) *MockUserAccountRepository {
+	mock := &MockUserAccountRepository{ctrl: ctrl}
+	mock.recorder = &MockUserAccountRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserAccountRepository) EXPECT() *MockUserAccountRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockUserAccountRepository) Delete(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockUserAccountRepositoryMockRecorder) Delete(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserAccountRepository)(nil).Delete), ctx, uid)
+}
+
+// Export mocks base method.
+func (m *MockUserAccountRepository) Export(ctx context.Context, uid int64) (domain.UserArchive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", ctx, uid)
+	ret0, _ := ret[0].(domain.UserArchive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockUserAccountRepositoryMockRecorder) Export(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockUserAccountRepository)(nil).Export), ctx, uid)
+}
@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/repository/user_merge.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/repository/user_merge.go -package=repomocks -destination=./internal/repository/mocks/user_merge.mock.go
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	domain "webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserMergeRepository is a mock of UserMergeRepository interface.
+type MockUserMergeRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserMergeRepositoryMockRecorder
+}
+
+// MockUserMergeRepositoryMockRecorder is the mock recorder for MockUserMergeRepository.
+type MockUserMergeRepositoryMockRecorder struct {
+	mock *MockUserMergeRepository
+}
+
+// NewMockUserMergeRepository creates a new mock instance.
+func NewMockUserMergeRepository(ctrl *gomock.Controller) *MockUserMergeRepository {
+	mock := &MockUserMergeRepository{ctrl: ctrl}
+	mock.recorder = &MockUserMergeRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserMergeRepository) EXPECT() *MockUserMergeRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Merge mocks base method.
+func (m *MockUserMergeRepository) Merge(ctx context.Context, sourceId, targetId, operator int64) (domain.UserMergeDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", ctx, sourceId, targetId, operator)
+	ret0, _ := ret[0].(domain.UserMergeDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Merge indicates an expected call of Merge.
+func (mr *MockUserMergeRepositoryMockRecorder) Merge(ctx, sourceId, targetId, operator any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockUserMergeRepository)(nil).Merge), ctx, sourceId, targetId, operator)
+}
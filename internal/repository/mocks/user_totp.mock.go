@@ -0,0 +1,126 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/repository/user_totp.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/repository/user_totp.go -package=repomocks -destination=./internal/repository/mocks/user_totp.mock.go
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	domain "webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserTOTPRepository is a mock of UserTOTPRepository interface.
+type MockUserTOTPRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserTOTPRepositoryMockRecorder
+}
+
+// MockUserTOTPRepositoryMockRecorder is the mock recorder for MockUserTOTPRepository.
+type MockUserTOTPRepositoryMockRecorder struct {
+	mock *MockUserTOTPRepository
+}
+
+// NewMockUserTOTPRepository creates a new mock instance.
+func NewMockUserTOTPRepository(ctrl *gomock.Controller) *MockUserTOTPRepository {
+	mock := &MockUserTOTPRepository{ctrl: ctrl}
+	mock.recorder = &MockUserTOTPRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserTOTPRepository) EXPECT() *MockUserTOTPRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Disable mocks base method.
+func (m *MockUserTOTPRepository) Disable(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Disable", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Disable indicates an expected call of Disable.
+func (mr *MockUserTOTPRepositoryMockRecorder) Disable(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disable", reflect.TypeOf((*MockUserTOTPRepository)(nil).Disable), ctx, uid)
+}
+
+// Enable mocks base method.
+func (m *MockUserTOTPRepository) Enable(ctx context.Context, uid, step int64, codeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enable", ctx, uid, step, codeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Enable indicates an expected call of Enable.
+func (mr *MockUserTOTPRepositoryMockRecorder) Enable(ctx, uid, step, codeHashes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enable", reflect.TypeOf((*MockUserTOTPRepository)(nil).Enable), ctx, uid, step, codeHashes)
+}
+
+// FindByUid mocks base method.
+func (m *MockUserTOTPRepository) FindByUid(ctx context.Context, uid int64) (domain.TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByUid", ctx, uid)
+	ret0, _ := ret[0].(domain.TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByUid indicates an expected call of FindByUid.
+func (mr *MockUserTOTPRepositoryMockRecorder) FindByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUid", reflect.TypeOf((*MockUserTOTPRepository)(nil).FindByUid), ctx, uid)
+}
+
+// Save mocks base method.
+func (m *MockUserTOTPRepository) Save(ctx context.Context, t domain.TOTP) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockUserTOTPRepositoryMockRecorder) Save(ctx, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockUserTOTPRepository)(nil).Save), ctx, t)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockUserTOTPRepository) UseRecoveryCode(ctx context.Context, uid int64, codeHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, uid, codeHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockUserTOTPRepositoryMockRecorder) UseRecoveryCode(ctx, uid, codeHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockUserTOTPRepository)(nil).UseRecoveryCode), ctx, uid, codeHash)
+}
+
+// UseStep mocks base method.
+func (m *MockUserTOTPRepository) UseStep(ctx context.Context, uid, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseStep", ctx, uid, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseStep indicates an expected call of UseStep.
+func (mr *MockUserTOTPRepositoryMockRecorder) UseStep(ctx, uid, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseStep", reflect.TypeOf((*MockUserTOTPRepository)(nil).UseStep), ctx, uid, step)
+}
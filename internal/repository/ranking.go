@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"webook/internal/domain"
+	"webook/internal/repository/cache"
+)
+
+type RankingRepository interface {
+	ReplaceTopN(ctx context.Context, arts []domain.Article) error
+	GetTopN(ctx context.Context) ([]domain.Article, error)
+}
+
+// CachedRankingRepository 榜单只存在缓存里面，先查本地缓存，再查 Redis
+type CachedRankingRepository struct {
+	redis *cache.RankingRedisCache
+	local *cache.RankingLocalCache
+}
+
+func NewCachedRankingRepository(redis *cache.RankingRedisCache, local *cache.RankingLocalCache) RankingRepository {
+	return &CachedRankingRepository{
+		redis: redis,
+		local: local,
+	}
+}
+
+func (c *CachedRankingRepository) ReplaceTopN(ctx context.Context, arts []domain.Article) error {
+	// 本地缓存不会失败
+	_ = c.local.Set(ctx, arts)
+	return c.redis.Set(ctx, arts)
+}
+
+func (c *CachedRankingRepository) GetTopN(ctx context.Context) ([]domain.Article, error) {
+	arts, err := c.local.Get(ctx)
+	if err == nil {
+		return arts, nil
+	}
+	arts, err = c.redis.Get(ctx)
+	if err != nil {
+		// Redis 出问题了，本地缓存即便过期了也先用着
+		return c.local.ForceGet(ctx)
+	}
+	_ = c.local.Set(ctx, arts)
+	return arts, nil
+}
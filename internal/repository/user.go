@@ -3,7 +3,14 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"github.com/ecodeclub/ekit/slice"
+	"golang.org/x/sync/singleflight"
 	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 	"webook/internal/domain"
 	"webook/internal/repository/cache"
 	"webook/internal/repository/dao"
@@ -12,6 +19,10 @@ import (
 var (
 	ErrDuplicateUser = dao.ErrDuplicateEmail
 	ErrUserNotFound  = dao.ErrRecordNotFound
+	// ErrUserStatusFrozen 已经注销或者被合并的账号不能再封禁或者解封
+	ErrUserStatusFrozen = dao.ErrUserStatusFrozen
+	// ErrUserDegraded 缓存不可用并且数据库负载过高，放弃查询
+	ErrUserDegraded = errors.New("系统繁忙，已降级")
 )
 
 type UserRepository interface {
@@ -21,22 +32,54 @@ type UserRepository interface {
 	FindById(ctx context.Context, id int64) (domain.User, error)
 	FindByPhone(ctx context.Context, phone string) (domain.User, error)
 	FindByWeChat(ctx context.Context, openId string) (domain.User, error)
+	List(ctx context.Context, offset int, limit int) ([]domain.User, error)
+	UpdateStatus(ctx context.Context, id int64, status domain.UserStatus) error
+	UpdatePassword(ctx context.Context, id int64, password string) error
+	VerifyEmail(ctx context.Context, id int64, email string) error
+	UpdateIdentity(ctx context.Context, u domain.User) error
 }
 
 type CachedUserRepository struct {
 	dao   dao.UserDAO
 	cache cache.UserCache
+	// delDelay 延迟双删的间隔，要大于一次读数据库再回写缓存的耗时
+	delDelay time.Duration
+	// loadTimeout 合并之后的数据库查询的超时时间
+	loadTimeout time.Duration
+
+	group singleflight.Group
+	// dbLoad 正在执行的数据库查询数量，用来粗略衡量数据库的负载
+	dbLoad atomic.Int64
+	// dbLoadThreshold 缓存不可用的时候，数据库负载达到这个值就不再查询数据库
+	dbLoadThreshold int64
 }
 
 func NewCachedUserRepository(dao dao.UserDAO, c cache.UserCache) UserRepository {
+	return NewCachedUserRepositoryV1(dao, c, 100)
+}
+
+func NewCachedUserRepositoryV1(dao dao.UserDAO, c cache.UserCache, dbLoadThreshold int64) UserRepository {
 	return &CachedUserRepository{
-		dao:   dao,
-		cache: c,
+		dao:             dao,
+		cache:           c,
+		delDelay:        time.Second,
+		loadTimeout:     time.Second,
+		dbLoadThreshold: dbLoadThreshold,
 	}
 }
 
 func (repo *CachedUserRepository) Create(ctx context.Context, u domain.User) error {
-	return repo.dao.Insert(ctx, repo.toEntity(u))
+	id, err := repo.dao.Insert(ctx, repo.toEntity(u))
+	if err != nil {
+		return err
+	}
+	// 注册之前有人查过这个 id 的话，缓存里面会有不存在的记录，要删掉
+	// 不然新用户在记录过期之前都查不到自己
+	err = repo.cache.Del(ctx, id)
+	if err != nil {
+		log.Println("删除用户缓存失败", err)
+	}
+	return nil
 }
 
 func (repo *CachedUserRepository) FindByName(ctx context.Context, email string) (domain.User, error) {
@@ -44,18 +87,22 @@ func (repo *CachedUserRepository) FindByName(ctx context.Context, email string)
 	if err != nil {
 		return domain.User{}, err
 	}
-	return repo.toDomain(u), nil
+	return userToDomain(u), nil
 }
 
-func (repo *CachedUserRepository) toDomain(u dao.User) domain.User {
+// userToDomain 用户相关的 repository 共用
+func userToDomain(u dao.User) domain.User {
 	return domain.User{
-		Id:       u.Id,
-		Email:    u.Email.String,
-		Phone:    u.Phone.String,
-		Password: u.Password,
-		Birthday: u.Birthday,
-		NickName: u.NickName,
-		AboutMe:  u.AboutMe,
+		Id:            u.Id,
+		Email:         u.Email.String,
+		EmailVerified: u.EmailVerified,
+		Phone:         u.Phone.String,
+		Password:      u.Password,
+		Birthday:      u.Birthday,
+		NickName:      u.NickName,
+		AboutMe:       u.AboutMe,
+		Roles:         toRoles(u.Roles),
+		Status:        domain.UserStatus(u.Status),
 		WechatInfo: domain.WechatInfo{
 			OpenId:  u.WechatOpenId.String,
 			UnionId: u.WechatUnionId.String,
@@ -63,6 +110,13 @@ func (repo *CachedUserRepository) toDomain(u dao.User) domain.User {
 	}
 }
 
+func toRoles(roles string) []string {
+	if roles == "" {
+		return nil
+	}
+	return strings.Split(roles, ",")
+}
+
 func (repo *CachedUserRepository) toEntity(u domain.User) dao.User {
 	return dao.User{
 		Id: u.Id,
@@ -74,10 +128,13 @@ func (repo *CachedUserRepository) toEntity(u domain.User) dao.User {
 			String: u.Phone,
 			Valid:  u.Phone != "",
 		},
-		Password: u.Password,
-		Birthday: u.Birthday,
-		NickName: u.NickName,
-		AboutMe:  u.AboutMe,
+		EmailVerified: u.EmailVerified,
+		Password:      u.Password,
+		Birthday:      u.Birthday,
+		NickName:      u.NickName,
+		AboutMe:       u.AboutMe,
+		Roles:         strings.Join(u.Roles, ","),
+		Status:        u.Status.ToUint8(),
 		WechatUnionId: sql.NullString{
 			String: u.WechatInfo.UnionId,
 			Valid:  u.WechatInfo.UnionId != "",
@@ -90,47 +147,154 @@ func (repo *CachedUserRepository) toEntity(u domain.User) dao.User {
 }
 
 func (repo *CachedUserRepository) Edit(ctx context.Context, u domain.User) error {
-	return repo.dao.Edit(ctx, dao.User{
+	err := repo.dao.Edit(ctx, dao.User{
 		Id:       u.Id,
 		Birthday: u.Birthday,
 		AboutMe:  u.AboutMe,
 		NickName: u.NickName,
 	})
+	if err != nil {
+		return err
+	}
+	// 先更新数据库，再删除缓存
+	// 删除失败也不影响更新的结果，最多就是读到旧数据直到缓存过期
+	err = repo.cache.Del(ctx, u.Id)
+	if err != nil {
+		log.Println("删除用户缓存失败", err)
+	}
+	// 延迟双删：更新数据库的同时，可能有读请求读到了旧数据，
+	// 并且在我们删除缓存之后才回写进去，所以过一会再删一次
+	go func() {
+		time.Sleep(repo.delDelay)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		er := repo.cache.Del(ctx, u.Id)
+		if er != nil {
+			log.Println("延迟删除用户缓存失败", er)
+		}
+	}()
+	return nil
 }
 
 func (repo *CachedUserRepository) FindById(ctx context.Context, id int64) (domain.User, error) {
 	du, err := repo.cache.Get(ctx, id)
-	// 只要err为nil，就返回
-	// 缓存未命中
-	if err == nil {
-		return du, err
+	switch err {
+	case nil:
+		return du, nil
+	case cache.ErrUserNotExist:
+		// 之前已经确认过数据库里面没有，直接返回，防止缓存穿透
+		return domain.User{}, ErrUserNotFound
+	case cache.ErrKeyNotExist:
+		// 缓存未命中，redis 是正常的，去查询数据库
+	default:
+		// redis 有问题，可能是网络问题，也可能是 redis 本身崩溃了
+		// 这时候所有的请求都会落到数据库上，数据库压力太大就不查了，保护住数据库
+		if repo.dbLoad.Load() >= repo.dbLoadThreshold {
+			return domain.User{}, ErrUserDegraded
+		}
 	}
 
-	// err 不为nil，再去查询数据库
-	// 这里err有两种可能
-	// 1.key不存在，redis是正常的，从redis中获取不到用户信息
-	// 2.redis有问题，访问redis报错了，可能是网络问题，也可能是redis本身崩溃了
+	// 同一个 id 并发未命中的时候，只有一个请求会去查询数据库
+	// 查询是大家共享的，不能用第一个请求的 ctx，不然它被取消了其它请求也跟着失败
+	val, err, _ := repo.group.Do(strconv.FormatInt(id, 10), func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), repo.loadTimeout)
+		defer cancel()
+		return repo.loadFromDB(ctx, id)
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+	return val.(domain.User), nil
+}
 
+func (repo *CachedUserRepository) loadFromDB(ctx context.Context, id int64) (domain.User, error) {
+	repo.dbLoad.Add(1)
 	u, err := repo.dao.FindById(ctx, id)
+	repo.dbLoad.Add(-1)
+	if err == dao.ErrRecordNotFound {
+		er := repo.cache.SetNotExist(ctx, id)
+		if er != nil {
+			log.Println("缓存不存在的用户失败", er)
+		}
+		return domain.User{}, ErrUserNotFound
+	}
 	if err != nil {
 		return domain.User{}, err
 	}
-	du = repo.toDomain(u)
-	//go func() {
+	du := userToDomain(u)
 	err = repo.cache.Set(ctx, du)
 	if err != nil {
 		log.Println(err)
 	}
-	//}()
 	return du, nil
 }
 
+func (repo *CachedUserRepository) List(ctx context.Context, offset int, limit int) ([]domain.User, error) {
+	users, err := repo.dao.List(ctx, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(users, func(idx int, src dao.User) domain.User {
+		return userToDomain(src)
+	}), nil
+}
+
+func (repo *CachedUserRepository) UpdateStatus(ctx context.Context, id int64, status domain.UserStatus) error {
+	err := repo.dao.UpdateStatus(ctx, id, status.ToUint8())
+	if err != nil {
+		return err
+	}
+	// 状态变了，缓存也要删掉
+	err = repo.cache.Del(ctx, id)
+	if err != nil {
+		log.Println("删除用户缓存失败", err)
+	}
+	return nil
+}
+
+func (repo *CachedUserRepository) UpdatePassword(ctx context.Context, id int64, password string) error {
+	err := repo.dao.UpdatePassword(ctx, id, password)
+	if err != nil {
+		return err
+	}
+	// 缓存里面也有密码，必须删掉
+	err = repo.cache.Del(ctx, id)
+	if err != nil {
+		log.Println("删除用户缓存失败", err)
+	}
+	return nil
+}
+
+func (repo *CachedUserRepository) VerifyEmail(ctx context.Context, id int64, email string) error {
+	err := repo.dao.VerifyEmail(ctx, id, email)
+	if err != nil {
+		return err
+	}
+	err = repo.cache.Del(ctx, id)
+	if err != nil {
+		log.Println("删除用户缓存失败", err)
+	}
+	return nil
+}
+
+func (repo *CachedUserRepository) UpdateIdentity(ctx context.Context, u domain.User) error {
+	err := repo.dao.UpdateIdentity(ctx, repo.toEntity(u))
+	if err != nil {
+		return err
+	}
+	err = repo.cache.Del(ctx, u.Id)
+	if err != nil {
+		log.Println("删除用户缓存失败", err)
+	}
+	return nil
+}
+
 func (repo *CachedUserRepository) FindByPhone(ctx context.Context, phone string) (domain.User, error) {
 	u, err := repo.dao.FindByPhone(ctx, phone)
 	if err != nil {
 		return domain.User{}, err
 	}
-	return repo.toDomain(u), nil
+	return userToDomain(u), nil
 }
 
 func (repo *CachedUserRepository) FindByWeChat(ctx context.Context, openId string) (domain.User, error) {
@@ -138,5 +302,5 @@ func (repo *CachedUserRepository) FindByWeChat(ctx context.Context, openId strin
 	if err != nil {
 		return domain.User{}, err
 	}
-	return repo.toDomain(ue), nil
+	return userToDomain(ue), nil
 }
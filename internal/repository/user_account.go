@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository/cache"
+	"webook/internal/repository/dao"
+)
+
+type UserAccountRepository interface {
+	Delete(ctx context.Context, uid int64) error
+	Export(ctx context.Context, uid int64) (domain.UserArchive, error)
+}
+
+type CachedUserAccountRepository struct {
+	dao   dao.UserAccountDAO
+	cache cache.UserCache
+}
+
+func NewCachedUserAccountRepository(d dao.UserAccountDAO, c cache.UserCache) UserAccountRepository {
+	return &CachedUserAccountRepository{
+		dao:   d,
+		cache: c,
+	}
+}
+
+func (repo *CachedUserAccountRepository) Delete(ctx context.Context, uid int64) error {
+	err := repo.dao.Delete(ctx, uid)
+	if err != nil {
+		return err
+	}
+	er := repo.cache.Del(ctx, uid)
+	if er != nil {
+		log.Println("删除用户缓存失败", er)
+	}
+	return nil
+}
+
+func (repo *CachedUserAccountRepository) Export(ctx context.Context, uid int64) (domain.UserArchive, error) {
+	a, err := repo.dao.Export(ctx, uid)
+	if err != nil {
+		return domain.UserArchive{}, err
+	}
+	res := domain.UserArchive{
+		Profile:  userToDomain(a.User),
+		Articles: make([]domain.Article, 0, len(a.Articles)),
+		Likes:    make([]domain.UserInteraction, 0, len(a.Likes)),
+		Collects: make([]domain.UserInteraction, 0, len(a.Collects)),
+	}
+	for _, art := range a.Articles {
+		res.Articles = append(res.Articles, articleToDomain(art))
+	}
+	for _, l := range a.Likes {
+		res.Likes = append(res.Likes, domain.UserInteraction{
+			Biz:   l.Biz,
+			BizId: l.BizId,
+			Ctime: time.UnixMilli(l.Ctime),
+		})
+	}
+	for _, c := range a.Collects {
+		res.Collects = append(res.Collects, domain.UserInteraction{
+			Biz:   c.Biz,
+			BizId: c.BizId,
+			Ctime: time.UnixMilli(c.Ctime),
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"webook/internal/domain"
+	"webook/internal/repository/cache"
+	"webook/internal/repository/dao"
+)
+
+var ErrUserMerged = dao.ErrUserMerged
+
+type UserMergeRepository interface {
+	Merge(ctx context.Context, sourceId int64, targetId int64, operator int64) (domain.UserMergeDetail, error)
+}
+
+type CachedUserMergeRepository struct {
+	dao   dao.UserMergeDAO
+	cache cache.UserCache
+	// intrCache 点赞和收藏挪过来之后，计数可能变了
+	intrCache cache.InteractiveCache
+}
+
+func NewCachedUserMergeRepository(d dao.UserMergeDAO, c cache.UserCache,
+	intrCache cache.InteractiveCache) UserMergeRepository {
+	return &CachedUserMergeRepository{
+		dao:       d,
+		cache:     c,
+		intrCache: intrCache,
+	}
+}
+
+func (repo *CachedUserMergeRepository) Merge(ctx context.Context, sourceId int64, targetId int64, operator int64) (domain.UserMergeDetail, error) {
+	detail, err := repo.dao.Merge(ctx, sourceId, targetId, operator)
+	if err != nil {
+		return domain.UserMergeDetail{}, err
+	}
+	// 两个用户的信息都变了
+	for _, id := range []int64{sourceId, targetId} {
+		er := repo.cache.Del(ctx, id)
+		if er != nil {
+			log.Println("删除用户缓存失败", er)
+		}
+	}
+	for _, k := range detail.Interactives {
+		er := repo.intrCache.Del(ctx, k.Biz, k.BizId)
+		if er != nil {
+			log.Println("删除互动数据缓存失败", er)
+		}
+	}
+	return domain.UserMergeDetail{
+		Email:    detail.Email,
+		Phone:    detail.Phone,
+		Wechat:   detail.Wechat,
+		Articles: detail.Articles,
+		Likes:    detail.Likes,
+		Collects: detail.Collects,
+	}, nil
+}
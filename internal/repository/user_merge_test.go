@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"webook/internal/domain"
+	"webook/internal/repository/cache"
+	cachemocks "webook/internal/repository/cache/mocks"
+	"webook/internal/repository/dao"
+	daomocks "webook/internal/repository/dao/mocks"
+)
+
+func TestCachedUserMergeRepository_Merge(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (dao.UserMergeDAO, cache.UserCache, cache.InteractiveCache)
+
+		wantDetail domain.UserMergeDetail
+		wantErr    error
+	}{
+		{
+			name: "合并成功，删除用户和互动数据的缓存",
+			mock: func(ctrl *gomock.Controller) (dao.UserMergeDAO, cache.UserCache, cache.InteractiveCache) {
+				d := daomocks.NewMockUserMergeDAO(ctrl)
+				uc := cachemocks.NewMockUserCache(ctrl)
+				ic := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().Merge(gomock.Any(), int64(1), int64(2), int64(99)).Return(dao.MergeDetail{
+					Likes:    1,
+					Collects: 1,
+					Interactives: []dao.InteractiveKey{
+						{Biz: "article", BizId: 10},
+						{Biz: "article", BizId: 11},
+					},
+				}, nil)
+				uc.EXPECT().Del(gomock.Any(), int64(1)).Return(nil)
+				uc.EXPECT().Del(gomock.Any(), int64(2)).Return(nil)
+				ic.EXPECT().Del(gomock.Any(), "article", int64(10)).Return(nil)
+				// 删除失败不影响合并的结果
+				ic.EXPECT().Del(gomock.Any(), "article", int64(11)).Return(errors.New("redis 错误"))
+				return d, uc, ic
+			},
+			wantDetail: domain.UserMergeDetail{Likes: 1, Collects: 1},
+		},
+		{
+			name: "合并失败，不动缓存",
+			mock: func(ctrl *gomock.Controller) (dao.UserMergeDAO, cache.UserCache, cache.InteractiveCache) {
+				d := daomocks.NewMockUserMergeDAO(ctrl)
+				d.EXPECT().Merge(gomock.Any(), int64(1), int64(2), int64(99)).
+					Return(dao.MergeDetail{}, dao.ErrUserMerged)
+				return d, cachemocks.NewMockUserCache(ctrl), cachemocks.NewMockInteractiveCache(ctrl)
+			},
+			wantErr: ErrUserMerged,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, uc, ic := tc.mock(ctrl)
+			repo := NewCachedUserMergeRepository(d, uc, ic)
+			detail, err := repo.Merge(context.Background(), 1, 2, 99)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantDetail, detail)
+		})
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
+	"sync"
 	"testing"
+	"time"
 	"webook/internal/domain"
 	"webook/internal/repository/cache"
 	cachemocks "webook/internal/repository/cache/mocks"
@@ -111,12 +113,51 @@ func TestCachedUserRepository_FindById(t *testing.T) {
 				c := cachemocks.NewMockUserCache(ctrl)
 				c.EXPECT().Get(gomock.Any(), uid).Return(domain.User{}, cache.ErrKeyNotExist)
 				d.EXPECT().FindById(gomock.Any(), uid).Return(dao.User{}, dao.ErrRecordNotFound)
+				c.EXPECT().SetNotExist(gomock.Any(), uid).Return(nil)
 				return d, c
 			},
 			uid:      123,
 			ctx:      context.Background(),
 			wantUser: domain.User{},
-			wantErr:  dao.ErrRecordNotFound,
+			wantErr:  ErrUserNotFound,
+		},
+		{
+			name: "缓存了用户不存在，不查询数据库",
+			mock: func(ctrl *gomock.Controller) (dao.UserDAO, cache.UserCache) {
+				uid := int64(123)
+				d := daomocks.NewMockUserDAO(ctrl)
+				c := cachemocks.NewMockUserCache(ctrl)
+				c.EXPECT().Get(gomock.Any(), uid).Return(domain.User{}, cache.ErrUserNotExist)
+				return d, c
+			},
+			uid:      123,
+			ctx:      context.Background(),
+			wantUser: domain.User{},
+			wantErr:  ErrUserNotFound,
+		},
+		{
+			name: "redis 出错，数据库负载不高，查询数据库",
+			mock: func(ctrl *gomock.Controller) (dao.UserDAO, cache.UserCache) {
+				uid := int64(123)
+				d := daomocks.NewMockUserDAO(ctrl)
+				c := cachemocks.NewMockUserCache(ctrl)
+				c.EXPECT().Get(gomock.Any(), uid).Return(domain.User{}, errors.New("redis error"))
+				d.EXPECT().FindById(gomock.Any(), uid).Return(dao.User{
+					Id:       123,
+					NickName: "nick_name",
+				}, nil)
+				c.EXPECT().Set(gomock.Any(), domain.User{
+					Id:       123,
+					NickName: "nick_name",
+				}).Return(errors.New("redis error"))
+				return d, c
+			},
+			uid: 123,
+			ctx: context.Background(),
+			wantUser: domain.User{
+				Id:       123,
+				NickName: "nick_name",
+			},
 		},
 		{
 			name: "回写缓存失败",
@@ -173,11 +214,227 @@ func TestCachedUserRepository_FindById(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 			ud, uc := tc.mock(ctrl)
-			svc := NewCachedUserRepository(ud, uc)
-			user, err := svc.FindById(tc.ctx, tc.uid)
+			repo := NewCachedUserRepository(ud, uc)
+			user, err := repo.FindById(tc.ctx, tc.uid)
 			assert.Equal(t, tc.wantErr, err)
 			assert.Equal(t, tc.wantUser, user)
 
 		})
 	}
 }
+
+func TestCachedUserRepository_Edit(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller, deleted chan struct{}) (dao.UserDAO, cache.UserCache)
+
+		user domain.User
+
+		wantErr error
+		// 是否会触发延迟双删
+		wantDelayDel bool
+	}{
+		{
+			name: "更新成功，删除缓存",
+			mock: func(ctrl *gomock.Controller, deleted chan struct{}) (dao.UserDAO, cache.UserCache) {
+				d := daomocks.NewMockUserDAO(ctrl)
+				c := cachemocks.NewMockUserCache(ctrl)
+				d.EXPECT().Edit(gomock.Any(), dao.User{
+					Id:       123,
+					Birthday: 100,
+					AboutMe:  "自我介绍",
+					NickName: "nick_name",
+				}).Return(nil)
+				first := c.EXPECT().Del(gomock.Any(), int64(123)).Return(nil)
+				c.EXPECT().Del(gomock.Any(), int64(123)).After(first).DoAndReturn(
+					func(ctx context.Context, id int64) error {
+						close(deleted)
+						return nil
+					})
+				return d, c
+			},
+			user: domain.User{
+				Id:       123,
+				Birthday: 100,
+				AboutMe:  "自我介绍",
+				NickName: "nick_name",
+			},
+			wantDelayDel: true,
+		},
+		{
+			name: "删除缓存失败，不影响更新结果",
+			mock: func(ctrl *gomock.Controller, deleted chan struct{}) (dao.UserDAO, cache.UserCache) {
+				d := daomocks.NewMockUserDAO(ctrl)
+				c := cachemocks.NewMockUserCache(ctrl)
+				d.EXPECT().Edit(gomock.Any(), gomock.Any()).Return(nil)
+				first := c.EXPECT().Del(gomock.Any(), int64(123)).Return(errors.New("redis error"))
+				c.EXPECT().Del(gomock.Any(), int64(123)).After(first).DoAndReturn(
+					func(ctx context.Context, id int64) error {
+						close(deleted)
+						return nil
+					})
+				return d, c
+			},
+			user: domain.User{
+				Id: 123,
+			},
+			wantDelayDel: true,
+		},
+		{
+			name: "更新数据库失败，不删除缓存",
+			mock: func(ctrl *gomock.Controller, deleted chan struct{}) (dao.UserDAO, cache.UserCache) {
+				d := daomocks.NewMockUserDAO(ctrl)
+				c := cachemocks.NewMockUserCache(ctrl)
+				d.EXPECT().Edit(gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+				return d, c
+			},
+			user: domain.User{
+				Id: 123,
+			},
+			wantErr: errors.New("db error"),
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			deleted := make(chan struct{})
+			ud, uc := tc.mock(ctrl, deleted)
+			repo := NewCachedUserRepository(ud, uc).(*CachedUserRepository)
+			repo.delDelay = time.Millisecond * 10
+			err := repo.Edit(context.Background(), tc.user)
+			assert.Equal(t, tc.wantErr, err)
+			if !tc.wantDelayDel {
+				return
+			}
+			select {
+			case <-deleted:
+			case <-time.After(time.Second):
+				assert.Fail(t, "没有延迟删除缓存")
+			}
+		})
+	}
+}
+
+func TestCachedUserRepository_FindByIdDegraded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	d := daomocks.NewMockUserDAO(ctrl)
+	c := cachemocks.NewMockUserCache(ctrl)
+	c.EXPECT().Get(gomock.Any(), int64(123)).Return(domain.User{}, errors.New("redis error"))
+	repo := NewCachedUserRepositoryV1(d, c, 10).(*CachedUserRepository)
+	// 模拟数据库已经有很多查询了
+	repo.dbLoad.Store(10)
+	_, err := repo.FindById(context.Background(), 123)
+	assert.Equal(t, ErrUserDegraded, err)
+}
+
+func TestCachedUserRepository_FindByIdSingleflight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	const n = 10
+	d := daomocks.NewMockUserDAO(ctrl)
+	c := cachemocks.NewMockUserCache(ctrl)
+	c.EXPECT().Get(gomock.Any(), int64(123)).Return(domain.User{}, cache.ErrKeyNotExist).Times(n)
+	// 所有请求都到齐了再返回，保证它们是并发的
+	var wg sync.WaitGroup
+	wg.Add(n)
+	start := make(chan struct{})
+	d.EXPECT().FindById(gomock.Any(), int64(123)).DoAndReturn(func(ctx context.Context, id int64) (dao.User, error) {
+		<-start
+		return dao.User{Id: 123}, nil
+	}).Times(1)
+	c.EXPECT().Set(gomock.Any(), domain.User{Id: 123}).Return(nil).Times(1)
+
+	repo := NewCachedUserRepository(d, c)
+	var done sync.WaitGroup
+	done.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer done.Done()
+			wg.Done()
+			u, err := repo.FindById(context.Background(), 123)
+			assert.NoError(t, err)
+			assert.Equal(t, domain.User{Id: 123}, u)
+		}()
+	}
+	wg.Wait()
+	// 给其它 goroutine 一点时间进入 singleflight
+	time.Sleep(time.Millisecond * 100)
+	close(start)
+	done.Wait()
+}
+
+func TestCachedUserRepository_FindByIdDetachedCtx(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	d := daomocks.NewMockUserDAO(ctrl)
+	c := cachemocks.NewMockUserCache(ctrl)
+	c.EXPECT().Get(gomock.Any(), int64(123)).Return(domain.User{}, cache.ErrKeyNotExist)
+	d.EXPECT().FindById(gomock.Any(), int64(123)).DoAndReturn(func(ctx context.Context, id int64) (dao.User, error) {
+		// 发起请求的 ctx 已经取消了，共享的查询不受影响
+		if ctx.Err() != nil {
+			return dao.User{}, ctx.Err()
+		}
+		return dao.User{Id: 123}, nil
+	})
+	c.EXPECT().Set(gomock.Any(), domain.User{Id: 123}).Return(nil)
+
+	repo := NewCachedUserRepository(d, c)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	u, err := repo.FindById(ctx, 123)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.User{Id: 123}, u)
+}
+
+func TestCachedUserRepository_Create(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (dao.UserDAO, cache.UserCache)
+
+		wantErr error
+	}{
+		{
+			name: "创建成功，删除不存在的缓存",
+			mock: func(ctrl *gomock.Controller) (dao.UserDAO, cache.UserCache) {
+				d := daomocks.NewMockUserDAO(ctrl)
+				c := cachemocks.NewMockUserCache(ctrl)
+				d.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(int64(123), nil)
+				c.EXPECT().Del(gomock.Any(), int64(123)).Return(nil)
+				return d, c
+			},
+		},
+		{
+			name: "删除缓存失败，不影响创建",
+			mock: func(ctrl *gomock.Controller) (dao.UserDAO, cache.UserCache) {
+				d := daomocks.NewMockUserDAO(ctrl)
+				c := cachemocks.NewMockUserCache(ctrl)
+				d.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(int64(123), nil)
+				c.EXPECT().Del(gomock.Any(), int64(123)).Return(errors.New("redis error"))
+				return d, c
+			},
+		},
+		{
+			name: "邮箱冲突",
+			mock: func(ctrl *gomock.Controller) (dao.UserDAO, cache.UserCache) {
+				d := daomocks.NewMockUserDAO(ctrl)
+				c := cachemocks.NewMockUserCache(ctrl)
+				d.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(int64(0), dao.ErrDuplicateEmail)
+				return d, c
+			},
+			wantErr: ErrDuplicateUser,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c := tc.mock(ctrl)
+			repo := NewCachedUserRepository(d, c)
+			err := repo.Create(context.Background(), domain.User{Email: "123@qq.com"})
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
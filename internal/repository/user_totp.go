@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"webook/internal/domain"
+	"webook/internal/repository/dao"
+)
+
+var (
+	ErrTOTPNotFound = dao.ErrRecordNotFound
+	ErrTOTPStepUsed = dao.ErrTOTPStepUsed
+)
+
+type UserTOTPRepository interface {
+	// Save 保存还没有启用的密钥
+	Save(ctx context.Context, t domain.TOTP) error
+	FindByUid(ctx context.Context, uid int64) (domain.TOTP, error)
+	Enable(ctx context.Context, uid int64, step int64, codeHashes []string) error
+	Disable(ctx context.Context, uid int64) error
+	UseStep(ctx context.Context, uid int64, step int64) error
+	UseRecoveryCode(ctx context.Context, uid int64, codeHash string) error
+}
+
+type GORMUserTOTPRepository struct {
+	dao dao.UserTOTPDAO
+}
+
+func NewGORMUserTOTPRepository(dao dao.UserTOTPDAO) UserTOTPRepository {
+	return &GORMUserTOTPRepository{
+		dao: dao,
+	}
+}
+
+func (repo *GORMUserTOTPRepository) Save(ctx context.Context, t domain.TOTP) error {
+	return repo.dao.Upsert(ctx, dao.UserTOTP{
+		Uid:    t.Uid,
+		Secret: t.Secret,
+	})
+}
+
+func (repo *GORMUserTOTPRepository) FindByUid(ctx context.Context, uid int64) (domain.TOTP, error) {
+	t, err := repo.dao.FindByUid(ctx, uid)
+	if err != nil {
+		return domain.TOTP{}, err
+	}
+	return domain.TOTP{
+		Uid:     t.Uid,
+		Secret:  t.Secret,
+		Enabled: t.Enabled,
+	}, nil
+}
+
+func (repo *GORMUserTOTPRepository) Enable(ctx context.Context, uid int64, step int64, codeHashes []string) error {
+	return repo.dao.Enable(ctx, uid, step, codeHashes)
+}
+
+func (repo *GORMUserTOTPRepository) Disable(ctx context.Context, uid int64) error {
+	return repo.dao.Disable(ctx, uid)
+}
+
+func (repo *GORMUserTOTPRepository) UseStep(ctx context.Context, uid int64, step int64) error {
+	return repo.dao.UseStep(ctx, uid, step)
+}
+
+func (repo *GORMUserTOTPRepository) UseRecoveryCode(ctx context.Context, uid int64, codeHash string) error {
+	return repo.dao.UseRecoveryCode(ctx, uid, codeHash)
+}
@@ -3,14 +3,28 @@ package service
 import (
 	"context"
 	"errors"
+	"time"
 	"webook/internal/domain"
 	"webook/internal/repository"
 	"webook/pkg/logger"
 )
 
+var (
+	ErrArticleNotFound = repository.ErrArticleNotFound
+	// ErrArticleTakenDown 文章被管理员下架了，作者不能再修改、发表或者撤回
+	ErrArticleTakenDown = errors.New("文章已被下架")
+)
+
 type ArticleService interface {
 	Save(ctx context.Context, art domain.Article) (int64, error)
 	Publish(ctx context.Context, art domain.Article) (int64, error)
+	GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error)
+	GetById(ctx context.Context, id int64) (domain.Article, error)
+	GetPubById(ctx context.Context, id int64) (domain.Article, error)
+	Withdraw(ctx context.Context, uid int64, id int64) error
+	ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]domain.Article, error)
+	// TakeDown 管理员下架文章
+	TakeDown(ctx context.Context, id int64) error
 }
 
 type articleService struct {
@@ -23,9 +37,59 @@ type articleService struct {
 }
 
 func (a *articleService) Publish(ctx context.Context, art domain.Article) (int64, error) {
+	err := a.checkTakenDown(ctx, art.Id)
+	if err != nil {
+		return 0, err
+	}
+	art.Status = domain.ArticleStatusPublished
 	return a.repo.Sync(ctx, art)
 }
 
+// Withdraw 撤回文章，制作库和线上库都改成仅自己可见
+func (a *articleService) Withdraw(ctx context.Context, uid int64, id int64) error {
+	err := a.checkTakenDown(ctx, id)
+	if err != nil {
+		return err
+	}
+	return a.repo.SyncStatus(ctx, uid, id, domain.ArticleStatusPrivate)
+}
+
+func (a *articleService) TakeDown(ctx context.Context, id int64) error {
+	return a.repo.UpdateStatusById(ctx, id, domain.ArticleStatusTakenDown)
+}
+
+// checkTakenDown 已有的文章，如果被下架了就不允许作者再操作
+// 作者对不对由后面的更新语句校验
+func (a *articleService) checkTakenDown(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return nil
+	}
+	art, err := a.repo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if art.Status == domain.ArticleStatusTakenDown {
+		return ErrArticleTakenDown
+	}
+	return nil
+}
+
+func (a *articleService) GetByAuthor(ctx context.Context, uid int64, offset int, limit int) ([]domain.Article, error) {
+	return a.repo.GetByAuthor(ctx, uid, offset, limit)
+}
+
+func (a *articleService) GetById(ctx context.Context, id int64) (domain.Article, error) {
+	return a.repo.GetById(ctx, id)
+}
+
+func (a *articleService) GetPubById(ctx context.Context, id int64) (domain.Article, error) {
+	return a.repo.GetPubById(ctx, id)
+}
+
+func (a *articleService) ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]domain.Article, error) {
+	return a.repo.ListPub(ctx, start, offset, limit)
+}
+
 func NewArticleService(repo repository.ArticleRepository) ArticleService {
 	return &articleService{repo: repo}
 }
@@ -67,6 +131,11 @@ func (a *articleService) PublishV1(ctx context.Context, art domain.Article) (int
 }
 
 func (a *articleService) Save(ctx context.Context, art domain.Article) (int64, error) {
+	err := a.checkTakenDown(ctx, art.Id)
+	if err != nil {
+		return 0, err
+	}
+	art.Status = domain.ArticleStatusUnpublished
 	if art.Id > 0 {
 		err := a.repo.Update(ctx, art)
 		return art.Id, err
@@ -29,7 +29,7 @@ func NewCodeService(repo repository.CodeRepository, smsSvc sms.Service) CodeServ
 }
 
 func (svc *codeService) Send(ctx context.Context, biz, phone string) error {
-	code := svc.generate()
+	code := generateCode()
 	err := svc.repo.Set(ctx, biz, phone, code)
 	if err != nil {
 		return err
@@ -47,7 +47,7 @@ func (svc *codeService) Verify(ctx context.Context, biz, phone, inputCode string
 	return ok, err
 }
 
-func (svc *codeService) generate() string {
+func generateCode() string {
 	code := rand.Intn(1000000)
 	return fmt.Sprintf("%06d", code)
 }
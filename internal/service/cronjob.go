@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	"webook/pkg/logger"
+)
+
+var (
+	ErrNoJobToPreempt = repository.ErrNoJobToPreempt
+	ErrJobNotOwned    = repository.ErrJobNotOwned
+	// ErrInvalidCronExpression cron 表达式不合法，具体原因在错误信息里面
+	ErrInvalidCronExpression = errors.New("cron 表达式不合法")
+)
+
+type CronJobService interface {
+	// Preempt 抢占一个任务，用完之后要调用 CancelFunc 释放
+	Preempt(ctx context.Context) (domain.CronJob, error)
+	ResetNextTime(ctx context.Context, j domain.CronJob) error
+	// AddJob 表达式不合法返回 ErrInvalidCronExpression
+	AddJob(ctx context.Context, j domain.CronJob) error
+}
+
+type cronJobService struct {
+	repo repository.CronJobRepository
+	l    logger.LoggerV1
+	// 续约间隔，要比 abandoned 小很多
+	refreshInterval time.Duration
+	// 超过这个时间没有续约，就认为持有者崩溃了
+	abandoned time.Duration
+}
+
+func NewCronJobService(repo repository.CronJobRepository, l logger.LoggerV1) CronJobService {
+	return &cronJobService{
+		repo:            repo,
+		l:               l,
+		refreshInterval: time.Second * 10,
+		abandoned:       time.Minute,
+	}
+}
+
+func (c *cronJobService) Preempt(ctx context.Context) (domain.CronJob, error) {
+	j, err := c.repo.Preempt(ctx, c.abandoned)
+	if err != nil {
+		return domain.CronJob{}, err
+	}
+	ticker := time.NewTicker(c.refreshInterval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if c.refresh(j) == ErrJobNotOwned {
+					// 任务已经被别人抢走了，再续约也没有用
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	j.CancelFunc = func() {
+		ticker.Stop()
+		close(done)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		er := c.repo.Release(ctx, j.Id, j.Version)
+		if er == ErrJobNotOwned {
+			c.l.Warn("任务已经被别的实例抢占，不需要释放", logger.Int64("jid", j.Id))
+			return
+		}
+		if er != nil {
+			c.l.Error("释放任务失败", logger.Int64("jid", j.Id), logger.Error(er))
+		}
+	}
+	return j, nil
+}
+
+func (c *cronJobService) refresh(j domain.CronJob) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := c.repo.UpdateUtime(ctx, j.Id, j.Version)
+	if err == ErrJobNotOwned {
+		c.l.Warn("任务已经被别的实例抢占，停止续约", logger.Int64("jid", j.Id))
+		return err
+	}
+	if err != nil {
+		// 续约失败，超过 abandoned 之后任务会被别的实例抢走
+		c.l.Error("续约任务失败", logger.Int64("jid", j.Id), logger.Error(err))
+	}
+	return err
+}
+
+func (c *cronJobService) ResetNextTime(ctx context.Context, j domain.CronJob) error {
+	next := j.Next(time.Now())
+	if next.IsZero() {
+		// 没有下一次了
+		return c.repo.Stop(ctx, j.Id, j.Version)
+	}
+	return c.repo.UpdateNextTime(ctx, j.Id, j.Version, next)
+}
+
+func (c *cronJobService) AddJob(ctx context.Context, j domain.CronJob) error {
+	s, err := j.Schedule()
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidCronExpression, err)
+	}
+	j.NextTime = s.Next(time.Now())
+	return c.repo.AddJob(ctx, j)
+}
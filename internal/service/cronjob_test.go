@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	repomocks "webook/internal/repository/mocks"
+	"webook/pkg/logger"
+)
+
+func Test_cronJobService_AddJob(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.CronJobRepository
+		job  domain.CronJob
+
+		wantErr error
+	}{
+		{
+			name: "添加成功",
+			mock: func(ctrl *gomock.Controller) repository.CronJobRepository {
+				repo := repomocks.NewMockCronJobRepository(ctrl)
+				repo.EXPECT().AddJob(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, j domain.CronJob) error {
+						assert.False(t, j.NextTime.IsZero())
+						return nil
+					})
+				return repo
+			},
+			job: domain.CronJob{Name: "ranking", Expression: "0 */3 * * * ?"},
+		},
+		{
+			name: "表达式不合法",
+			mock: func(ctrl *gomock.Controller) repository.CronJobRepository {
+				return repomocks.NewMockCronJobRepository(ctrl)
+			},
+			job:     domain.CronJob{Name: "ranking", Expression: "every 3 minutes"},
+			wantErr: ErrInvalidCronExpression,
+		},
+		{
+			name: "数据库错误",
+			mock: func(ctrl *gomock.Controller) repository.CronJobRepository {
+				repo := repomocks.NewMockCronJobRepository(ctrl)
+				repo.EXPECT().AddJob(gomock.Any(), gomock.Any()).Return(errors.New("db 错误"))
+				return repo
+			},
+			job:     domain.CronJob{Name: "ranking", Expression: "@every 1m"},
+			wantErr: errors.New("db 错误"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewCronJobService(tc.mock(ctrl), logger.NewNopLogger())
+			err := svc.AddJob(context.Background(), tc.job)
+			if tc.wantErr == ErrInvalidCronExpression {
+				assert.ErrorIs(t, err, ErrInvalidCronExpression)
+				return
+			}
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
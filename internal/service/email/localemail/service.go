@@ -0,0 +1,18 @@
+package localemail
+
+import (
+	"context"
+	"log"
+)
+
+type Service struct {
+}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+func (s *Service) Send(ctx context.Context, subject string, content string, to ...string) error {
+	log.Println("邮件", to, subject, content)
+	return nil
+}
@@ -0,0 +1,50 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+)
+
+type Service struct {
+	// addr host:port
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewService(host string, port int, username string, password string, from string) *Service {
+	return &Service{
+		addr: fmt.Sprintf("%s:%d", host, port),
+		auth: smtp.PlainAuth("", username, password, host),
+		from: from,
+	}
+}
+
+func (s *Service) Send(ctx context.Context, subject string, content string, to ...string) error {
+	if len(to) == 0 {
+		return errors.New("收件人不能为空")
+	}
+	// net/smtp 不支持 context，只能在发之前检查一下
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from, to, s.message(subject, content, to))
+}
+
+func (s *Service) message(subject string, content string, to []string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + s.from + "\r\n")
+	buf.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	// 中文标题要编码，不然会乱码
+	buf.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", subject) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(content)
+	return buf.Bytes()
+}
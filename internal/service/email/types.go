@@ -0,0 +1,8 @@
+package email
+
+import "context"
+
+type Service interface {
+	// Send content 是 HTML 格式的正文
+	Send(ctx context.Context, subject string, content string, to ...string) error
+}
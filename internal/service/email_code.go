@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"webook/internal/repository"
+	"webook/internal/service/email"
+)
+
+// EmailCodeService 通过邮件发送验证码
+// 和短信验证码共用存储，发送频率和校验次数的限制也一样
+type EmailCodeService interface {
+	Send(ctx context.Context, biz, addr string) error
+	Verify(ctx context.Context, biz, addr, inputCode string) (bool, error)
+}
+
+type emailCodeService struct {
+	repo  repository.CodeRepository
+	email email.Service
+}
+
+func NewEmailCodeService(repo repository.CodeRepository, emailSvc email.Service) EmailCodeService {
+	return &emailCodeService{
+		repo:  repo,
+		email: emailSvc,
+	}
+}
+
+func (svc *emailCodeService) Send(ctx context.Context, biz, addr string) error {
+	code := generateCode()
+	err := svc.repo.Set(ctx, biz, addr, code)
+	if err != nil {
+		return err
+	}
+	return svc.email.Send(ctx, "webook 验证码",
+		fmt.Sprintf("<p>你的验证码是 <b>%s</b>，10 分钟内有效。</p><p>如果不是你本人操作，请忽略这封邮件。</p>", code),
+		addr)
+}
+
+func (svc *emailCodeService) Verify(ctx context.Context, biz, addr, inputCode string) (bool, error) {
+	ok, err := svc.repo.Verify(ctx, biz, addr, inputCode)
+	if errors.Is(err, repository.ErrCodeVerifyToMany) {
+		return false, nil
+	}
+	return ok, err
+}
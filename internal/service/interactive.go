@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"golang.org/x/sync/errgroup"
+	"webook/internal/domain"
+	"webook/internal/repository"
+)
+
+type InteractiveService interface {
+	IncrReadCnt(ctx context.Context, biz string, bizId int64) error
+	Like(ctx context.Context, biz string, bizId int64, uid int64) error
+	CancelLike(ctx context.Context, biz string, bizId int64, uid int64) error
+	// Collect 收藏，cid 是收藏夹的 ID
+	Collect(ctx context.Context, biz string, bizId, cid, uid int64) error
+	Get(ctx context.Context, biz string, bizId, uid int64) (domain.Interactive, error)
+	// GetByIds 返回 bizId => Interactive，没有互动数据的 bizId 不在结果里面
+	GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error)
+}
+
+type interactiveService struct {
+	repo repository.InteractiveRepository
+}
+
+func NewInteractiveService(repo repository.InteractiveRepository) InteractiveService {
+	return &interactiveService{
+		repo: repo,
+	}
+}
+
+func (i *interactiveService) IncrReadCnt(ctx context.Context, biz string, bizId int64) error {
+	return i.repo.IncrReadCnt(ctx, biz, bizId)
+}
+
+func (i *interactiveService) Like(ctx context.Context, biz string, bizId int64, uid int64) error {
+	return i.repo.IncrLike(ctx, biz, bizId, uid)
+}
+
+func (i *interactiveService) CancelLike(ctx context.Context, biz string, bizId int64, uid int64) error {
+	return i.repo.DecrLike(ctx, biz, bizId, uid)
+}
+
+func (i *interactiveService) Collect(ctx context.Context, biz string, bizId, cid, uid int64) error {
+	return i.repo.AddCollectionItem(ctx, biz, bizId, cid, uid)
+}
+
+func (i *interactiveService) Get(ctx context.Context, biz string, bizId, uid int64) (domain.Interactive, error) {
+	intr, err := i.repo.Get(ctx, biz, bizId)
+	if err != nil {
+		return domain.Interactive{}, err
+	}
+	if uid <= 0 {
+		// 没有登录，不需要查询点赞收藏
+		return intr, nil
+	}
+	var eg errgroup.Group
+	eg.Go(func() error {
+		var er error
+		intr.Liked, er = i.repo.Liked(ctx, biz, bizId, uid)
+		return er
+	})
+	eg.Go(func() error {
+		var er error
+		intr.Collected, er = i.repo.Collected(ctx, biz, bizId, uid)
+		return er
+	})
+	return intr, eg.Wait()
+}
+
+func (i *interactiveService) GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error) {
+	intrs, err := i.repo.GetByIds(ctx, biz, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]domain.Interactive, len(intrs))
+	for _, intr := range intrs {
+		res[intr.BizId] = intr
+	}
+	return res, nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+)
+
+var ErrLoginLocked = errors.New("登录失败次数太多，暂时锁定")
+
+const (
+	loginAttemptAccount = "account"
+	loginAttemptIP      = "ip"
+)
+
+// LoginAttemptService 防止暴力破解密码，账号和 IP 分开统计失败次数，失败越多锁得越久
+type LoginAttemptService interface {
+	// Check 账号或者 IP 被锁定了就返回 ErrLoginLocked，以及还要等多久
+	Check(ctx context.Context, account string, ip string) (time.Duration, error)
+	// Fail 账号和 IP 各记一次失败，返回这一次触发的锁定时间，没有锁定返回 0
+	Fail(ctx context.Context, account string, ip string) (time.Duration, error)
+	// Succeed 登录成功之后清空账号的失败次数
+	// IP 的不清空，不然攻击者用自己的账号登录一次就能把 IP 的计数清掉
+	Succeed(ctx context.Context, account string) error
+}
+
+type loginAttemptService struct {
+	repo          repository.LoginAttemptRepository
+	accountPolicy domain.LoginLockPolicy
+	ipPolicy      domain.LoginLockPolicy
+}
+
+func NewLoginAttemptService(repo repository.LoginAttemptRepository) LoginAttemptService {
+	return &loginAttemptService{
+		repo: repo,
+		accountPolicy: domain.LoginLockPolicy{
+			Threshold: 5,
+			BaseLock:  time.Minute,
+			MaxLock:   time.Hour,
+			Window:    time.Hour * 24,
+		},
+		// 同一个 IP 后面可能有很多用户，比如说公司的出口 IP，所以阈值高一点
+		ipPolicy: domain.LoginLockPolicy{
+			Threshold: 20,
+			BaseLock:  time.Minute,
+			MaxLock:   time.Hour,
+			Window:    time.Hour,
+		},
+	}
+}
+
+func (svc *loginAttemptService) Check(ctx context.Context, account string, ip string) (time.Duration, error) {
+	var ttl time.Duration
+	account = svc.normalize(account)
+	if account != "" {
+		t, err := svc.repo.LockTTL(ctx, loginAttemptAccount, account)
+		if err != nil {
+			return 0, err
+		}
+		ttl = t
+	}
+	t, err := svc.repo.LockTTL(ctx, loginAttemptIP, ip)
+	if err != nil {
+		return 0, err
+	}
+	if t > ttl {
+		ttl = t
+	}
+	if ttl > 0 {
+		return ttl, ErrLoginLocked
+	}
+	return 0, nil
+}
+
+func (svc *loginAttemptService) Fail(ctx context.Context, account string, ip string) (time.Duration, error) {
+	var lock time.Duration
+	account = svc.normalize(account)
+	if account != "" {
+		l, err := svc.repo.Fail(ctx, loginAttemptAccount, account, svc.accountPolicy)
+		if err != nil {
+			return 0, err
+		}
+		lock = l
+	}
+	l, err := svc.repo.Fail(ctx, loginAttemptIP, ip, svc.ipPolicy)
+	if err != nil {
+		return 0, err
+	}
+	if l > lock {
+		lock = l
+	}
+	return lock, nil
+}
+
+func (svc *loginAttemptService) Succeed(ctx context.Context, account string) error {
+	account = svc.normalize(account)
+	if account == "" {
+		return nil
+	}
+	return svc.repo.Reset(ctx, loginAttemptAccount, account)
+}
+
+// normalize 邮箱不区分大小写，不然换个大小写就能绕过
+func (svc *loginAttemptService) normalize(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"webook/internal/repository"
+	repomocks "webook/internal/repository/mocks"
+)
+
+func Test_loginAttemptService_Check(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) repository.LoginAttemptRepository
+		account string
+
+		wantTTL time.Duration
+		wantErr error
+	}{
+		{
+			name: "没有锁定",
+			mock: func(ctrl *gomock.Controller) repository.LoginAttemptRepository {
+				repo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				repo.EXPECT().LockTTL(gomock.Any(), loginAttemptAccount, "123@qq.com").Return(time.Duration(0), nil)
+				repo.EXPECT().LockTTL(gomock.Any(), loginAttemptIP, "10.0.0.1").Return(time.Duration(0), nil)
+				return repo
+			},
+			// 大小写不同也是同一个账号
+			account: " 123@QQ.com",
+		},
+		{
+			name: "账号和 IP 都锁定了，取长的",
+			mock: func(ctrl *gomock.Controller) repository.LoginAttemptRepository {
+				repo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				repo.EXPECT().LockTTL(gomock.Any(), loginAttemptAccount, "123@qq.com").Return(time.Minute, nil)
+				repo.EXPECT().LockTTL(gomock.Any(), loginAttemptIP, "10.0.0.1").Return(time.Minute*4, nil)
+				return repo
+			},
+			account: "123@qq.com",
+			wantTTL: time.Minute * 4,
+			wantErr: ErrLoginLocked,
+		},
+		{
+			name: "没有账号只看 IP",
+			mock: func(ctrl *gomock.Controller) repository.LoginAttemptRepository {
+				repo := repomocks.NewMockLoginAttemptRepository(ctrl)
+				repo.EXPECT().LockTTL(gomock.Any(), loginAttemptIP, "10.0.0.1").Return(time.Minute, nil)
+				return repo
+			},
+			wantTTL: time.Minute,
+			wantErr: ErrLoginLocked,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewLoginAttemptService(tc.mock(ctrl))
+			ttl, err := svc.Check(context.Background(), tc.account, "10.0.0.1")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantTTL, ttl)
+		})
+	}
+}
+
+func Test_loginAttemptService_Fail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockLoginAttemptRepository(ctrl)
+	svc := NewLoginAttemptService(repo).(*loginAttemptService)
+	// 账号和 IP 用的是不同的策略
+	repo.EXPECT().Fail(gomock.Any(), loginAttemptAccount, "123@qq.com", svc.accountPolicy).
+		Return(time.Minute*2, nil)
+	repo.EXPECT().Fail(gomock.Any(), loginAttemptIP, "10.0.0.1", svc.ipPolicy).
+		Return(time.Duration(0), nil)
+	lock, err := svc.Fail(context.Background(), "123@qq.com", "10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute*2, lock)
+}
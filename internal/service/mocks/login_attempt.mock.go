@@ -0,0 +1,85 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/service/login_attempt.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/service/login_attempt.go -package=svcmocks -destination=./internal/service/mocks/login_attempt.mock.go
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLoginAttemptService is a mock of LoginAttemptService interface.
+type MockLoginAttemptService struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginAttemptServiceMockRecorder
+}
+
+// MockLoginAttemptServiceMockRecorder is the mock recorder for MockLoginAttemptService.
+type MockLoginAttemptServiceMockRecorder struct {
+	mock *MockLoginAttemptService
+}
+
+// NewMockLoginAttemptService creates a new mock instance.
+func NewMockLoginAttemptService(ctrl *gomock.Controller) *MockLoginAttemptService {
+	mock := &MockLoginAttemptService{ctrl: ctrl}
+	mock.recorder = &MockLoginAttemptServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginAttemptService) EXPECT() *MockLoginAttemptServiceMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockLoginAttemptService) Check(ctx context.Context, account, ip string) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", ctx, account, ip)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockLoginAttemptServiceMockRecorder) Check(ctx, account, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockLoginAttemptService)(nil).Check), ctx, account, ip)
+}
+
+// Fail mocks base method.
+func (m *MockLoginAttemptService) Fail(ctx context.Context, account, ip string) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Fail", ctx, account, ip)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Fail indicates an expected call of Fail.
+func (mr *MockLoginAttemptServiceMockRecorder) Fail(ctx, account, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fail", reflect.TypeOf((*MockLoginAttemptService)(nil).Fail), ctx, account, ip)
+}
+
+// Succeed mocks base method.
+func (m *MockLoginAttemptService) Succeed(ctx context.Context, account string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Succeed", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Succeed indicates an expected call of Succeed.
+func (mr *MockLoginAttemptServiceMockRecorder) Succeed(ctx, account any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Succeed", reflect.TypeOf((*MockLoginAttemptService)(nil).Succeed), ctx, account)
+}
@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/service/user_account.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/service/user_account.go -package=svcmocks -destination=./internal/service/mocks/user_account.mock.go
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	domain "webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserAccountService is a mock of UserAccountService interface.
+type MockUserAccountService struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserAccountServiceMockRecorder
+}
+
+// MockUserAccountServiceMockRecorder is the mock recorder for MockUserAccountService.
+type MockUserAccountServiceMockRecorder struct {
+	mock *MockUserAccountService
+}
+
+// NewMockUserAccountService creates a new mock instance.
+func NewMockUserAccountService(ctrl *gomock.Controller) *MockUserAccountService {
+	mock := &MockUserAccountService{ctrl: ctrl}
+	mock.recorder = &MockUserAccountServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserAccountService) EXPECT() *MockUserAccountServiceMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockUserAccountService) Delete(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockUserAccountServiceMockRecorder) Delete(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserAccountService)(nil).Delete), ctx, uid)
+}
+
+// Export mocks base method.
+func (m *MockUserAccountService) Export(ctx context.Context, uid int64) (domain.UserArchive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", ctx, uid)
+	ret0, _ := ret[0].(domain.UserArchive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockUserAccountServiceMockRecorder) Export(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockUserAccountService)(nil).Export), ctx, uid)
+}
@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/service/user_merge.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/service/user_merge.go -package=svcmocks -destination=./internal/service/mocks/user_merge.mock.go
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	domain "webook/internal/domain"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserMergeService is a mock of UserMergeService interface.
+type MockUserMergeService struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserMergeServiceMockRecorder
+}
+
+// MockUserMergeServiceMockRecorder is the mock recorder for MockUserMergeService.
+type MockUserMergeServiceMockRecorder struct {
+	mock *MockUserMergeService
+}
+
+// NewMockUserMergeService creates a new mock instance.
+func NewMockUserMergeService(ctrl *gomock.Controller) *MockUserMergeService {
+	mock := &MockUserMergeService{ctrl: ctrl}
+	mock.recorder = &MockUserMergeServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserMergeService) EXPECT() *MockUserMergeServiceMockRecorder {
+	return m.recorder
+}
+
+// Merge mocks base method.
+func (m *MockUserMergeService) Merge(ctx context.Context, sourceId, targetId, operator int64) (domain.UserMergeDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", ctx, sourceId, targetId, operator)
+	ret0, _ := ret[0].(domain.UserMergeDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Merge indicates an expected call of Merge.
+func (mr *MockUserMergeServiceMockRecorder) Merge(ctx, sourceId, targetId, operator any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockUserMergeService)(nil).Merge), ctx, sourceId, targetId, operator)
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"github.com/ecodeclub/ekit/queue"
+	"github.com/ecodeclub/ekit/slice"
+	"math"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+)
+
+type RankingService interface {
+	// TopN 计算热榜并且保存下来
+	TopN(ctx context.Context) error
+	GetTopN(ctx context.Context) ([]domain.Article, error)
+}
+
+type BatchRankingService struct {
+	artSvc  ArticleService
+	intrSvc InteractiveService
+	repo    repository.RankingRepository
+
+	batchSize int
+	n         int
+	// 只计算最近一段时间内更新过的文章
+	window    time.Duration
+	scoreFunc func(likeCnt int64, utime time.Time) float64
+}
+
+func NewBatchRankingService(artSvc ArticleService, intrSvc InteractiveService,
+	repo repository.RankingRepository) RankingService {
+	return &BatchRankingService{
+		artSvc:    artSvc,
+		intrSvc:   intrSvc,
+		repo:      repo,
+		batchSize: 100,
+		n:         100,
+		window:    time.Hour * 24 * 7,
+		scoreFunc: hackerNewsScore,
+	}
+}
+
+// hackerNewsScore (点赞数 - 1) / (发表了多少小时 + 2) ^ 1.5
+func hackerNewsScore(likeCnt int64, utime time.Time) float64 {
+	hours := time.Since(utime).Hours()
+	return float64(likeCnt-1) / math.Pow(hours+2, 1.5)
+}
+
+func (b *BatchRankingService) GetTopN(ctx context.Context) ([]domain.Article, error) {
+	return b.repo.GetTopN(ctx)
+}
+
+func (b *BatchRankingService) TopN(ctx context.Context) error {
+	arts, err := b.topN(ctx)
+	if err != nil {
+		return err
+	}
+	return b.repo.ReplaceTopN(ctx, arts)
+}
+
+func (b *BatchRankingService) topN(ctx context.Context) ([]domain.Article, error) {
+	type Score struct {
+		art   domain.Article
+		score float64
+	}
+	// 小顶堆，堆顶是目前榜单上分数最低的
+	topN := queue.NewConcurrentPriorityQueue[Score](b.n, func(src Score, dst Score) int {
+		if src.score > dst.score {
+			return 1
+		} else if src.score == dst.score {
+			return 0
+		}
+		return -1
+	})
+
+	now := time.Now()
+	ddl := now.Add(-b.window)
+	offset := 0
+	for {
+		arts, err := b.artSvc.ListPub(ctx, now, offset, b.batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(arts) == 0 {
+			break
+		}
+		ids := slice.Map[domain.Article, int64](arts, func(idx int, src domain.Article) int64 {
+			return src.Id
+		})
+		intrs, err := b.intrSvc.GetByIds(ctx, "article", ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, art := range arts {
+			ele := Score{
+				art:   art,
+				score: b.scoreFunc(intrs[art.Id].LikeCnt, art.Utime),
+			}
+			if topN.Len() < b.n {
+				_ = topN.Enqueue(ele)
+				continue
+			}
+			// 堆满了，比堆顶分数高才能上榜
+			minEle, _ := topN.Peek()
+			if minEle.score < ele.score {
+				_, _ = topN.Dequeue()
+				_ = topN.Enqueue(ele)
+			}
+		}
+		// 按照 utime 倒序查的，最后一篇已经超出窗口，后面的就不用看了
+		if len(arts) < b.batchSize || arts[len(arts)-1].Utime.Before(ddl) {
+			break
+		}
+		offset += len(arts)
+	}
+
+	res := make([]domain.Article, topN.Len())
+	// 出队是从低分到高分，所以倒着放
+	for i := len(res) - 1; i >= 0; i-- {
+		ele, _ := topN.Dequeue()
+		res[i] = ele.art
+	}
+	return res, nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	repomocks "webook/internal/repository/mocks"
+	svcmocks "webook/internal/service/mocks"
+)
+
+func TestBatchRankingService_TopN(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (ArticleService, InteractiveService, repository.RankingRepository)
+		wantErr error
+	}{
+		{
+			name: "计算成功",
+			mock: func(ctrl *gomock.Controller) (ArticleService, InteractiveService, repository.RankingRepository) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				// 两批数据，第二批不满一批，说明已经查完了
+				artSvc.EXPECT().ListPub(gomock.Any(), gomock.Any(), 0, 2).Return([]domain.Article{
+					{Id: 1, Utime: now},
+					{Id: 2, Utime: now},
+				}, nil)
+				artSvc.EXPECT().ListPub(gomock.Any(), gomock.Any(), 2, 2).Return([]domain.Article{
+					{Id: 3, Utime: now},
+				}, nil)
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				intrSvc.EXPECT().GetByIds(gomock.Any(), "article", []int64{1, 2}).Return(map[int64]domain.Interactive{
+					1: {BizId: 1, LikeCnt: 1},
+					2: {BizId: 2, LikeCnt: 2},
+				}, nil)
+				intrSvc.EXPECT().GetByIds(gomock.Any(), "article", []int64{3}).Return(map[int64]domain.Interactive{
+					3: {BizId: 3, LikeCnt: 3},
+				}, nil)
+				repo := repomocks.NewMockRankingRepository(ctrl)
+				// 只取前两名，点赞多的排前面
+				repo.EXPECT().ReplaceTopN(gomock.Any(), []domain.Article{
+					{Id: 3, Utime: now},
+					{Id: 2, Utime: now},
+				}).Return(nil)
+				return artSvc, intrSvc, repo
+			},
+		},
+		{
+			name: "查询文章失败",
+			mock: func(ctrl *gomock.Controller) (ArticleService, InteractiveService, repository.RankingRepository) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().ListPub(gomock.Any(), gomock.Any(), 0, 2).Return(nil, errors.New("mock db error"))
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				repo := repomocks.NewMockRankingRepository(ctrl)
+				return artSvc, intrSvc, repo
+			},
+			wantErr: errors.New("mock db error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			artSvc, intrSvc, repo := tc.mock(ctrl)
+			svc := NewBatchRankingService(artSvc, intrSvc, repo).(*BatchRankingService)
+			svc.batchSize = 2
+			svc.n = 2
+			err := svc.TopN(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	"webook/pkg/totp"
+)
+
+var (
+	ErrTOTPEnabled     = errors.New("已经开启了两步验证")
+	ErrTOTPNotEnrolled = errors.New("还没有绑定验证器")
+	ErrTOTPNotEnabled  = errors.New("没有开启两步验证")
+	ErrInvalidTOTPCode = errors.New("验证码错误")
+)
+
+const (
+	totpIssuer = "webook"
+	// totpSkew 前后各容忍一个周期
+	totpSkew          = 1
+	recoveryCodeCount = 10
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPService TOTP 两步验证
+type TOTPService interface {
+	// Enroll 生成新的密钥，返回给 App 扫码用的 otpauth URI
+	// 输入一次正确的验证码之后才会启用，在那之前可以重复调用
+	Enroll(ctx context.Context, uid int64) (string, error)
+	// Enable 启用两步验证，返回恢复码，恢复码只有这一次能看到
+	Enable(ctx context.Context, uid int64, code string) ([]string, error)
+	// Disable 关闭两步验证，需要验证码或者恢复码
+	Disable(ctx context.Context, uid int64, code string) error
+	Enabled(ctx context.Context, uid int64) (bool, error)
+	// Verify 校验验证码或者恢复码，同一个验证码和恢复码都只能用一次
+	Verify(ctx context.Context, uid int64, code string) error
+}
+
+type totpService struct {
+	repo     repository.UserTOTPRepository
+	userRepo repository.UserRepository
+}
+
+func NewTOTPService(repo repository.UserTOTPRepository, userRepo repository.UserRepository) TOTPService {
+	return &totpService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+func (svc *totpService) Enroll(ctx context.Context, uid int64) (string, error) {
+	t, err := svc.repo.FindByUid(ctx, uid)
+	switch {
+	case err == nil && t.Enabled:
+		return "", ErrTOTPEnabled
+	case err != nil && err != repository.ErrTOTPNotFound:
+		return "", err
+	}
+	u, err := svc.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return "", err
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	err = svc.repo.Save(ctx, domain.TOTP{
+		Uid:    uid,
+		Secret: secret,
+	})
+	if err != nil {
+		return "", err
+	}
+	return totp.URI(totpIssuer, svc.account(u), secret), nil
+}
+
+// account App 里面显示的账号名
+func (svc *totpService) account(u domain.User) string {
+	switch {
+	case u.Email != "":
+		return u.Email
+	case u.Phone != "":
+		return u.Phone
+	default:
+		return strconv.FormatInt(u.Id, 10)
+	}
+}
+
+func (svc *totpService) Enable(ctx context.Context, uid int64, code string) ([]string, error) {
+	t, err := svc.repo.FindByUid(ctx, uid)
+	if err == repository.ErrTOTPNotFound {
+		return nil, ErrTOTPNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.Enabled {
+		return nil, ErrTOTPEnabled
+	}
+	step, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+	codes, hashes, err := svc.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = svc.repo.Enable(ctx, uid, step, hashes)
+	if err == repository.ErrTOTPNotFound {
+		// 并发启用了
+		return nil, ErrTOTPEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (svc *totpService) Disable(ctx context.Context, uid int64, code string) error {
+	err := svc.Verify(ctx, uid, code)
+	if err != nil {
+		return err
+	}
+	return svc.repo.Disable(ctx, uid)
+}
+
+func (svc *totpService) Enabled(ctx context.Context, uid int64) (bool, error) {
+	t, err := svc.repo.FindByUid(ctx, uid)
+	if err == repository.ErrTOTPNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return t.Enabled, nil
+}
+
+func (svc *totpService) Verify(ctx context.Context, uid int64, code string) error {
+	t, err := svc.repo.FindByUid(ctx, uid)
+	if err == repository.ErrTOTPNotFound {
+		return ErrTOTPNotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	if !t.Enabled {
+		return ErrTOTPNotEnabled
+	}
+	code = strings.TrimSpace(code)
+	if _, er := strconv.Atoi(code); er != nil || len(code) != 6 {
+		// 不是 6 位数字，那就是恢复码
+		return svc.useRecoveryCode(ctx, uid, code)
+	}
+	step, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return ErrInvalidTOTPCode
+	}
+	err = svc.repo.UseStep(ctx, uid, step)
+	if err == repository.ErrTOTPStepUsed {
+		// 验证码被截获重放了，或者同一个周期里面登录了两次
+		return ErrInvalidTOTPCode
+	}
+	return err
+}
+
+func (svc *totpService) useRecoveryCode(ctx context.Context, uid int64, code string) error {
+	err := svc.repo.UseRecoveryCode(ctx, uid, hashRecoveryCode(code))
+	if err == repository.ErrTOTPNotFound {
+		return ErrInvalidTOTPCode
+	}
+	return err
+}
+
+// generateRecoveryCodes 恢复码长这样：abcde-fghij，数据库里面只保存哈希
+func (svc *totpService) generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	buf := make([]byte, 7)
+	for i := 0; i < recoveryCodeCount; i++ {
+		_, err := rand.Read(buf)
+		if err != nil {
+			return nil, nil, err
+		}
+		s := strings.ToLower(recoveryCodeEncoding.EncodeToString(buf))[:10]
+		code := s[:5] + "-" + s[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode 忽略大小写和中间的横线
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(code, "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
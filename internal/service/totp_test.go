@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/repository"
+	repomocks "webook/internal/repository/mocks"
+	"webook/pkg/totp"
+)
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func Test_totpService_Enable(t *testing.T) {
+	code, err := totp.GenerateCode(testTOTPSecret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserTOTPRepository
+		code string
+
+		wantCodes int
+		wantErr   error
+	}{
+		{
+			name: "启用成功",
+			mock: func(ctrl *gomock.Controller) repository.UserTOTPRepository {
+				repo := repomocks.NewMockUserTOTPRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.TOTP{Uid: 123, Secret: testTOTPSecret}, nil)
+				repo.EXPECT().Enable(gomock.Any(), int64(123), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, uid int64, step int64, hashes []string) error {
+						assert.Len(t, hashes, recoveryCodeCount)
+						return nil
+					})
+				return repo
+			},
+			code:      code,
+			wantCodes: recoveryCodeCount,
+		},
+		{
+			name: "验证码错误",
+			mock: func(ctrl *gomock.Controller) repository.UserTOTPRepository {
+				repo := repomocks.NewMockUserTOTPRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.TOTP{Uid: 123, Secret: testTOTPSecret}, nil)
+				return repo
+			},
+			code:    "abcdef",
+			wantErr: ErrInvalidTOTPCode,
+		},
+		{
+			name: "还没有绑定",
+			mock: func(ctrl *gomock.Controller) repository.UserTOTPRepository {
+				repo := repomocks.NewMockUserTOTPRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.TOTP{}, repository.ErrTOTPNotFound)
+				return repo
+			},
+			code:    code,
+			wantErr: ErrTOTPNotEnrolled,
+		},
+		{
+			name: "已经启用了",
+			mock: func(ctrl *gomock.Controller) repository.UserTOTPRepository {
+				repo := repomocks.NewMockUserTOTPRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.TOTP{Uid: 123, Secret: testTOTPSecret, Enabled: true}, nil)
+				return repo
+			},
+			code:    code,
+			wantErr: ErrTOTPEnabled,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewTOTPService(tc.mock(ctrl), nil)
+			codes, err := svc.Enable(context.Background(), 123, tc.code)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Len(t, codes, tc.wantCodes)
+		})
+	}
+}
+
+func Test_totpService_Verify(t *testing.T) {
+	code, err := totp.GenerateCode(testTOTPSecret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	enabled := domain.TOTP{Uid: 123, Secret: testTOTPSecret, Enabled: true}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserTOTPRepository
+		code string
+
+		wantErr error
+	}{
+		{
+			name: "验证码正确",
+			mock: func(ctrl *gomock.Controller) repository.UserTOTPRepository {
+				repo := repomocks.NewMockUserTOTPRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).Return(enabled, nil)
+				repo.EXPECT().UseStep(gomock.Any(), int64(123), gomock.Any()).Return(nil)
+				return repo
+			},
+			code: code,
+		},
+		{
+			name: "验证码被重复使用",
+			mock: func(ctrl *gomock.Controller) repository.UserTOTPRepository {
+				repo := repomocks.NewMockUserTOTPRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).Return(enabled, nil)
+				repo.EXPECT().UseStep(gomock.Any(), int64(123), gomock.Any()).
+					Return(repository.ErrTOTPStepUsed)
+				return repo
+			},
+			code:    code,
+			wantErr: ErrInvalidTOTPCode,
+		},
+		{
+			name: "恢复码正确",
+			mock: func(ctrl *gomock.Controller) repository.UserTOTPRepository {
+				repo := repomocks.NewMockUserTOTPRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).Return(enabled, nil)
+				// 大小写和横线都不影响
+				repo.EXPECT().UseRecoveryCode(gomock.Any(), int64(123),
+					hashRecoveryCode("abcde-fghij")).Return(nil)
+				return repo
+			},
+			code: "ABCDEFGHIJ",
+		},
+		{
+			name: "恢复码错误",
+			mock: func(ctrl *gomock.Controller) repository.UserTOTPRepository {
+				repo := repomocks.NewMockUserTOTPRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).Return(enabled, nil)
+				repo.EXPECT().UseRecoveryCode(gomock.Any(), int64(123), gomock.Any()).
+					Return(repository.ErrTOTPNotFound)
+				return repo
+			},
+			code:    "abcde-fghij",
+			wantErr: ErrInvalidTOTPCode,
+		},
+		{
+			name: "没有启用",
+			mock: func(ctrl *gomock.Controller) repository.UserTOTPRepository {
+				repo := repomocks.NewMockUserTOTPRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(123)).
+					Return(domain.TOTP{Uid: 123, Secret: testTOTPSecret}, nil)
+				return repo
+			},
+			code:    code,
+			wantErr: ErrTOTPNotEnabled,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewTOTPService(tc.mock(ctrl), nil)
+			err := svc.Verify(context.Background(), 123, tc.code)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 	"webook/internal/domain"
@@ -12,6 +13,11 @@ import (
 var (
 	ErrDuplicateEmail        = repository.ErrDuplicateUser
 	ErrInvalidUserOrPassword = errors.New("用户不存在或密码错误")
+	ErrUserNotFound          = repository.ErrUserNotFound
+	ErrUserStatusFrozen      = repository.ErrUserStatusFrozen
+	ErrUserBanned            = errors.New("账号已被封禁")
+	ErrIdentityConflict      = errors.New("已经绑定了别的账号")
+	ErrLastLoginMethod       = errors.New("至少要保留一种登录方式")
 )
 
 type UserService interface {
@@ -21,6 +27,23 @@ type UserService interface {
 	FindById(ctx *gin.Context, id int64) (domain.User, error)
 	FindOrCreate(ctx context.Context, phone string) (domain.User, error)
 	FindOrCreateByWeChat(ctx context.Context, info domain.WechatInfo) (domain.User, error)
+	List(ctx context.Context, offset int, limit int) ([]domain.User, error)
+	UpdateStatus(ctx context.Context, id int64, status domain.UserStatus) error
+	// CheckPassword 敏感操作之前再确认一次密码，密码不对返回 ErrInvalidUserOrPassword
+	CheckPassword(ctx context.Context, id int64, password string) error
+	// ChangePassword 登录状态下修改密码，需要校验旧密码
+	ChangePassword(ctx context.Context, id int64, oldPassword string, newPassword string) error
+	// ResetPassword 忘记密码，验证码校验通过之后直接重置，返回被重置的用户
+	ResetPassword(ctx context.Context, phone string, password string) (domain.User, error)
+	// VerifyEmail 验证码校验通过之后，把邮箱标记为已验证
+	VerifyEmail(ctx context.Context, id int64, email string) error
+	// BindPhone 调用方要先校验过短信验证码
+	BindPhone(ctx context.Context, id int64, phone string) error
+	// BindEmail 调用方要先校验过邮件验证码，所以绑定之后邮箱就是已验证的
+	BindEmail(ctx context.Context, id int64, email string) error
+	BindWechat(ctx context.Context, id int64, info domain.WechatInfo) error
+	// Unbind 解绑之后至少还要有一种登录方式
+	Unbind(ctx context.Context, id int64, identity domain.Identity) error
 }
 
 type userService struct {
@@ -54,7 +77,8 @@ func (svc *userService) Login(ctx context.Context, email string, password string
 	if err != nil {
 		return domain.User{}, ErrInvalidUserOrPassword
 	}
-	return u, err
+	// 密码对了才告诉对方被封禁了，不然可以用来探测账号
+	return svc.checkStatus(u, nil)
 }
 
 func (svc *userService) Edit(ctx context.Context, user domain.User) error {
@@ -72,7 +96,7 @@ func (svc *userService) FindOrCreate(ctx context.Context, phone string) (domain.
 		// 两种情况
 		// 1.err=nil u是可用的
 		// 2.err!=nil 系统错误
-		return u, err
+		return svc.checkStatus(u, err)
 	}
 	//用户没有找到
 	err = svc.repo.Create(ctx, domain.User{
@@ -83,7 +107,8 @@ func (svc *userService) FindOrCreate(ctx context.Context, phone string) (domain.
 	if err != nil && err != repository.ErrDuplicateUser {
 		return domain.User{}, err
 	}
-	return svc.repo.FindByPhone(ctx, phone)
+	// 唯一索引冲突说明别人抢先创建了，也要检查状态
+	return svc.checkStatus(svc.repo.FindByPhone(ctx, phone))
 }
 
 func (svc *userService) FindOrCreateByWeChat(ctx context.Context, wechatInfo domain.WechatInfo) (domain.User, error) {
@@ -93,7 +118,7 @@ func (svc *userService) FindOrCreateByWeChat(ctx context.Context, wechatInfo dom
 		// 两种情况
 		// 1.err=nil u是可用的
 		// 2.err!=nil 系统错误
-		return u, err
+		return svc.checkStatus(u, err)
 	}
 	//用户没有找到
 	err = svc.repo.Create(ctx, domain.User{
@@ -103,5 +128,150 @@ func (svc *userService) FindOrCreateByWeChat(ctx context.Context, wechatInfo dom
 	if err != nil && err != repository.ErrDuplicateUser {
 		return domain.User{}, err
 	}
-	return svc.repo.FindByWeChat(ctx, wechatInfo.OpenId)
+	return svc.checkStatus(svc.repo.FindByWeChat(ctx, wechatInfo.OpenId))
+}
+
+func (svc *userService) List(ctx context.Context, offset int, limit int) ([]domain.User, error) {
+	return svc.repo.List(ctx, offset, limit)
+}
+
+func (svc *userService) UpdateStatus(ctx context.Context, id int64, status domain.UserStatus) error {
+	return svc.repo.UpdateStatus(ctx, id, status)
+}
+
+// checkStatus 只有正常状态的用户才能登录
+// 被封禁的返回 ErrUserBanned，已经注销或者被合并的返回 ErrUserStatusFrozen
+func (svc *userService) checkStatus(u domain.User, err error) (domain.User, error) {
+	if err != nil {
+		return domain.User{}, err
+	}
+	switch u.Status {
+	case domain.UserStatusActive:
+		return u, nil
+	case domain.UserStatusBanned:
+		return domain.User{}, ErrUserBanned
+	default:
+		return domain.User{}, ErrUserStatusFrozen
+	}
+}
+
+func (svc *userService) CheckPassword(ctx context.Context, id int64, password string) error {
+	u, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+	// 手机号或者微信注册的用户没有密码，怎么都对不上
+	err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
+	if err != nil {
+		return ErrInvalidUserOrPassword
+	}
+	return nil
+}
+
+func (svc *userService) ChangePassword(ctx context.Context, id int64, oldPassword string, newPassword string) error {
+	// 没有密码的用户只能走重置
+	err := svc.CheckPassword(ctx, id, oldPassword)
+	if err != nil {
+		return err
+	}
+	return svc.updatePassword(ctx, id, newPassword)
+}
+
+func (svc *userService) ResetPassword(ctx context.Context, phone string, password string) (domain.User, error) {
+	u, err := svc.repo.FindByPhone(ctx, phone)
+	if err != nil {
+		return domain.User{}, err
+	}
+	err = svc.updatePassword(ctx, u.Id, password)
+	if err != nil {
+		return domain.User{}, err
+	}
+	return u, nil
+}
+
+func (svc *userService) updatePassword(ctx context.Context, id int64, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return svc.repo.UpdatePassword(ctx, id, string(hash))
+}
+
+func (svc *userService) VerifyEmail(ctx context.Context, id int64, email string) error {
+	return svc.repo.VerifyEmail(ctx, id, email)
+}
+
+func (svc *userService) BindPhone(ctx context.Context, id int64, phone string) error {
+	return svc.bind(ctx, id, func() (domain.User, error) {
+		return svc.repo.FindByPhone(ctx, phone)
+	}, func(u *domain.User) {
+		u.Phone = phone
+	})
+}
+
+func (svc *userService) BindEmail(ctx context.Context, id int64, email string) error {
+	return svc.bind(ctx, id, func() (domain.User, error) {
+		return svc.repo.FindByName(ctx, email)
+	}, func(u *domain.User) {
+		u.Email = email
+		u.EmailVerified = true
+	})
+}
+
+func (svc *userService) BindWechat(ctx context.Context, id int64, info domain.WechatInfo) error {
+	return svc.bind(ctx, id, func() (domain.User, error) {
+		return svc.repo.FindByWeChat(ctx, info.OpenId)
+	}, func(u *domain.User) {
+		u.WechatInfo = info
+	})
+}
+
+// bind find 查询这个身份现在属于谁，set 把身份设置到用户上
+func (svc *userService) bind(ctx context.Context, id int64,
+	find func() (domain.User, error), set func(u *domain.User)) error {
+	owner, err := find()
+	switch err {
+	case nil:
+		if owner.Id != id {
+			return ErrIdentityConflict
+		}
+		// 已经绑定在自己身上了
+		return nil
+	case repository.ErrUserNotFound:
+	default:
+		return err
+	}
+	u, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+	set(&u)
+	err = svc.repo.UpdateIdentity(ctx, u)
+	if err == repository.ErrDuplicateUser {
+		// 查询之后被别人抢先绑定了
+		return ErrIdentityConflict
+	}
+	return err
+}
+
+func (svc *userService) Unbind(ctx context.Context, id int64, identity domain.Identity) error {
+	u, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+	switch identity {
+	case domain.IdentityPhone:
+		u.Phone = ""
+	case domain.IdentityEmail:
+		u.Email = ""
+		u.EmailVerified = false
+	case domain.IdentityWechat:
+		u.WechatInfo = domain.WechatInfo{}
+	default:
+		return fmt.Errorf("未知的身份类型 %s", identity)
+	}
+	if u.LoginMethods() == 0 {
+		return ErrLastLoginMethod
+	}
+	return svc.repo.UpdateIdentity(ctx, u)
 }
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"webook/internal/domain"
+	"webook/internal/repository"
+)
+
+// UserAccountService 用户自己注销账号和导出个人数据
+type UserAccountService interface {
+	// Delete 注销账号，个人信息抹掉，已经发表的文章撤回
+	// 会话由调用方吊销
+	Delete(ctx context.Context, uid int64) error
+	Export(ctx context.Context, uid int64) (domain.UserArchive, error)
+}
+
+type userAccountService struct {
+	repo repository.UserAccountRepository
+}
+
+func NewUserAccountService(repo repository.UserAccountRepository) UserAccountService {
+	return &userAccountService{
+		repo: repo,
+	}
+}
+
+func (svc *userAccountService) Delete(ctx context.Context, uid int64) error {
+	return svc.repo.Delete(ctx, uid)
+}
+
+func (svc *userAccountService) Export(ctx context.Context, uid int64) (domain.UserArchive, error) {
+	return svc.repo.Export(ctx, uid)
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"webook/internal/domain"
+	"webook/internal/repository"
+)
+
+var (
+	ErrMergeSelf  = errors.New("不能合并到自己")
+	ErrUserMerged = repository.ErrUserMerged
+)
+
+// UserMergeService 把重复注册的账号合并成一个
+type UserMergeService interface {
+	// Merge 把 source 的登录方式、文章、点赞和收藏都搬到 target 上，source 只保留一个墓碑
+	// operator 是操作的管理员，会记录在审计日志里面
+	Merge(ctx context.Context, sourceId int64, targetId int64, operator int64) (domain.UserMergeDetail, error)
+}
+
+type userMergeService struct {
+	repo repository.UserMergeRepository
+}
+
+func NewUserMergeService(repo repository.UserMergeRepository) UserMergeService {
+	return &userMergeService{
+		repo: repo,
+	}
+}
+
+func (svc *userMergeService) Merge(ctx context.Context, sourceId int64, targetId int64, operator int64) (domain.UserMergeDetail, error) {
+	if sourceId == targetId {
+		return domain.UserMergeDetail{}, ErrMergeSelf
+	}
+	return svc.repo.Merge(ctx, sourceId, targetId, operator)
+}
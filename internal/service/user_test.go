@@ -97,6 +97,40 @@ func Test_userService_Login(t *testing.T) {
 			wantUser: domain.User{},
 			wantErr:  ErrInvalidUserOrPassword,
 		},
+		{
+			name: "账号已被封禁",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByName(gomock.Any(), "123456@qq.com").Return(domain.User{
+					Email:    "123456@qq.com",
+					Password: "$2a$10$N.edWE4zAEdb33BlrZiGe.R/yxjJSY2yhYIV2lWOstwxyGeLbMcuW",
+					Status:   domain.UserStatusBanned,
+				}, nil)
+				return repo
+			},
+			email:    "123456@qq.com",
+			password: "Hello#world123",
+
+			wantUser: domain.User{},
+			wantErr:  ErrUserBanned,
+		},
+		{
+			name: "账号已经注销",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByName(gomock.Any(), "123456@qq.com").Return(domain.User{
+					Email:    "123456@qq.com",
+					Password: "$2a$10$N.edWE4zAEdb33BlrZiGe.R/yxjJSY2yhYIV2lWOstwxyGeLbMcuW",
+					Status:   domain.UserStatusDeleted,
+				}, nil)
+				return repo
+			},
+			email:    "123456@qq.com",
+			password: "Hello#world123",
+
+			wantUser: domain.User{},
+			wantErr:  ErrUserStatusFrozen,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -111,3 +145,305 @@ func Test_userService_Login(t *testing.T) {
 		})
 	}
 }
+
+func Test_userService_FindOrCreate(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		wantUser domain.User
+		wantErr  error
+	}{
+		{
+			name: "老用户",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "15811111111").
+					Return(domain.User{Id: 1, Phone: "15811111111"}, nil)
+				return repo
+			},
+			wantUser: domain.User{Id: 1, Phone: "15811111111"},
+		},
+		{
+			name: "新用户",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "15811111111").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				repo.EXPECT().Create(gomock.Any(), domain.User{Phone: "15811111111"}).Return(nil)
+				repo.EXPECT().FindByPhone(gomock.Any(), "15811111111").
+					Return(domain.User{Id: 1, Phone: "15811111111"}, nil)
+				return repo
+			},
+			wantUser: domain.User{Id: 1, Phone: "15811111111"},
+		},
+		{
+			name: "账号已被封禁",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "15811111111").
+					Return(domain.User{Id: 1, Phone: "15811111111", Status: domain.UserStatusBanned}, nil)
+				return repo
+			},
+			wantErr: ErrUserBanned,
+		},
+		{
+			name: "账号已经被合并",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "15811111111").
+					Return(domain.User{Id: 1, Phone: "15811111111", Status: domain.UserStatusMerged}, nil)
+				return repo
+			},
+			wantErr: ErrUserStatusFrozen,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserService(tc.mock(ctrl))
+			u, err := svc.FindOrCreate(context.Background(), "15811111111")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUser, u)
+		})
+	}
+}
+
+func Test_userService_ChangePassword(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		oldPassword string
+
+		wantErr error
+	}{
+		{
+			name: "修改成功",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{
+					Id:       1,
+					Password: "$2a$10$N.edWE4zAEdb33BlrZiGe.R/yxjJSY2yhYIV2lWOstwxyGeLbMcuW",
+				}, nil)
+				repo.EXPECT().UpdatePassword(gomock.Any(), int64(1), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, id int64, password string) error {
+						// 存进去的必须是新密码加密之后的结果
+						return bcrypt.CompareHashAndPassword([]byte(password), []byte("New#world123"))
+					})
+				return repo
+			},
+			oldPassword: "Hello#world123",
+		},
+		{
+			name: "旧密码错误",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{
+					Id:       1,
+					Password: "$2a$10$N.edWE4zAEdb33BlrZiGe.R/yxjJSY2yhYIV2lWOstwxyGeLbMcuW",
+				}, nil)
+				return repo
+			},
+			oldPassword: "Wrong#world123",
+			wantErr:     ErrInvalidUserOrPassword,
+		},
+		{
+			name: "没有设置过密码",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{Id: 1}, nil)
+				return repo
+			},
+			oldPassword: "",
+			wantErr:     ErrInvalidUserOrPassword,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserService(tc.mock(ctrl))
+			err := svc.ChangePassword(context.Background(), 1, tc.oldPassword, "New#world123")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func Test_userService_ResetPassword(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		wantUser domain.User
+		wantErr  error
+	}{
+		{
+			name: "重置成功",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "15811111111").
+					Return(domain.User{Id: 1, Phone: "15811111111"}, nil)
+				repo.EXPECT().UpdatePassword(gomock.Any(), int64(1), gomock.Any()).Return(nil)
+				return repo
+			},
+			wantUser: domain.User{Id: 1, Phone: "15811111111"},
+		},
+		{
+			name: "用户不存在",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "15811111111").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				return repo
+			},
+			wantErr: ErrUserNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserService(tc.mock(ctrl))
+			u, err := svc.ResetPassword(context.Background(), "15811111111", "New#world123")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUser, u)
+		})
+	}
+}
+
+func Test_userService_BindPhone(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		wantErr error
+	}{
+		{
+			name: "绑定成功",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "15811111111").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				repo.EXPECT().UpdateIdentity(gomock.Any(), domain.User{
+					Id:    1,
+					Email: "123@qq.com",
+					Phone: "15811111111",
+				}).Return(nil)
+				return repo
+			},
+		},
+		{
+			name: "已经绑定在自己身上",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "15811111111").
+					Return(domain.User{Id: 1, Phone: "15811111111"}, nil)
+				return repo
+			},
+		},
+		{
+			name: "属于别的账号",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "15811111111").
+					Return(domain.User{Id: 2, Phone: "15811111111"}, nil)
+				return repo
+			},
+			wantErr: ErrIdentityConflict,
+		},
+		{
+			name: "并发绑定，唯一索引冲突",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByPhone(gomock.Any(), "15811111111").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{Id: 1}, nil)
+				repo.EXPECT().UpdateIdentity(gomock.Any(), gomock.Any()).Return(repository.ErrDuplicateUser)
+				return repo
+			},
+			wantErr: ErrIdentityConflict,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserService(tc.mock(ctrl))
+			err := svc.BindPhone(context.Background(), 1, "15811111111")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func Test_userService_Unbind(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) repository.UserRepository
+		identity domain.Identity
+
+		wantErr error
+	}{
+		{
+			name: "解绑手机号",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{
+					Id:         1,
+					Phone:      "15811111111",
+					WechatInfo: domain.WechatInfo{OpenId: "open-id"},
+				}, nil)
+				repo.EXPECT().UpdateIdentity(gomock.Any(), domain.User{
+					Id:         1,
+					WechatInfo: domain.WechatInfo{OpenId: "open-id"},
+				}).Return(nil)
+				return repo
+			},
+			identity: domain.IdentityPhone,
+		},
+		{
+			name: "最后一种登录方式",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{
+					Id:         1,
+					WechatInfo: domain.WechatInfo{OpenId: "open-id"},
+				}, nil)
+				return repo
+			},
+			identity: domain.IdentityWechat,
+			wantErr:  ErrLastLoginMethod,
+		},
+		{
+			name: "没有密码的邮箱不算登录方式",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{
+					Id:    1,
+					Email: "123@qq.com",
+					Phone: "15811111111",
+				}, nil)
+				return repo
+			},
+			identity: domain.IdentityPhone,
+			wantErr:  ErrLastLoginMethod,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserService(tc.mock(ctrl))
+			err := svc.Unbind(context.Background(), 1, tc.identity)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
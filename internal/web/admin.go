@@ -0,0 +1,239 @@
+package web
+
+import (
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"webook/internal/domain"
+	"webook/internal/service"
+	"webook/internal/web/jwt"
+	"webook/internal/web/middleware"
+	"webook/pkg/logger"
+)
+
+// adminMaxLimit 管理后台一页最多查多少条
+const adminMaxLimit = 100
+
+// AdminHandler 管理后台，所有接口都要登录并且有对应的权限
+type AdminHandler struct {
+	userSvc  service.UserService
+	artSvc   service.ArticleService
+	mergeSvc service.UserMergeService
+	// jwtHdl 封禁用户、合并用户的时候踢掉所有会话
+	jwtHdl jwt.Handler
+	l      logger.LoggerV1
+}
+
+func NewAdminHandler(userSvc service.UserService, artSvc service.ArticleService,
+	mergeSvc service.UserMergeService, jwtHdl jwt.Handler, l logger.LoggerV1) *AdminHandler {
+	return &AdminHandler{
+		userSvc:  userSvc,
+		artSvc:   artSvc,
+		mergeSvc: mergeSvc,
+		jwtHdl:   jwtHdl,
+		l:        l,
+	}
+}
+
+func (h *AdminHandler) RegisterRoutes(s *gin.Engine) {
+	g := s.Group("/admin")
+	g.POST("/users/list", middleware.RequirePermission(domain.PermissionUserList), h.ListUsers)
+	g.POST("/users/ban", middleware.RequirePermission(domain.PermissionUserBan), h.BanUser)
+	g.POST("/users/merge", middleware.RequirePermission(domain.PermissionUserMerge), h.MergeUsers)
+	g.POST("/articles/takedown", middleware.RequirePermission(domain.PermissionArticleTakedown), h.TakeDownArticle)
+}
+
+func (h *AdminHandler) ListUsers(ctx *gin.Context) {
+	var req ListReq
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if req.Offset < 0 || req.Limit <= 0 || req.Limit > adminMaxLimit {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	users, err := h.userSvc.List(ctx, req.Offset, req.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询用户列表失败", logger.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: slice.Map[domain.User, UserVO](users, func(idx int, src domain.User) UserVO {
+			return UserVO{
+				Id:       src.Id,
+				Email:    src.Email,
+				Phone:    src.Phone,
+				NickName: src.NickName,
+				Roles:    src.Roles,
+				Status:   src.Status.ToUint8(),
+			}
+		}),
+	})
+}
+
+func (h *AdminHandler) BanUser(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+		// Ban true 是封禁，false 是解封
+		Ban bool `json:"ban"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	if req.Id == uc.Uid {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "不能封禁自己",
+		})
+		return
+	}
+	status := domain.UserStatusActive
+	if req.Ban {
+		status = domain.UserStatusBanned
+	}
+	err := h.userSvc.UpdateStatus(ctx, req.Id, status)
+	if err == service.ErrUserNotFound {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "用户不存在",
+		})
+		return
+	}
+	if err == service.ErrUserStatusFrozen {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "账号已经注销或者被合并",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("修改用户状态失败", logger.Int64("uid", req.Id), logger.Error(err))
+		return
+	}
+	if req.Ban {
+		// 已经登录的设备立刻下线，长 token 也会在 CheckSession 的时候被拦住
+		err = h.jwtHdl.RevokeAllSessions(ctx, req.Id)
+		if err != nil {
+			// 状态已经改了，再调用一次封禁就能重试
+			ctx.JSON(http.StatusOK, Result{
+				Code: 5,
+				Msg:  "系统错误",
+			})
+			h.l.Error("封禁用户之后踢掉会话失败", logger.Int64("uid", req.Id), logger.Error(err))
+			return
+		}
+	}
+	h.l.Info("管理员修改了用户状态", logger.Int64("operator", uc.Uid),
+		logger.Int64("uid", req.Id), logger.Field{Key: "ban", Value: req.Ban})
+	ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+}
+
+func (h *AdminHandler) MergeUsers(ctx *gin.Context) {
+	type Req struct {
+		// SourceId 被合并的账号，合并之后就不能再登录了
+		SourceId int64 `json:"sourceId"`
+		TargetId int64 `json:"targetId"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	detail, err := h.mergeSvc.Merge(ctx, req.SourceId, req.TargetId, uc.Uid)
+	switch err {
+	case nil:
+	case service.ErrMergeSelf:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "不能合并到自己",
+		})
+		return
+	case service.ErrUserNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "用户不存在",
+		})
+		return
+	case service.ErrUserMerged:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "用户已经被合并过了",
+		})
+		return
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("合并用户失败", logger.Int64("source", req.SourceId),
+			logger.Int64("target", req.TargetId), logger.Error(err))
+		return
+	}
+	h.l.Info("管理员合并了用户", logger.Int64("operator", uc.Uid),
+		logger.Int64("source", req.SourceId), logger.Int64("target", req.TargetId))
+	vo := UserMergeVO{
+		Email:    detail.Email,
+		Phone:    detail.Phone,
+		Wechat:   detail.Wechat,
+		Articles: detail.Articles,
+		Likes:    detail.Likes,
+		Collects: detail.Collects,
+	}
+	// 被合并的账号已经没有登录方式了，已经登录的设备也要下线
+	err = h.jwtHdl.RevokeAllSessions(ctx, req.SourceId)
+	if err != nil {
+		// 合并没法回滚，告诉管理员会话还在，刷新 token 的时候会被拦住
+		h.l.Error("合并用户之后踢掉会话失败", logger.Int64("uid", req.SourceId), logger.Error(err))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "合并成功，但是被合并账号的登录设备没有全部下线",
+			Data: vo,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: vo,
+	})
+}
+
+func (h *AdminHandler) TakeDownArticle(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(jwt.UserClaims)
+	err := h.artSvc.TakeDown(ctx, req.Id)
+	if err == service.ErrArticleNotFound {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "文章不存在",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("下架文章失败", logger.Int64("id", req.Id), logger.Error(err))
+		return
+	}
+	h.l.Info("管理员下架了文章", logger.Int64("operator", uc.Uid), logger.Int64("id", req.Id))
+	ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+}
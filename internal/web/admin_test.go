@@ -0,0 +1,306 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"webook/internal/domain"
+	"webook/internal/service"
+	svcmocks "webook/internal/service/mocks"
+	ijwt "webook/internal/web/jwt"
+	jwtmocks "webook/internal/web/jwt/mocks"
+	"webook/pkg/logger"
+)
+
+func TestAdminHandler_BanUser(t *testing.T) {
+	admin := ijwt.UserClaims{Uid: 1, Roles: []string{domain.RoleAdmin}}
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler)
+		user    ijwt.UserClaims
+		reqBody string
+
+		wantCode int
+		wantRes  Result
+	}{
+		{
+			name: "封禁成功",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().UpdateStatus(gomock.Any(), int64(123), domain.UserStatusBanned).Return(nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(123)).Return(nil)
+				return svc, hdl
+			},
+			user:     admin,
+			reqBody:  `{"id":123,"ban":true}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Msg: "OK"},
+		},
+		{
+			name: "解封成功",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().UpdateStatus(gomock.Any(), int64(123), domain.UserStatusActive).Return(nil)
+				return svc, jwtmocks.NewMockHandler(ctrl)
+			},
+			user:     admin,
+			reqBody:  `{"id":123,"ban":false}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Msg: "OK"},
+		},
+		{
+			name: "没有权限",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				return svcmocks.NewMockUserService(ctrl), jwtmocks.NewMockHandler(ctrl)
+			},
+			user:     ijwt.UserClaims{Uid: 2, Roles: []string{domain.RoleOperator}},
+			reqBody:  `{"id":123,"ban":true}`,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name: "不能封禁自己",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				return svcmocks.NewMockUserService(ctrl), jwtmocks.NewMockHandler(ctrl)
+			},
+			user:     admin,
+			reqBody:  `{"id":1,"ban":true}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 4, Msg: "不能封禁自己"},
+		},
+		{
+			name: "用户不存在",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().UpdateStatus(gomock.Any(), int64(123), domain.UserStatusBanned).
+					Return(service.ErrUserNotFound)
+				return svc, jwtmocks.NewMockHandler(ctrl)
+			},
+			user:     admin,
+			reqBody:  `{"id":123,"ban":true}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 4, Msg: "用户不存在"},
+		},
+		{
+			name: "已经被合并的账号不能解封",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().UpdateStatus(gomock.Any(), int64(123), domain.UserStatusActive).
+					Return(service.ErrUserStatusFrozen)
+				return svc, jwtmocks.NewMockHandler(ctrl)
+			},
+			user:     admin,
+			reqBody:  `{"id":123,"ban":false}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 4, Msg: "账号已经注销或者被合并"},
+		},
+		{
+			name: "系统错误",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().UpdateStatus(gomock.Any(), int64(123), domain.UserStatusBanned).
+					Return(errors.New("db 错误"))
+				return svc, jwtmocks.NewMockHandler(ctrl)
+			},
+			user:     admin,
+			reqBody:  `{"id":123,"ban":true}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 5, Msg: "系统错误"},
+		},
+		{
+			name: "踢掉会话失败",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().UpdateStatus(gomock.Any(), int64(123), domain.UserStatusBanned).Return(nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(123)).Return(errors.New("redis 错误"))
+				return svc, hdl
+			},
+			user:     admin,
+			reqBody:  `{"id":123,"ban":true}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 5, Msg: "系统错误"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, jwtHdl := tc.mock(ctrl)
+			hdl := NewAdminHandler(userSvc, nil, nil, jwtHdl, logger.NewNopLogger())
+
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", tc.user)
+			})
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/admin/users/ban", bytes.NewBufferString(tc.reqBody))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestAdminHandler_TakeDownArticle(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) service.ArticleService
+
+		wantRes Result
+	}{
+		{
+			name: "下架成功",
+			mock: func(ctrl *gomock.Controller) service.ArticleService {
+				svc := svcmocks.NewMockArticleService(ctrl)
+				svc.EXPECT().TakeDown(gomock.Any(), int64(10)).Return(nil)
+				return svc
+			},
+			wantRes: Result{Msg: "OK"},
+		},
+		{
+			name: "文章不存在",
+			mock: func(ctrl *gomock.Controller) service.ArticleService {
+				svc := svcmocks.NewMockArticleService(ctrl)
+				svc.EXPECT().TakeDown(gomock.Any(), int64(10)).Return(service.ErrArticleNotFound)
+				return svc
+			},
+			wantRes: Result{Code: 4, Msg: "文章不存在"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			hdl := NewAdminHandler(nil, tc.mock(ctrl), nil, nil, logger.NewNopLogger())
+
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 2, Roles: []string{domain.RoleOperator}})
+			})
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/admin/articles/takedown", bytes.NewBufferString(`{"id":10}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestAdminHandler_MergeUsers(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserMergeService, ijwt.Handler)
+
+		wantRes Result
+	}{
+		{
+			name: "合并成功",
+			mock: func(ctrl *gomock.Controller) (service.UserMergeService, ijwt.Handler) {
+				svc := svcmocks.NewMockUserMergeService(ctrl)
+				svc.EXPECT().Merge(gomock.Any(), int64(10), int64(20), int64(1)).
+					Return(domain.UserMergeDetail{Phone: true, Articles: 2}, nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(10)).Return(nil)
+				return svc, hdl
+			},
+			wantRes: Result{Data: map[string]any{
+				"email":    false,
+				"phone":    true,
+				"wechat":   false,
+				"articles": float64(2),
+				"likes":    float64(0),
+				"collects": float64(0),
+			}},
+		},
+		{
+			name: "踢掉会话失败",
+			mock: func(ctrl *gomock.Controller) (service.UserMergeService, ijwt.Handler) {
+				svc := svcmocks.NewMockUserMergeService(ctrl)
+				svc.EXPECT().Merge(gomock.Any(), int64(10), int64(20), int64(1)).
+					Return(domain.UserMergeDetail{Phone: true, Articles: 2}, nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(10)).Return(errors.New("redis 错误"))
+				return svc, hdl
+			},
+			wantRes: Result{
+				Code: 5,
+				Msg:  "合并成功，但是被合并账号的登录设备没有全部下线",
+				Data: map[string]any{
+					"email":    false,
+					"phone":    true,
+					"wechat":   false,
+					"articles": float64(2),
+					"likes":    float64(0),
+					"collects": float64(0),
+				},
+			},
+		},
+		{
+			name: "已经被合并过了",
+			mock: func(ctrl *gomock.Controller) (service.UserMergeService, ijwt.Handler) {
+				svc := svcmocks.NewMockUserMergeService(ctrl)
+				svc.EXPECT().Merge(gomock.Any(), int64(10), int64(20), int64(1)).
+					Return(domain.UserMergeDetail{}, service.ErrUserMerged)
+				return svc, jwtmocks.NewMockHandler(ctrl)
+			},
+			wantRes: Result{Code: 4, Msg: "用户已经被合并过了"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mergeSvc, jwtHdl := tc.mock(ctrl)
+			hdl := NewAdminHandler(nil, nil, mergeSvc, jwtHdl, logger.NewNopLogger())
+
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 1, Roles: []string{domain.RoleAdmin}})
+			})
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/admin/users/merge",
+				bytes.NewBufferString(`{"sourceId":10,"targetId":20}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
@@ -1,23 +1,46 @@
 package web
 
 import (
+	"context"
+	"github.com/ecodeclub/ekit/slice"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strconv"
+	"time"
 	"webook/internal/domain"
+	"webook/internal/events/article"
 	"webook/internal/service"
 	"webook/internal/web/jwt"
+	"webook/internal/web/middleware"
 	"webook/pkg/logger"
 )
 
+const (
+	// articleDefaultLimit 没有传 limit 的时候一页查多少条
+	articleDefaultLimit = 10
+	// articleMaxLimit 一页最多查多少条，超过了就按最大值查
+	articleMaxLimit = 100
+)
+
 type ArticleHandler struct {
-	svc service.ArticleService
-	l   logger.LoggerV1
+	svc        service.ArticleService
+	intrSvc    service.InteractiveService
+	rankingSvc service.RankingService
+	producer   article.Producer
+	l          logger.LoggerV1
+	biz        string
 }
 
-func NewArticleHandler(l logger.LoggerV1, svc service.ArticleService) *ArticleHandler {
+func NewArticleHandler(l logger.LoggerV1, svc service.ArticleService,
+	intrSvc service.InteractiveService, rankingSvc service.RankingService,
+	producer article.Producer) *ArticleHandler {
 	return &ArticleHandler{
-		svc: svc,
-		l:   l,
+		svc:        svc,
+		intrSvc:    intrSvc,
+		rankingSvc: rankingSvc,
+		producer:   producer,
+		l:          l,
+		biz:        "article",
 	}
 }
 
@@ -25,6 +48,23 @@ func (h *ArticleHandler) RegisterRoutes(s *gin.Engine) {
 	g := s.Group("/articles")
 	g.POST("/edit", h.Edit)
 	g.POST("/publish", h.Publish)
+	g.POST("/withdraw", h.Withdraw)
+	g.GET("/detail/:id", h.Detail)
+	g.POST("/list", h.List)
+	g.GET("/ranking", h.Ranking)
+
+	pub := g.Group("/pub")
+	pub.GET("/:id", h.PubDetail)
+	pub.POST("/like", h.Like)
+	pub.POST("/collect", h.Collect)
+}
+
+func (h *ArticleHandler) AuthPolicy() middleware.AuthPolicy {
+	return middleware.AuthPolicy{
+		Ignore: []string{"/articles/ranking"},
+		// 没登录也可以看已发表的文章，登录了就能看到自己有没有点赞收藏
+		Optional: []string{"/articles/pub/:id"},
+	}
 }
 
 func (h *ArticleHandler) Edit(ctx *gin.Context) {
@@ -51,6 +91,13 @@ func (h *ArticleHandler) Edit(ctx *gin.Context) {
 			Id: uc.Uid,
 		},
 	})
+	if err == service.ErrArticleTakenDown {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "文章已被下架",
+		})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Msg: "系统错误",
@@ -84,6 +131,13 @@ func (h *ArticleHandler) Publish(ctx *gin.Context) {
 			Id: uc.Uid,
 		},
 	})
+	if err == service.ErrArticleTakenDown {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "文章已被下架",
+		})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Code: 5,
@@ -93,3 +147,300 @@ func (h *ArticleHandler) Publish(ctx *gin.Context) {
 	}
 	ctx.JSON(http.StatusOK, Result{Data: id})
 }
+
+func (h *ArticleHandler) Withdraw(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+
+	uc, ok := ctx.MustGet("user").(jwt.UserClaims)
+	if !ok {
+		return
+	}
+	err := h.svc.Withdraw(ctx, uc.Uid, req.Id)
+	if err == service.ErrArticleTakenDown {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "文章已被下架",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("撤回文章失败", logger.Int64("uid", uc.Uid), logger.Int64("id", req.Id), logger.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+}
+
+func (h *ArticleHandler) Detail(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		h.l.Warn("查询文章失败，id 格式不对", logger.Field{Key: "id", Value: idStr}, logger.Error(err))
+		return
+	}
+
+	uc, ok := ctx.MustGet("user").(jwt.UserClaims)
+	if !ok {
+		return
+	}
+	art, err := h.svc.GetById(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询文章失败", logger.Int64("id", id), logger.Error(err))
+		return
+	}
+	// 制作库的文章只有作者本人能看
+	if art.Author.Id != uc.Uid {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "输入有误",
+		})
+		h.l.Warn("非法访问文章，创作者 ID 不匹配", logger.Int64("uid", uc.Uid), logger.Int64("id", id))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Data: ArticleVO{
+		Id:       art.Id,
+		Title:    art.Title,
+		Content:  art.Content,
+		AuthorId: art.Author.Id,
+		Status:   art.Status.ToUint8(),
+		Ctime:    art.Ctime.Format(time.DateTime),
+		Utime:    art.Utime.Format(time.DateTime),
+	}})
+}
+
+func (h *ArticleHandler) List(ctx *gin.Context) {
+	var req ListReq
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if req.Offset < 0 || req.Limit < 0 {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = articleDefaultLimit
+	}
+	if req.Limit > articleMaxLimit {
+		req.Limit = articleMaxLimit
+	}
+	uc, ok := ctx.MustGet("user").(jwt.UserClaims)
+	if !ok {
+		return
+	}
+	arts, err := h.svc.GetByAuthor(ctx, uc.Uid, req.Offset, req.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查找文章列表失败", logger.Int64("uid", uc.Uid), logger.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: slice.Map[domain.Article, ArticleVO](arts, func(idx int, src domain.Article) ArticleVO {
+			// 列表页不返回全文，只返回摘要
+			return ArticleVO{
+				Id:       src.Id,
+				Title:    src.Title,
+				Abstract: src.Abstract(),
+				AuthorId: src.Author.Id,
+				Status:   src.Status.ToUint8(),
+				Ctime:    src.Ctime.Format(time.DateTime),
+				Utime:    src.Utime.Format(time.DateTime),
+			}
+		}),
+	})
+}
+
+func (h *ArticleHandler) PubDetail(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		h.l.Warn("查询文章失败，id 格式不对", logger.Field{Key: "id", Value: idStr}, logger.Error(err))
+		return
+	}
+	// 没有登录的时候 uid 是 0
+	val, _ := ctx.Get("user")
+	uc, _ := val.(jwt.UserClaims)
+	art, err := h.svc.GetPubById(ctx, id)
+	if err == service.ErrArticleNotFound {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "文章不存在",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询线上库文章失败", logger.Int64("id", id), logger.Error(err))
+		return
+	}
+
+	// 阅读数通过消息异步增加，发送失败不影响主流程
+	pctx, cancel := context.WithTimeout(ctx, time.Second)
+	er := h.producer.ProduceReadEvent(pctx, article.ReadEvent{
+		Uid: uc.Uid,
+		Aid: art.Id,
+	})
+	cancel()
+	if er != nil {
+		h.l.Error("发送阅读事件失败", logger.Int64("aid", art.Id), logger.Error(er))
+	}
+
+	intr, err := h.intrSvc.Get(ctx, h.biz, id, uc.Uid)
+	if err != nil {
+		// 拿不到计数也可以返回文章内容
+		h.l.Error("查询文章互动数据失败", logger.Int64("id", id), logger.Int64("uid", uc.Uid), logger.Error(err))
+	}
+	ctx.JSON(http.StatusOK, Result{Data: ArticleVO{
+		Id:         art.Id,
+		Title:      art.Title,
+		Content:    art.Content,
+		AuthorId:   art.Author.Id,
+		Status:     art.Status.ToUint8(),
+		ReadCnt:    intr.ReadCnt,
+		LikeCnt:    intr.LikeCnt,
+		CollectCnt: intr.CollectCnt,
+		Liked:      intr.Liked,
+		Collected:  intr.Collected,
+		Ctime:      art.Ctime.Format(time.DateTime),
+		Utime:      art.Utime.Format(time.DateTime),
+	}})
+}
+
+func (h *ArticleHandler) Like(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+		// true 是点赞，false 是取消点赞
+		Like bool `json:"like"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(jwt.UserClaims)
+	if !ok {
+		return
+	}
+	if !h.checkPublished(ctx, req.Id) {
+		return
+	}
+	var err error
+	if req.Like {
+		err = h.intrSvc.Like(ctx, h.biz, req.Id, uc.Uid)
+	} else {
+		err = h.intrSvc.CancelLike(ctx, h.biz, req.Id, uc.Uid)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("点赞/取消点赞失败", logger.Int64("uid", uc.Uid), logger.Int64("aid", req.Id), logger.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+}
+
+func (h *ArticleHandler) Collect(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+		// 收藏夹的 ID
+		Cid int64 `json:"cid"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(jwt.UserClaims)
+	if !ok {
+		return
+	}
+	if !h.checkPublished(ctx, req.Id) {
+		return
+	}
+	err := h.intrSvc.Collect(ctx, h.biz, req.Id, req.Cid, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("收藏失败", logger.Int64("uid", uc.Uid), logger.Int64("aid", req.Id), logger.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+}
+
+// checkPublished 只能对已发表的文章点赞、收藏，不然随便一个 id 都能写出互动数据
+// 返回 false 的时候已经写好了响应
+func (h *ArticleHandler) checkPublished(ctx *gin.Context, id int64) bool {
+	_, err := h.svc.GetPubById(ctx, id)
+	if err == service.ErrArticleNotFound {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "文章不存在",
+		})
+		return false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询线上库文章失败", logger.Int64("id", id), logger.Error(err))
+		return false
+	}
+	return true
+}
+
+func (h *ArticleHandler) Ranking(ctx *gin.Context) {
+	arts, err := h.rankingSvc.GetTopN(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.l.Error("查询热榜失败", logger.Error(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: slice.Map[domain.Article, ArticleVO](arts, func(idx int, src domain.Article) ArticleVO {
+			return ArticleVO{
+				Id:       src.Id,
+				Title:    src.Title,
+				Abstract: src.Abstract(),
+				AuthorId: src.Author.Id,
+				Status:   src.Status.ToUint8(),
+				Ctime:    src.Ctime.Format(time.DateTime),
+				Utime:    src.Utime.Format(time.DateTime),
+			}
+		}),
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"webook/internal/domain"
 	"webook/internal/service"
 	svcmocks "webook/internal/service/mocks"
@@ -112,7 +113,7 @@ func TestArticleHandler_Publish(t *testing.T) {
 			artSvc := tc.mock(ctrl)
 
 			// 利用mock构造UserHandler
-			hdl := NewArticleHandler(logger.NewNopLogger(), artSvc)
+			hdl := NewArticleHandler(logger.NewNopLogger(), artSvc, nil, nil, nil)
 
 			// 准备服务器 注册路由
 			server := gin.Default()
@@ -145,3 +146,463 @@ func TestArticleHandler_Publish(t *testing.T) {
 		})
 	}
 }
+
+func TestArticleHandler_Detail(t *testing.T) {
+	testCase := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) service.ArticleService
+		id       string
+		wantCode int
+		wantRes  Result
+	}{
+		{
+			name: "查询成功",
+			mock: func(ctrl *gomock.Controller) service.ArticleService {
+				svc := svcmocks.NewMockArticleService(ctrl)
+				svc.EXPECT().GetById(gomock.Any(), int64(1)).Return(domain.Article{
+					Id:      1,
+					Title:   "我的标题",
+					Content: "我的内容",
+					Author:  domain.Author{Id: 123},
+					Status:  domain.ArticleStatusUnpublished,
+					Ctime:   time.UnixMilli(101),
+					Utime:   time.UnixMilli(102),
+				}, nil)
+				return svc
+			},
+			id:       "1",
+			wantCode: http.StatusOK,
+			wantRes: Result{Data: map[string]any{
+				"id":         float64(1),
+				"title":      "我的标题",
+				"abstract":   "",
+				"content":    "我的内容",
+				"authorId":   float64(123),
+				"status":     float64(1),
+				"readCnt":    float64(0),
+				"likeCnt":    float64(0),
+				"collectCnt": float64(0),
+				"liked":      false,
+				"collected":  false,
+				"ctime":      time.UnixMilli(101).Format(time.DateTime),
+				"utime":      time.UnixMilli(102).Format(time.DateTime),
+			}},
+		},
+		{
+			name: "别人的帖子",
+			mock: func(ctrl *gomock.Controller) service.ArticleService {
+				svc := svcmocks.NewMockArticleService(ctrl)
+				svc.EXPECT().GetById(gomock.Any(), int64(1)).Return(domain.Article{
+					Id:      1,
+					Title:   "我的标题",
+					Content: "我的内容",
+					Author:  domain.Author{Id: 456},
+				}, nil)
+				return svc
+			},
+			id:       "1",
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 4, Msg: "输入有误"},
+		},
+		{
+			name: "查询失败",
+			mock: func(ctrl *gomock.Controller) service.ArticleService {
+				svc := svcmocks.NewMockArticleService(ctrl)
+				svc.EXPECT().GetById(gomock.Any(), int64(1)).Return(domain.Article{}, errors.New("mock error"))
+				return svc
+			},
+			id:       "1",
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 5, Msg: "系统错误"},
+		},
+		{
+			name: "id 格式不对",
+			mock: func(ctrl *gomock.Controller) service.ArticleService {
+				return svcmocks.NewMockArticleService(ctrl)
+			},
+			id:       "abc",
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 4, Msg: "参数错误"},
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			artSvc := tc.mock(ctrl)
+
+			hdl := NewArticleHandler(logger.NewNopLogger(), artSvc, nil, nil, nil)
+
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{
+					Uid: 123,
+				})
+			})
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodGet, "/articles/detail/"+tc.id, nil)
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestArticleHandler_List(t *testing.T) {
+	testCase := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) service.ArticleService
+		reqBody  string
+		wantCode int
+		wantRes  Result
+	}{
+		{
+			name: "查询成功",
+			mock: func(ctrl *gomock.Controller) service.ArticleService {
+				svc := svcmocks.NewMockArticleService(ctrl)
+				svc.EXPECT().GetByAuthor(gomock.Any(), int64(123), 10, 20).Return([]domain.Article{}, nil)
+				return svc
+			},
+			reqBody:  `{"offset":10,"limit":20}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Data: []any{}},
+		},
+		{
+			name: "没有传 limit，用默认值",
+			mock: func(ctrl *gomock.Controller) service.ArticleService {
+				svc := svcmocks.NewMockArticleService(ctrl)
+				svc.EXPECT().GetByAuthor(gomock.Any(), int64(123), 0, articleDefaultLimit).Return([]domain.Article{}, nil)
+				return svc
+			},
+			reqBody:  `{}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Data: []any{}},
+		},
+		{
+			name: "limit 太大，按最大值查",
+			mock: func(ctrl *gomock.Controller) service.ArticleService {
+				svc := svcmocks.NewMockArticleService(ctrl)
+				svc.EXPECT().GetByAuthor(gomock.Any(), int64(123), 0, articleMaxLimit).Return([]domain.Article{}, nil)
+				return svc
+			},
+			reqBody:  `{"offset":0,"limit":100000}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Data: []any{}},
+		},
+		{
+			name: "offset 是负数",
+			mock: func(ctrl *gomock.Controller) service.ArticleService {
+				return svcmocks.NewMockArticleService(ctrl)
+			},
+			reqBody:  `{"offset":-1,"limit":10}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 4, Msg: "参数错误"},
+		},
+		{
+			name: "limit 是负数",
+			mock: func(ctrl *gomock.Controller) service.ArticleService {
+				return svcmocks.NewMockArticleService(ctrl)
+			},
+			reqBody:  `{"offset":0,"limit":-1}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 4, Msg: "参数错误"},
+		},
+		{
+			name: "查询失败",
+			mock: func(ctrl *gomock.Controller) service.ArticleService {
+				svc := svcmocks.NewMockArticleService(ctrl)
+				svc.EXPECT().GetByAuthor(gomock.Any(), int64(123), 0, 10).Return(nil, errors.New("mock error"))
+				return svc
+			},
+			reqBody:  `{"offset":0,"limit":10}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 5, Msg: "系统错误"},
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			artSvc := tc.mock(ctrl)
+
+			hdl := NewArticleHandler(logger.NewNopLogger(), artSvc, nil, nil, nil)
+
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{
+					Uid: 123,
+				})
+			})
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/articles/list", bytes.NewBufferString(tc.reqBody))
+			req.Header.Set("Content-Type", "application/json")
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestArticleHandler_Withdraw(t *testing.T) {
+	testCase := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) service.ArticleService
+		reqBody  string
+		wantCode int
+		wantRes  Result
+	}{
+		{
+			name: "撤回成功",
+			mock: func(ctrl *gomock.Controller) service.ArticleService {
+				svc := svcmocks.NewMockArticleService(ctrl)
+				svc.EXPECT().Withdraw(gomock.Any(), int64(123), int64(1)).Return(nil)
+				return svc
+			},
+			reqBody:  `{"id":1}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Msg: "OK"},
+		},
+		{
+			name: "撤回失败",
+			mock: func(ctrl *gomock.Controller) service.ArticleService {
+				svc := svcmocks.NewMockArticleService(ctrl)
+				svc.EXPECT().Withdraw(gomock.Any(), int64(123), int64(1)).Return(errors.New("mock error"))
+				return svc
+			},
+			reqBody:  `{"id":1}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 5, Msg: "系统错误"},
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			artSvc := tc.mock(ctrl)
+
+			hdl := NewArticleHandler(logger.NewNopLogger(), artSvc, nil, nil, nil)
+
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{
+					Uid: 123,
+				})
+			})
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/articles/withdraw", bytes.NewBufferString(tc.reqBody))
+			req.Header.Set("Content-Type", "application/json")
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestArticleHandler_Like(t *testing.T) {
+	testCase := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) (service.ArticleService, service.InteractiveService)
+		reqBody  string
+		wantCode int
+		wantRes  Result
+	}{
+		{
+			name: "点赞成功",
+			mock: func(ctrl *gomock.Controller) (service.ArticleService, service.InteractiveService) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().GetPubById(gomock.Any(), int64(1)).Return(domain.Article{Id: 1}, nil)
+				svc := svcmocks.NewMockInteractiveService(ctrl)
+				svc.EXPECT().Like(gomock.Any(), "article", int64(1), int64(123)).Return(nil)
+				return artSvc, svc
+			},
+			reqBody:  `{"id":1,"like":true}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Msg: "OK"},
+		},
+		{
+			name: "取消点赞成功",
+			mock: func(ctrl *gomock.Controller) (service.ArticleService, service.InteractiveService) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().GetPubById(gomock.Any(), int64(1)).Return(domain.Article{Id: 1}, nil)
+				svc := svcmocks.NewMockInteractiveService(ctrl)
+				svc.EXPECT().CancelLike(gomock.Any(), "article", int64(1), int64(123)).Return(nil)
+				return artSvc, svc
+			},
+			reqBody:  `{"id":1,"like":false}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Msg: "OK"},
+		},
+		{
+			name: "文章不存在或者没有发表",
+			mock: func(ctrl *gomock.Controller) (service.ArticleService, service.InteractiveService) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().GetPubById(gomock.Any(), int64(1)).Return(domain.Article{}, service.ErrArticleNotFound)
+				return artSvc, svcmocks.NewMockInteractiveService(ctrl)
+			},
+			reqBody:  `{"id":1,"like":true}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 4, Msg: "文章不存在"},
+		},
+		{
+			name: "查询文章失败",
+			mock: func(ctrl *gomock.Controller) (service.ArticleService, service.InteractiveService) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().GetPubById(gomock.Any(), int64(1)).Return(domain.Article{}, errors.New("mock error"))
+				return artSvc, svcmocks.NewMockInteractiveService(ctrl)
+			},
+			reqBody:  `{"id":1,"like":true}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 5, Msg: "系统错误"},
+		},
+		{
+			name: "点赞失败",
+			mock: func(ctrl *gomock.Controller) (service.ArticleService, service.InteractiveService) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().GetPubById(gomock.Any(), int64(1)).Return(domain.Article{Id: 1}, nil)
+				svc := svcmocks.NewMockInteractiveService(ctrl)
+				svc.EXPECT().Like(gomock.Any(), "article", int64(1), int64(123)).Return(errors.New("mock error"))
+				return artSvc, svc
+			},
+			reqBody:  `{"id":1,"like":true}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 5, Msg: "系统错误"},
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			artSvc, intrSvc := tc.mock(ctrl)
+
+			hdl := NewArticleHandler(logger.NewNopLogger(), artSvc, intrSvc, nil, nil)
+
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{
+					Uid: 123,
+				})
+			})
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/articles/pub/like", bytes.NewBufferString(tc.reqBody))
+			req.Header.Set("Content-Type", "application/json")
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestArticleHandler_Collect(t *testing.T) {
+	testCase := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) (service.ArticleService, service.InteractiveService)
+		reqBody  string
+		wantCode int
+		wantRes  Result
+	}{
+		{
+			name: "收藏成功",
+			mock: func(ctrl *gomock.Controller) (service.ArticleService, service.InteractiveService) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().GetPubById(gomock.Any(), int64(1)).Return(domain.Article{Id: 1}, nil)
+				svc := svcmocks.NewMockInteractiveService(ctrl)
+				svc.EXPECT().Collect(gomock.Any(), "article", int64(1), int64(2), int64(123)).Return(nil)
+				return artSvc, svc
+			},
+			reqBody:  `{"id":1,"cid":2}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Msg: "OK"},
+		},
+		{
+			name: "文章不存在或者没有发表",
+			mock: func(ctrl *gomock.Controller) (service.ArticleService, service.InteractiveService) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().GetPubById(gomock.Any(), int64(1)).Return(domain.Article{}, service.ErrArticleNotFound)
+				return artSvc, svcmocks.NewMockInteractiveService(ctrl)
+			},
+			reqBody:  `{"id":1,"cid":2}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 4, Msg: "文章不存在"},
+		},
+		{
+			name: "收藏失败",
+			mock: func(ctrl *gomock.Controller) (service.ArticleService, service.InteractiveService) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().GetPubById(gomock.Any(), int64(1)).Return(domain.Article{Id: 1}, nil)
+				svc := svcmocks.NewMockInteractiveService(ctrl)
+				svc.EXPECT().Collect(gomock.Any(), "article", int64(1), int64(2), int64(123)).Return(errors.New("mock error"))
+				return artSvc, svc
+			},
+			reqBody:  `{"id":1,"cid":2}`,
+			wantCode: http.StatusOK,
+			wantRes:  Result{Code: 5, Msg: "系统错误"},
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			artSvc, intrSvc := tc.mock(ctrl)
+
+			hdl := NewArticleHandler(logger.NewNopLogger(), artSvc, intrSvc, nil, nil)
+
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{
+					Uid: 123,
+				})
+			})
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/articles/pub/collect", bytes.NewBufferString(tc.reqBody))
+			req.Header.Set("Content-Type", "application/json")
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
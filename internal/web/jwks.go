@@ -0,0 +1,36 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	ijwt "webook/internal/web/jwt"
+	"webook/internal/web/middleware"
+)
+
+// JWKSHandler 对外公开校验 token 用的公钥，其它服务可以自己校验 webook 的 token
+type JWKSHandler struct {
+	hdl ijwt.Handler
+}
+
+func NewJWKSHandler(hdl ijwt.Handler) *JWKSHandler {
+	return &JWKSHandler{
+		hdl: hdl,
+	}
+}
+
+func (h *JWKSHandler) RegisterRoutes(server *gin.Engine) {
+	server.GET("/.well-known/jwks.json", h.JWKS)
+}
+
+func (h *JWKSHandler) AuthPolicy() middleware.AuthPolicy {
+	return middleware.AuthPolicy{
+		Ignore: []string{"/.well-known/jwks.json"},
+	}
+}
+
+// JWKS 按照 RFC 7517 的格式返回，不包在 Result 里面
+func (h *JWKSHandler) JWKS(ctx *gin.Context) {
+	// 密钥轮换的时候新公钥要提前发布，所以缓存时间不能太长
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, h.hdl.JWKS())
+}
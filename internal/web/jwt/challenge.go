@@ -0,0 +1,77 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	// challengeAudience 区分挑战 token 和刷新 token，它们用的是同一套密钥
+	challengeAudience    = "2fa"
+	challengeExpiration  = time.Minute * 5
+	challengeMaxAttempts = 5
+)
+
+var ErrChallengeExhausted = errors.New("挑战 token 已经失效")
+
+// ChallengeClaims 密码校验通过，但是还没有通过两步验证
+type ChallengeClaims struct {
+	jwt.RegisteredClaims
+	Uid int64
+}
+
+// NewChallengeToken 用刷新 token 的密钥签名，
+// 长 token 的公钥可能是公开的，而且挑战 token 绝对不能被当成长 token 用
+func (h *RedisJWTHandler) NewChallengeToken(uid int64) (string, error) {
+	cc := ChallengeClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Audience:  jwt.ClaimStrings{challengeAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(challengeExpiration)),
+		},
+		Uid: uid,
+	}
+	return h.refreshKeys.Sign(refreshSigningMethod, cc)
+}
+
+func (h *RedisJWTHandler) VerifyChallengeToken(ctx *gin.Context, tokenStr string) (ChallengeClaims, error) {
+	var cc ChallengeClaims
+	token, err := jwt.ParseWithClaims(tokenStr, &cc, h.refreshKeys.Keyfunc,
+		jwt.WithValidMethods([]string{refreshSigningMethod.Alg()}),
+		jwt.WithAudience(challengeAudience))
+	if err != nil {
+		return ChallengeClaims{}, err
+	}
+	if token == nil || !token.Valid || cc.ID == "" {
+		return ChallengeClaims{}, errors.New("token 无效")
+	}
+	// 验证码只有 6 位，每个挑战 token 只能试几次
+	key := h.challengeKey(cc.ID)
+	cnt, err := h.client.Incr(ctx, key).Result()
+	if err != nil {
+		return ChallengeClaims{}, err
+	}
+	if cnt == 1 {
+		err = h.client.Expire(ctx, key, challengeExpiration).Err()
+		if err != nil {
+			return ChallengeClaims{}, err
+		}
+	}
+	if cnt > challengeMaxAttempts {
+		return ChallengeClaims{}, ErrChallengeExhausted
+	}
+	return cc, nil
+}
+
+func (h *RedisJWTHandler) FinishChallenge(ctx *gin.Context, cc ChallengeClaims) error {
+	return h.client.Set(ctx, h.challengeKey(cc.ID), challengeMaxAttempts, challengeExpiration).Err()
+}
+
+// challengeKey 挑战 token 已经尝试的次数
+func (h *RedisJWTHandler) challengeKey(jti string) string {
+	return fmt.Sprintf("users:challenge:%s", jti)
+}
@@ -0,0 +1,92 @@
+package jwt
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http/httptest"
+	"testing"
+	"webook/internal/repository/cache/redismocks"
+)
+
+func TestRedisJWTHandler_VerifyChallengeToken(t *testing.T) {
+	incrRes := func(val int64) *redis.IntCmd {
+		cmd := redis.NewIntCmd(context.Background())
+		cmd.SetVal(val)
+		return cmd
+	}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+
+		wantErr error
+	}{
+		{
+			name: "第一次尝试",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Incr(gomock.Any(), gomock.Any()).Return(incrRes(1))
+				cmd.EXPECT().Expire(gomock.Any(), gomock.Any(), challengeExpiration).
+					Return(redis.NewBoolResult(true, nil))
+				return cmd
+			},
+		},
+		{
+			name: "最后一次尝试",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Incr(gomock.Any(), gomock.Any()).Return(incrRes(challengeMaxAttempts))
+				return cmd
+			},
+		},
+		{
+			name: "尝试次数太多",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Incr(gomock.Any(), gomock.Any()).Return(incrRes(challengeMaxAttempts + 1))
+				return cmd
+			},
+			wantErr: ErrChallengeExhausted,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			accessKeys, err := NewKeyRing(Key{Id: "a1", Secret: "access"})
+			require.NoError(t, err)
+			refreshKeys, err := NewKeyRing(Key{Id: "r1", Secret: "refresh"})
+			require.NoError(t, err)
+			hdl := NewRedisJWTHandler(tc.mock(ctrl), jwt.SigningMethodHS512, accessKeys, refreshKeys)
+
+			token, err := hdl.NewChallengeToken(123)
+			require.NoError(t, err)
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			cc, err := hdl.VerifyChallengeToken(ctx, token)
+			assert.Equal(t, tc.wantErr, err)
+			if err == nil {
+				assert.Equal(t, int64(123), cc.Uid)
+			}
+		})
+	}
+}
+
+func TestRedisJWTHandler_ChallengeTokenMisuse(t *testing.T) {
+	accessKeys, err := NewKeyRing(Key{Id: "a1", Secret: "access"})
+	require.NoError(t, err)
+	refreshKeys, err := NewKeyRing(Key{Id: "r1", Secret: "refresh"})
+	require.NoError(t, err)
+	hdl := NewRedisJWTHandler(nil, jwt.SigningMethodHS512, accessKeys, refreshKeys)
+	token, err := hdl.NewChallengeToken(123)
+	require.NoError(t, err)
+
+	// 挑战 token 既不能当长 token，也不能当刷新 token
+	_, err = hdl.VerifyAccessToken(token)
+	assert.Error(t, err)
+	_, err = hdl.VerifyRefreshToken(token)
+	assert.Error(t, err)
+}
@@ -0,0 +1,216 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"sync"
+)
+
+var (
+	ErrEmptyKeyRing = errors.New("至少需要一个签名密钥")
+	ErrUnknownKey   = errors.New("未知的签名密钥")
+)
+
+// Key 签名密钥，Id 会放在 token 的 kid 头部
+// Secret 和 PrivateKey 二选一：
+// Secret 是 HMAC 的密钥；PrivateKey 是 PEM 格式的 RSA 或者 Ed25519 私钥
+type Key struct {
+	Id         string `yaml:"id"`
+	Secret     string `yaml:"secret"`
+	PrivateKey string `yaml:"privateKey"`
+	// Legacy 引入 kid 之前签发的 token 没有 kid 头部，用这个密钥校验。
+	// 只在过渡期间打开，等这些 token 都过期了就去掉
+	Legacy bool `yaml:"legacy"`
+}
+
+// signingKey 解析之后的密钥
+type signingKey struct {
+	id string
+	// sign 签名用的密钥
+	sign any
+	// verify 校验用的密钥，HMAC 的时候和 sign 是同一个
+	verify any
+}
+
+// KeyRing 密钥环
+// 第一个密钥是当前用来签名的密钥，后面的是之前用过的密钥，只用来校验。
+// 轮换密钥的时候，把新的密钥放在最前面，旧的密钥往后挪，
+// 等旧密钥签发的 token 都过期了再把它删掉，这样轮换的时候用户不会被踢下线。
+type KeyRing struct {
+	lock    sync.RWMutex
+	current signingKey
+	keys    map[string]signingKey
+	// legacy 校验没有 kid 的 token 用的密钥，为空表示不接受没有 kid 的 token
+	legacy string
+	// ids 保持配置里面的顺序，输出 JWKS 的时候用
+	ids []string
+}
+
+func NewKeyRing(keys ...Key) (*KeyRing, error) {
+	r := &KeyRing{}
+	err := r.Reset(keys...)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reset 整体替换密钥，第一个是当前签名用的密钥
+func (r *KeyRing) Reset(keys ...Key) error {
+	if len(keys) == 0 {
+		return ErrEmptyKeyRing
+	}
+	m := make(map[string]signingKey, len(keys))
+	ids := make([]string, 0, len(keys))
+	var legacy string
+	for _, k := range keys {
+		if _, ok := m[k.Id]; ok {
+			return fmt.Errorf("密钥 id 重复, id: %s", k.Id)
+		}
+		if k.Legacy {
+			if legacy != "" {
+				return fmt.Errorf("只能有一个 legacy 密钥, id: %s, %s", legacy, k.Id)
+			}
+			legacy = k.Id
+		}
+		sk, err := parseKey(k)
+		if err != nil {
+			return err
+		}
+		m[k.Id] = sk
+		ids = append(ids, k.Id)
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.current = m[keys[0].Id]
+	r.keys = m
+	r.legacy = legacy
+	r.ids = ids
+	return nil
+}
+
+func parseKey(k Key) (signingKey, error) {
+	if k.Id == "" {
+		return signingKey{}, errors.New("密钥的 id 不能为空")
+	}
+	switch {
+	case k.Secret != "" && k.PrivateKey != "":
+		return signingKey{}, fmt.Errorf("secret 和 privateKey 只能配置一个, id: %s", k.Id)
+	case k.Secret != "":
+		return signingKey{id: k.Id, sign: []byte(k.Secret), verify: []byte(k.Secret)}, nil
+	case k.PrivateKey != "":
+		pem := []byte(k.PrivateKey)
+		if rk, err := jwt.ParseRSAPrivateKeyFromPEM(pem); err == nil {
+			return signingKey{id: k.Id, sign: rk, verify: &rk.PublicKey}, nil
+		}
+		ek, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+		if err != nil {
+			return signingKey{}, fmt.Errorf("无法解析私钥，只支持 RSA 和 Ed25519, id: %s", k.Id)
+		}
+		return signingKey{id: k.Id, sign: ek, verify: ek.(ed25519.PrivateKey).Public()}, nil
+	default:
+		return signingKey{}, fmt.Errorf("secret 和 privateKey 必须配置一个, id: %s", k.Id)
+	}
+}
+
+// Sign 用当前的密钥签名，并且设置 kid 头部
+// 签名算法要和密钥的类型匹配，不然会返回 jwt.ErrInvalidKeyType
+func (r *KeyRing) Sign(method jwt.SigningMethod, claims jwt.Claims) (string, error) {
+	r.lock.RLock()
+	k := r.current
+	r.lock.RUnlock()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = k.id
+	return token.SignedString(k.sign)
+}
+
+// Keyfunc 根据 token 的 kid 头部找到对应的密钥，给 jwt.Parse 用
+// 没有 kid 的是引入 kid 之前签发的 token，配置了 legacy 密钥的时候用它校验
+// 会检查 token 的签名算法和密钥类型是否匹配，防止算法混淆攻击
+func (r *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	r.lock.RLock()
+	if kid == "" {
+		kid = r.legacy
+	}
+	k, ok := r.keys[kid]
+	r.lock.RUnlock()
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	var match bool
+	switch k.verify.(type) {
+	case []byte:
+		_, match = token.Method.(*jwt.SigningMethodHMAC)
+	case *rsa.PublicKey:
+		_, match = token.Method.(*jwt.SigningMethodRSA)
+	case ed25519.PublicKey:
+		_, match = token.Method.(*jwt.SigningMethodEd25519)
+	}
+	if !match {
+		return nil, fmt.Errorf("不支持的签名算法: %v", token.Header["alg"])
+	}
+	return k.verify, nil
+}
+
+// JWKS 所有非对称密钥的公钥，HMAC 的密钥不能公开，所以不会出现在里面
+func (r *KeyRing) JWKS() JWKSet {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	set := JWKSet{Keys: []JWK{}}
+	for _, id := range r.ids {
+		jwk, ok := toJWK(r.keys[id])
+		if ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// JWKSet 见 RFC 7517
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg,omitempty"`
+	// RSA 公钥
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 公钥
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+func toJWK(k signingKey) (JWK, bool) {
+	enc := base64.RawURLEncoding
+	switch pk := k.verify.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: k.id,
+			Use: "sig",
+			N:   enc.EncodeToString(pk.N.Bytes()),
+			E:   enc.EncodeToString(big.NewInt(int64(pk.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: k.id,
+			Use: "sig",
+			Alg: jwt.SigningMethodEdDSA.Alg(),
+			Crv: "Ed25519",
+			X:   enc.EncodeToString(pk),
+		}, true
+	default:
+		return JWK{}, false
+	}
+}
@@ -0,0 +1,249 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestKeyRing_Rotate(t *testing.T) {
+	k1 := Key{Id: "k1", Secret: "secret-1"}
+	k2 := Key{Id: "k2", Secret: "secret-2"}
+	r, err := NewKeyRing(k1)
+	require.NoError(t, err)
+
+	claims := func() UserClaims {
+		return UserClaims{
+			Uid: 123,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+		}
+	}
+	parse := func(tokenStr string) (UserClaims, error) {
+		var uc UserClaims
+		_, err := jwt.ParseWithClaims(tokenStr, &uc, r.Keyfunc)
+		return uc, err
+	}
+
+	oldToken, err := r.Sign(jwt.SigningMethodHS512, claims())
+	require.NoError(t, err)
+
+	// 轮换：新密钥放在最前面，旧密钥保留用于校验
+	err = r.Reset(k2, k1)
+	require.NoError(t, err)
+	newToken, err := r.Sign(jwt.SigningMethodHS512, claims())
+	require.NoError(t, err)
+
+	token, _, err := jwt.NewParser().ParseUnverified(newToken, &UserClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "k2", token.Header["kid"])
+
+	uc, err := parse(oldToken)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123), uc.Uid)
+	_, err = parse(newToken)
+	assert.NoError(t, err)
+
+	// 旧密钥下线之后，旧 token 就不能用了
+	err = r.Reset(k2)
+	require.NoError(t, err)
+	_, err = parse(oldToken)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	_, err = parse(newToken)
+	assert.NoError(t, err)
+}
+
+func TestKeyRing_Keyfunc(t *testing.T) {
+	r, err := NewKeyRing(Key{Id: "k1", Secret: "secret-1"})
+	require.NoError(t, err)
+
+	testCase := []struct {
+		name    string
+		token   func(t *testing.T) string
+		wantErr bool
+	}{
+		{
+			name: "没有 kid",
+			token: func(t *testing.T) string {
+				str, err := jwt.NewWithClaims(jwt.SigningMethodHS512, UserClaims{Uid: 123}).
+					SignedString([]byte("secret-1"))
+				require.NoError(t, err)
+				return str
+			},
+			wantErr: true,
+		},
+		{
+			name: "kid 对了但是密钥不对",
+			token: func(t *testing.T) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS512, UserClaims{Uid: 123})
+				token.Header["kid"] = "k1"
+				str, err := token.SignedString([]byte("other"))
+				require.NoError(t, err)
+				return str
+			},
+			wantErr: true,
+		},
+		{
+			name: "签名算法不对",
+			token: func(t *testing.T) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodNone, UserClaims{Uid: 123})
+				token.Header["kid"] = "k1"
+				str, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+				require.NoError(t, err)
+				return str
+			},
+			wantErr: true,
+		},
+		{
+			name: "校验通过",
+			token: func(t *testing.T) string {
+				str, err := r.Sign(jwt.SigningMethodHS256, UserClaims{Uid: 123})
+				require.NoError(t, err)
+				return str
+			},
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			var uc UserClaims
+			_, err := jwt.ParseWithClaims(tc.token(t), &uc, r.Keyfunc)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestKeyRing_Legacy(t *testing.T) {
+	// 引入 kid 之前签发的 token
+	legacyToken := func(t *testing.T, secret string) string {
+		str, err := jwt.NewWithClaims(jwt.SigningMethodHS512, UserClaims{Uid: 123}).
+			SignedString([]byte(secret))
+		require.NoError(t, err)
+		return str
+	}
+	r, err := NewKeyRing(Key{Id: "k2", Secret: "secret-2"}, Key{Id: "k1", Secret: "secret-1", Legacy: true})
+	require.NoError(t, err)
+
+	var uc UserClaims
+	_, err = jwt.ParseWithClaims(legacyToken(t, "secret-1"), &uc, r.Keyfunc)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123), uc.Uid)
+	// 没有 kid 的只能用 legacy 密钥校验
+	_, err = jwt.ParseWithClaims(legacyToken(t, "secret-2"), &UserClaims{}, r.Keyfunc)
+	assert.Error(t, err)
+	// 新签发的 token 带着 kid，不受影响
+	str, err := r.Sign(jwt.SigningMethodHS512, UserClaims{Uid: 123})
+	require.NoError(t, err)
+	_, err = jwt.ParseWithClaims(str, &UserClaims{}, r.Keyfunc)
+	assert.NoError(t, err)
+
+	// 过渡期结束，去掉 legacy 之后没有 kid 的 token 就不能用了
+	err = r.Reset(Key{Id: "k2", Secret: "secret-2"}, Key{Id: "k1", Secret: "secret-1"})
+	require.NoError(t, err)
+	_, err = jwt.ParseWithClaims(legacyToken(t, "secret-1"), &UserClaims{}, r.Keyfunc)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeyRing_Reset(t *testing.T) {
+	r, err := NewKeyRing(Key{Id: "k1", Secret: "secret-1"})
+	require.NoError(t, err)
+	assert.Equal(t, ErrEmptyKeyRing, r.Reset())
+	assert.Error(t, r.Reset(Key{Id: "k1"}))
+	assert.Error(t, r.Reset(Key{Id: "k1", Secret: "a"}, Key{Id: "k1", Secret: "b"}))
+	assert.Error(t, r.Reset(Key{Id: "k1", Secret: "a", Legacy: true}, Key{Id: "k2", Secret: "b", Legacy: true}))
+	// 出错的时候原来的密钥不受影响
+	str, err := r.Sign(jwt.SigningMethodHS512, UserClaims{Uid: 123})
+	require.NoError(t, err)
+	_, err = jwt.ParseWithClaims(str, &UserClaims{}, r.Keyfunc)
+	assert.NoError(t, err)
+}
+
+func TestKeyRing_Asymmetric(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	testCase := []struct {
+		name   string
+		key    Key
+		method jwt.SigningMethod
+		// 从 JWKS 里面还原出公钥，模拟其它服务校验 token
+		pubKey func(t *testing.T, jwk JWK) any
+	}{
+		{
+			name:   "RS256",
+			key:    Key{Id: "rsa-1", PrivateKey: toPEM(t, rsaKey)},
+			method: jwt.SigningMethodRS256,
+			pubKey: func(t *testing.T, jwk JWK) any {
+				assert.Equal(t, "RSA", jwk.Kty)
+				n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+				require.NoError(t, err)
+				e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+				require.NoError(t, err)
+				return &rsa.PublicKey{
+					N: new(big.Int).SetBytes(n),
+					E: int(new(big.Int).SetBytes(e).Int64()),
+				}
+			},
+		},
+		{
+			name:   "EdDSA",
+			key:    Key{Id: "ed-1", PrivateKey: toPEM(t, edKey)},
+			method: jwt.SigningMethodEdDSA,
+			pubKey: func(t *testing.T, jwk JWK) any {
+				assert.Equal(t, "OKP", jwk.Kty)
+				assert.Equal(t, "Ed25519", jwk.Crv)
+				x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+				require.NoError(t, err)
+				return ed25519.PublicKey(x)
+			},
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			// HMAC 的密钥不能出现在 JWKS 里面
+			r, err := NewKeyRing(tc.key, Key{Id: "hmac-1", Secret: "secret-1"})
+			require.NoError(t, err)
+			tokenStr, err := r.Sign(tc.method, UserClaims{Uid: 123})
+			require.NoError(t, err)
+
+			var uc UserClaims
+			_, err = jwt.ParseWithClaims(tokenStr, &uc, r.Keyfunc)
+			require.NoError(t, err)
+			assert.Equal(t, int64(123), uc.Uid)
+
+			set := r.JWKS()
+			require.Len(t, set.Keys, 1)
+			assert.Equal(t, tc.key.Id, set.Keys[0].Kid)
+			pub := tc.pubKey(t, set.Keys[0])
+			_, err = jwt.ParseWithClaims(tokenStr, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
+				return pub, nil
+			})
+			assert.NoError(t, err)
+
+			// 用 HS256 加上公钥伪造的 token 不能通过校验
+			forged := jwt.NewWithClaims(jwt.SigningMethodHS256, UserClaims{Uid: 1})
+			forged.Header["kid"] = tc.key.Id
+			forgedStr, err := forged.SignedString([]byte(tc.key.PrivateKey))
+			require.NoError(t, err)
+			_, err = jwt.ParseWithClaims(forgedStr, &UserClaims{}, r.Keyfunc)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func toPEM(t *testing.T, key any) string {
+	data, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: data}))
+}
@@ -0,0 +1,42 @@
+-- 刷新 token 家族，记录的是这个 ssid 当前唯一有效的刷新 token id
+local key = KEYS[1]
+-- 会话被吊销的标记
+local ssidKey = KEYS[2]
+-- 用户所有的会话，刷新的时候也要跟着续期，不然吊销所有会话的时候找不到这个会话
+local sessionsKey = KEYS[3]
+-- 这一次用来刷新的 token id，旧版本签发的 token 没有 id，是空字符串
+local old = ARGV[1]
+local new = ARGV[2]
+local ttl = tonumber(ARGV[3])
+-- 旧版本签发的 token 建立家族的时候，顺便把会话记下来，不然吊销所有会话的时候找不到它
+local ssid = ARGV[4]
+local session = ARGV[5]
+
+local cur = redis.call("get", key)
+if cur == false then
+    if old == "" then
+        if redis.call("exists", ssidKey) == 1 then
+            -- 已经退出登录或者被吊销了
+            return -1
+        end
+        -- 旧版本签发的 token，第一次刷新的时候建立家族
+        redis.call("set", key, new, "EX", ttl)
+        redis.call("hset", sessionsKey, ssid, session)
+        redis.call("expire", sessionsKey, ttl)
+        return 0
+    end
+    -- 家族已经不存在了，比如说过期了或者退出登录了
+    return -1
+end
+
+if cur ~= old then
+    -- 已经轮换过的 token 又被拿来用了，说明 token 可能泄露了
+    -- 整个会话都作废
+    redis.call("del", key)
+    redis.call("set", ssidKey, "", "EX", ttl)
+    return -2
+end
+
+redis.call("set", key, new, "EX", ttl)
+redis.call("expire", sessionsKey, ttl)
+return 0
@@ -1,33 +1,85 @@
 package jwt
 
 import (
+	_ "embed"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
-	"net/http"
 	"strings"
 	"time"
 )
 
+// refreshSigningMethod 刷新 token 的签名算法
+var refreshSigningMethod = jwt.SigningMethodHS512
+
+var (
+	//go:embed lua/rotate_refresh.lua
+	luaRotateRefresh string
+
+	ErrRefreshTokenReused  = errors.New("刷新 token 被重复使用")
+	ErrRefreshTokenRevoked = errors.New("刷新 token 已经失效")
+)
+
 type RedisJWTHandler struct {
 	client        redis.Cmdable
 	signingMethod jwt.SigningMethod
 	rtExpiration  time.Duration
+	// accessKeys 长 token 的密钥
+	accessKeys *KeyRing
+	// refreshKeys 刷新 token 的密钥
+	refreshKeys *KeyRing
 }
 
-func NewRedisJWTHandler(client redis.Cmdable) Handler {
+// NewRedisJWTHandler signingMethod 是长 token 的签名算法，
+// 用 RS256 或者 EdDSA 的时候，其它服务可以通过 JWKS 拿到公钥自己校验 token。
+// 刷新 token 只有我们自己校验，所以固定用 HMAC。
+func NewRedisJWTHandler(client redis.Cmdable, signingMethod jwt.SigningMethod,
+	accessKeys *KeyRing, refreshKeys *KeyRing) Handler {
 	return &RedisJWTHandler{
 		client:        client,
-		signingMethod: jwt.SigningMethodHS512,
+		signingMethod: signingMethod,
 		rtExpiration:  time.Hour * 24 * 7,
+		accessKeys:    accessKeys,
+		refreshKeys:   refreshKeys,
+	}
+}
+
+func (h *RedisJWTHandler) VerifyAccessToken(tokenStr string) (UserClaims, error) {
+	var uc UserClaims
+	token, err := jwt.ParseWithClaims(tokenStr, &uc, h.accessKeys.Keyfunc,
+		jwt.WithValidMethods([]string{h.signingMethod.Alg()}))
+	if err != nil {
+		return UserClaims{}, err
+	}
+	if token == nil || !token.Valid {
+		return UserClaims{}, errors.New("token 无效")
+	}
+	return uc, nil
+}
+
+func (h *RedisJWTHandler) VerifyRefreshToken(tokenStr string) (RefreshClaims, error) {
+	var rc RefreshClaims
+	token, err := jwt.ParseWithClaims(tokenStr, &rc, h.refreshKeys.Keyfunc,
+		jwt.WithValidMethods([]string{refreshSigningMethod.Alg()}))
+	if err != nil {
+		return RefreshClaims{}, err
+	}
+	// 挑战 token 也是用这套密钥签名的
+	if token == nil || !token.Valid || len(rc.Audience) > 0 {
+		return RefreshClaims{}, errors.New("token 无效")
 	}
+	return rc, nil
+}
+
+func (h *RedisJWTHandler) JWKS() JWKSet {
+	return h.accessKeys.JWKS()
 }
 
 func (h *RedisJWTHandler) CheckSession(ctx *gin.Context, ssid string) error {
-	logout, err := h.client.Exists(ctx, fmt.Sprintf("users:ssid:%s", ssid)).Result()
+	logout, err := h.client.Exists(ctx, h.ssidKey(ssid)).Result()
 	if err != nil {
 		return err
 	}
@@ -54,45 +106,101 @@ func (h *RedisJWTHandler) ExtractToken(ctx *gin.Context) string {
 
 var _ Handler = &RedisJWTHandler{}
 
-func (h *RedisJWTHandler) SetLoginToken(ctx *gin.Context, uid int64) error {
+func (h *RedisJWTHandler) SetLoginToken(ctx *gin.Context, uid int64, roles []string, method string) error {
 	ssid := uuid.New().String()
-	err := h.SetJWTToken(ctx, uid, ssid)
+	err := h.SetJWTToken(ctx, uid, ssid, roles)
 	if err != nil {
 		return err
 	}
-	err = h.setRefreshToken(ctx, uid, ssid)
-	return err
+	// 登录的时候建立刷新 token 家族
+	jti := uuid.New().String()
+	err = h.client.Set(ctx, h.refreshKey(ssid), jti, h.rtExpiration).Err()
+	if err != nil {
+		return err
+	}
+	err = h.addSession(ctx, uid, ssid, method)
+	if err != nil {
+		return err
+	}
+	return h.setRefreshToken(ctx, uid, ssid, jti)
+}
+
+// RotateRefreshToken 每次刷新都会签发新的刷新 token，旧的立刻失效
+// 已经轮换过的刷新 token 再被使用，就认为 token 泄露了，整个会话都会被吊销
+func (h *RedisJWTHandler) RotateRefreshToken(ctx *gin.Context, rc RefreshClaims, roles []string) error {
+	jti := uuid.New().String()
+	// 旧版本签发的 token 没有 id，登录的时候也没有记录会话，这一次补上
+	var session []byte
+	if rc.ID == "" {
+		var err error
+		session, err = h.sessionData(ctx, rc.Ssid, LoginMethodUnknown)
+		if err != nil {
+			return err
+		}
+	}
+	res, err := h.client.Eval(ctx, luaRotateRefresh,
+		[]string{h.refreshKey(rc.Ssid), h.ssidKey(rc.Ssid), h.sessionsKey(rc.Uid)},
+		rc.ID, jti, int64(h.rtExpiration/time.Second), rc.Ssid, string(session)).Int()
+	if err != nil {
+		return err
+	}
+	switch res {
+	case 0:
+	case -1:
+		return ErrRefreshTokenRevoked
+	case -2:
+		return ErrRefreshTokenReused
+	default:
+		return errors.New("系统错误")
+	}
+	err = h.SetJWTToken(ctx, rc.Uid, rc.Ssid, roles)
+	if err != nil {
+		return err
+	}
+	return h.setRefreshToken(ctx, rc.Uid, rc.Ssid, jti)
+}
+
+func (h *RedisJWTHandler) RenewJWTToken(ctx *gin.Context, uc UserClaims, roles []string) error {
+	// 刷新 token 家族只在登录和刷新的时候续期，所以一直续约也撑不过刷新 token 的有效期
+	cnt, err := h.client.Exists(ctx, h.refreshKey(uc.Ssid)).Result()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return ErrRefreshTokenRevoked
+	}
+	return h.SetJWTToken(ctx, uc.Uid, uc.Ssid, roles)
 }
 
-func (h *RedisJWTHandler) SetJWTToken(ctx *gin.Context, uid int64, ssid string) error {
+func (h *RedisJWTHandler) SetJWTToken(ctx *gin.Context, uid int64, ssid string, roles []string) error {
 	uc := UserClaims{
 		Uid: uid,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute * 30)),
 		},
-		Ssid: ssid,
+		Ssid:  ssid,
+		Roles: roles,
 	}
-	token := jwt.NewWithClaims(h.signingMethod, uc)
-	tokenStr, tokenErr := token.SignedString(JWTKey)
-	if tokenErr != nil {
-		ctx.String(http.StatusOK, "系统错误")
+	tokenStr, err := h.accessKeys.Sign(h.signingMethod, uc)
+	if err != nil {
+		return err
 	}
 	ctx.Header("x-jwt-token", tokenStr)
 	return nil
 }
 
-func (h *RedisJWTHandler) setRefreshToken(ctx *gin.Context, uid int64, ssid string) error {
+func (h *RedisJWTHandler) setRefreshToken(ctx *gin.Context, uid int64, ssid string, jti string) error {
 	uc := RefreshClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(h.rtExpiration)),
 		},
 		Uid:  uid,
 		Ssid: ssid,
 	}
-	token := jwt.NewWithClaims(h.signingMethod, uc)
-	tokenStr, tokenErr := token.SignedString(RefreshKey)
-	if tokenErr != nil {
-		return tokenErr
+	tokenStr, err := h.refreshKeys.Sign(refreshSigningMethod, uc)
+	if err != nil {
+		return err
 	}
 	ctx.Header("x-refresh-token", tokenStr)
 	return nil
@@ -103,11 +211,22 @@ func (h *RedisJWTHandler) ClearToken(ctx *gin.Context) error {
 	ctx.Header("x-jwt-token", "")
 	ctx.Header("x-refresh-token", "")
 	uc := ctx.MustGet("user").(UserClaims)
-	return h.client.Set(ctx, fmt.Sprintf("users:ssid:%s", uc.Ssid), "", h.rtExpiration).Err()
+	pipe := h.client.TxPipeline()
+	h.revoke(ctx, pipe, uc.Ssid)
+	pipe.HDel(ctx, h.sessionsKey(uc.Uid), uc.Ssid)
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
-var JWTKey = []byte("cgWrzQrzH2tfJngYC59iuqh3Dix246FX")
-var RefreshKey = []byte("cgWrzQrzH2tfJngYC59iuqh3Dix246FR")
+// ssidKey 存在就说明这个会话已经退出登录了
+func (h *RedisJWTHandler) ssidKey(ssid string) string {
+	return fmt.Sprintf("users:ssid:%s", ssid)
+}
+
+// refreshKey 刷新 token 家族，值是这个会话当前有效的刷新 token id
+func (h *RedisJWTHandler) refreshKey(ssid string) string {
+	return fmt.Sprintf("users:refresh:%s", ssid)
+}
 
 type RefreshClaims struct {
 	jwt.RegisteredClaims
@@ -119,4 +238,6 @@ type UserClaims struct {
 	jwt.RegisteredClaims
 	Uid  int64
 	Ssid string
+	// Roles 用户的角色，权限校验用，见 domain.HasPermission
+	Roles []string `json:",omitempty"`
 }
@@ -0,0 +1,231 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"webook/internal/repository/cache/redismocks"
+)
+
+func TestRedisJWTHandler_RotateRefreshToken(t *testing.T) {
+	rc := RefreshClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID: "old-jti",
+		},
+		Uid:  123,
+		Ssid: "ssid-1",
+	}
+	evalRes := func(val int64, err error) *redis.Cmd {
+		cmd := redis.NewCmd(context.Background())
+		cmd.SetVal(val)
+		cmd.SetErr(err)
+		return cmd
+	}
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) redis.Cmdable
+
+		wantErr error
+		// 是否签发了新的 token
+		wantTokens bool
+	}{
+		{
+			name: "轮换成功",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Eval(gomock.Any(), luaRotateRefresh,
+					[]string{"users:refresh:ssid-1", "users:ssid:ssid-1", "users:sessions:123"},
+					"old-jti", gomock.Any(), int64(7*24*3600), "ssid-1", "").
+					Return(evalRes(0, nil))
+				return cmd
+			},
+			wantTokens: true,
+		},
+		{
+			name: "刷新 token 被重复使用",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Eval(gomock.Any(), luaRotateRefresh, gomock.Any(),
+					gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(evalRes(-2, nil))
+				return cmd
+			},
+			wantErr: ErrRefreshTokenReused,
+		},
+		{
+			name: "会话已经失效",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Eval(gomock.Any(), luaRotateRefresh, gomock.Any(),
+					gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(evalRes(-1, nil))
+				return cmd
+			},
+			wantErr: ErrRefreshTokenRevoked,
+		},
+		{
+			name: "redis 错误",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Eval(gomock.Any(), luaRotateRefresh, gomock.Any(),
+					gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(evalRes(0, errors.New("redis 错误")))
+				return cmd
+			},
+			wantErr: errors.New("redis 错误"),
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			accessKeys, err := NewKeyRing(Key{Id: "a1", Secret: "access"})
+			require.NoError(t, err)
+			refreshKeys, err := NewKeyRing(Key{Id: "r1", Secret: "refresh"})
+			require.NoError(t, err)
+			hdl := NewRedisJWTHandler(tc.mock(ctrl), jwt.SigningMethodHS512, accessKeys, refreshKeys)
+
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			err = hdl.RotateRefreshToken(ctx, rc, []string{"admin"})
+			assert.Equal(t, tc.wantErr, err)
+			if !tc.wantTokens {
+				assert.Empty(t, recorder.Header().Get("x-refresh-token"))
+				return
+			}
+			newRc, err := hdl.VerifyRefreshToken(recorder.Header().Get("x-refresh-token"))
+			require.NoError(t, err)
+			assert.Equal(t, rc.Uid, newRc.Uid)
+			assert.Equal(t, rc.Ssid, newRc.Ssid)
+			assert.NotEmpty(t, newRc.ID)
+			assert.NotEqual(t, rc.ID, newRc.ID)
+			uc, err := hdl.VerifyAccessToken(recorder.Header().Get("x-jwt-token"))
+			require.NoError(t, err)
+			assert.Equal(t, rc.Ssid, uc.Ssid)
+			assert.Equal(t, []string{"admin"}, uc.Roles)
+		})
+	}
+}
+
+func TestRedisJWTHandler_RotateLegacyRefreshToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismocks.NewMockCmdable(ctrl)
+	// 旧版本签发的刷新 token 没有 id，建立家族的时候要把会话记下来
+	cmd.EXPECT().Eval(gomock.Any(), luaRotateRefresh,
+		[]string{"users:refresh:ssid-1", "users:ssid:ssid-1", "users:sessions:123"},
+		"", gomock.Any(), int64(7*24*3600), "ssid-1", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, script string, keys []string, args ...any) *redis.Cmd {
+			var s Session
+			err := json.Unmarshal([]byte(args[4].(string)), &s)
+			require.NoError(t, err)
+			assert.Equal(t, "ssid-1", s.Ssid)
+			assert.Equal(t, LoginMethodUnknown, s.LoginMethod)
+			cmd := redis.NewCmd(ctx)
+			cmd.SetVal(int64(0))
+			return cmd
+		})
+	accessKeys, err := NewKeyRing(Key{Id: "a1", Secret: "access"})
+	require.NoError(t, err)
+	refreshKeys, err := NewKeyRing(Key{Id: "r1", Secret: "refresh"})
+	require.NoError(t, err)
+	hdl := NewRedisJWTHandler(cmd, jwt.SigningMethodHS512, accessKeys, refreshKeys)
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/users/refresh_token", nil)
+	err = hdl.RotateRefreshToken(ctx, RefreshClaims{Uid: 123, Ssid: "ssid-1"}, nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, recorder.Header().Get("x-refresh-token"))
+}
+
+func TestRedisJWTHandler_RenewJWTToken(t *testing.T) {
+	uc := UserClaims{
+		Uid:   123,
+		Ssid:  "ssid-1",
+		Roles: []string{"admin"},
+	}
+	existsRes := func(val int64, err error) *redis.IntCmd {
+		cmd := redis.NewIntCmd(context.Background())
+		cmd.SetVal(val)
+		cmd.SetErr(err)
+		return cmd
+	}
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) redis.Cmdable
+		roles []string
+
+		wantErr   error
+		wantRoles []string
+	}{
+		{
+			name: "续约成功，用新的角色",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Exists(gomock.Any(), "users:refresh:ssid-1").
+					Return(existsRes(1, nil))
+				return cmd
+			},
+			roles:     []string{},
+			wantRoles: nil,
+		},
+		{
+			name: "刷新 token 已经过期，不能再续约",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Exists(gomock.Any(), "users:refresh:ssid-1").
+					Return(existsRes(0, nil))
+				return cmd
+			},
+			roles:   []string{"admin"},
+			wantErr: ErrRefreshTokenRevoked,
+		},
+		{
+			name: "redis 错误",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Exists(gomock.Any(), "users:refresh:ssid-1").
+					Return(existsRes(0, errors.New("redis 错误")))
+				return cmd
+			},
+			roles:   []string{"admin"},
+			wantErr: errors.New("redis 错误"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			accessKeys, err := NewKeyRing(Key{Id: "a1", Secret: "access"})
+			require.NoError(t, err)
+			refreshKeys, err := NewKeyRing(Key{Id: "r1", Secret: "refresh"})
+			require.NoError(t, err)
+			hdl := NewRedisJWTHandler(tc.mock(ctrl), jwt.SigningMethodHS512, accessKeys, refreshKeys)
+
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			err = hdl.RenewJWTToken(ctx, uc, tc.roles)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				assert.Empty(t, recorder.Header().Get("x-jwt-token"))
+				return
+			}
+			newUc, err := hdl.VerifyAccessToken(recorder.Header().Get("x-jwt-token"))
+			require.NoError(t, err)
+			assert.Equal(t, uc.Ssid, newUc.Ssid)
+			assert.Equal(t, tc.wantRoles, newUc.Roles)
+		})
+	}
+}
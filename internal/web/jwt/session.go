@@ -0,0 +1,143 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("会话不存在")
+
+// 登录方式
+const (
+	LoginMethodPassword = "password"
+	LoginMethodSMS      = "sms"
+	LoginMethodWechat   = "wechat"
+	// LoginMethodUnknown 引入会话列表之前登录的，不知道是怎么登录的
+	LoginMethodUnknown = "unknown"
+)
+
+// Session 一次登录就是一个会话，也就是一台设备
+type Session struct {
+	Ssid      string `json:"ssid"`
+	UserAgent string `json:"userAgent"`
+	IP        string `json:"ip"`
+	// LoginTime 毫秒数
+	LoginTime   int64  `json:"loginTime"`
+	LoginMethod string `json:"loginMethod"`
+}
+
+// sessionsKey 用户所有的会话，hash 结构，field 是 ssid，value 是 Session 的 JSON
+func (h *RedisJWTHandler) sessionsKey(uid int64) string {
+	return fmt.Sprintf("users:sessions:%d", uid)
+}
+
+func (h *RedisJWTHandler) addSession(ctx *gin.Context, uid int64, ssid string, method string) error {
+	data, err := h.sessionData(ctx, ssid, method)
+	if err != nil {
+		return err
+	}
+	key := h.sessionsKey(uid)
+	pipe := h.client.TxPipeline()
+	pipe.HSet(ctx, key, ssid, data)
+	// 登录和刷新的时候都会续期，最后一次登录或者刷新之后，最多过 rtExpiration 所有会话都会失效
+	pipe.Expire(ctx, key, h.rtExpiration)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (h *RedisJWTHandler) sessionData(ctx *gin.Context, ssid string, method string) ([]byte, error) {
+	return json.Marshal(Session{
+		Ssid:        ssid,
+		UserAgent:   ctx.GetHeader("User-Agent"),
+		IP:          ctx.ClientIP(),
+		LoginTime:   time.Now().UnixMilli(),
+		LoginMethod: method,
+	})
+}
+
+// ListSessions 列出用户还有效的会话
+// 刷新 token 家族不存在了，说明会话已经过期、退出或者被吊销了，顺手清理掉
+func (h *RedisJWTHandler) ListSessions(ctx *gin.Context, uid int64) ([]Session, error) {
+	key := h.sessionsKey(uid)
+	vals, err := h.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, 0, len(vals))
+	pipe := h.client.Pipeline()
+	cmds := make([]*redis.IntCmd, 0, len(vals))
+	for _, val := range vals {
+		var s Session
+		err = json.Unmarshal([]byte(val), &s)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+		cmds = append(cmds, pipe.Exists(ctx, h.refreshKey(s.Ssid)))
+	}
+	if len(cmds) == 0 {
+		return sessions, nil
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Session, 0, len(sessions))
+	var stale []string
+	for i, s := range sessions {
+		if cmds[i].Val() > 0 {
+			res = append(res, s)
+			continue
+		}
+		stale = append(stale, s.Ssid)
+	}
+	if len(stale) > 0 {
+		// 清理失败也不影响结果，下次还会再清理
+		_ = h.client.HDel(ctx, key, stale...).Err()
+	}
+	return res, nil
+}
+
+// RevokeSession 让用户的某一个会话退出登录
+func (h *RedisJWTHandler) RevokeSession(ctx *gin.Context, uid int64, ssid string) error {
+	key := h.sessionsKey(uid)
+	// 只能踢掉自己的会话
+	ok, err := h.client.HExists(ctx, key, ssid).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSessionNotFound
+	}
+	pipe := h.client.TxPipeline()
+	h.revoke(ctx, pipe, ssid)
+	pipe.HDel(ctx, key, ssid)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// RevokeAllSessions 退出所有设备，比如说修改密码之后
+func (h *RedisJWTHandler) RevokeAllSessions(ctx *gin.Context, uid int64) error {
+	key := h.sessionsKey(uid)
+	ssids, err := h.client.HKeys(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	pipe := h.client.TxPipeline()
+	for _, ssid := range ssids {
+		h.revoke(ctx, pipe, ssid)
+	}
+	pipe.Del(ctx, key)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// revoke 长 token 靠黑名单拦住，刷新 token 靠删除家族拦住
+func (h *RedisJWTHandler) revoke(ctx *gin.Context, pipe redis.Pipeliner, ssid string) {
+	pipe.Set(ctx, h.ssidKey(ssid), "", h.rtExpiration)
+	pipe.Del(ctx, h.refreshKey(ssid))
+}
@@ -4,8 +4,33 @@ import "github.com/gin-gonic/gin"
 
 type Handler interface {
 	ExtractToken(ctx *gin.Context) string
-	SetLoginToken(ctx *gin.Context, uid int64) error
-	SetJWTToken(ctx *gin.Context, uid int64, ssid string) error
+	// SetLoginToken method 是登录方式，会记录在会话里面
+	SetLoginToken(ctx *gin.Context, uid int64, roles []string, method string) error
+	SetJWTToken(ctx *gin.Context, uid int64, ssid string, roles []string) error
+	// RenewJWTToken 滑动续约，roles 由调用者重新查询
+	// 续约不能超过刷新 token 的有效期，刷新 token 家族不在了返回 ErrRefreshTokenRevoked
+	RenewJWTToken(ctx *gin.Context, uc UserClaims, roles []string) error
+	// RotateRefreshToken 用刷新 token 换一对新的长 token 和刷新 token
+	// roles 由调用者重新查询，这样角色变更之后刷新一次就能生效
+	RotateRefreshToken(ctx *gin.Context, rc RefreshClaims, roles []string) error
 	CheckSession(ctx *gin.Context, ssid string) error
 	ClearToken(ctx *gin.Context) error
+	// ListSessions 用户所有登录的设备
+	ListSessions(ctx *gin.Context, uid int64) ([]Session, error)
+	// RevokeSession 踢掉用户的某一个设备
+	RevokeSession(ctx *gin.Context, uid int64, ssid string) error
+	// RevokeAllSessions 退出所有设备
+	RevokeAllSessions(ctx *gin.Context, uid int64) error
+	// VerifyAccessToken 校验长 token 的签名和过期时间
+	VerifyAccessToken(tokenStr string) (UserClaims, error)
+	// VerifyRefreshToken 校验刷新 token 的签名和过期时间
+	VerifyRefreshToken(tokenStr string) (RefreshClaims, error)
+	// JWKS 校验长 token 用的公钥
+	JWKS() JWKSet
+	// NewChallengeToken 开启了两步验证的用户，密码校验通过之后先拿到一个短期的挑战 token
+	NewChallengeToken(uid int64) (string, error)
+	// VerifyChallengeToken 校验挑战 token，每个挑战 token 只能尝试有限的次数
+	VerifyChallengeToken(ctx *gin.Context, tokenStr string) (ChallengeClaims, error)
+	// FinishChallenge 两步验证通过之后挑战 token 就不能再用了
+	FinishChallenge(ctx *gin.Context, cc ChallengeClaims) error
 }
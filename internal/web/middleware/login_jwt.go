@@ -3,14 +3,40 @@ package middleware
 import (
 	"encoding/gob"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"net/http"
+	"path"
+	"strings"
 	"time"
+	"webook/internal/domain"
+	"webook/internal/service"
 	ijwt "webook/internal/web/jwt"
 )
 
+// AuthPolicy 路由的登录策略，由注册路由的 handler 自己声明
+// 路径的写法支持三种：
+// 1. 和注册路由时一样的路径，比如说 /articles/pub/:id
+// 2. path.Match 支持的通配符，比如说 /oauth2/*/callback
+// 3. 以 /** 结尾的前缀，比如说 /static/**
+type AuthPolicy struct {
+	// Ignore 不需要登录
+	Ignore []string
+	// Optional 登录了就设置 user，没登录也可以访问
+	Optional []string
+}
+
+// AuthPolicyDeclarer 声明了自己登录策略的 handler
+type AuthPolicyDeclarer interface {
+	AuthPolicy() AuthPolicy
+}
+
 type LoginJWTMiddlewareBuilder struct {
 	ijwt.Handler
+	ignore   []string
+	optional []string
+	// renewWindow 长 token 离过期不到这么长时间，就续约一个新的，0 表示不续约
+	renewWindow time.Duration
+	// users 续约之前重新查用户的状态和角色
+	users service.UserService
 }
 
 func NewLoginJWTMiddlewareBuilder(hdl ijwt.Handler) *LoginJWTMiddlewareBuilder {
@@ -19,49 +45,103 @@ func NewLoginJWTMiddlewareBuilder(hdl ijwt.Handler) *LoginJWTMiddlewareBuilder {
 	}
 }
 
+// IgnorePaths 这些路径不需要登录
+func (m *LoginJWTMiddlewareBuilder) IgnorePaths(patterns ...string) *LoginJWTMiddlewareBuilder {
+	m.ignore = append(m.ignore, patterns...)
+	return m
+}
+
+// OptionalPaths 这些路径登录不登录都可以访问
+func (m *LoginJWTMiddlewareBuilder) OptionalPaths(patterns ...string) *LoginJWTMiddlewareBuilder {
+	m.optional = append(m.optional, patterns...)
+	return m
+}
+
+// SlidingRenewal 开启滑动续约
+// 长 token 快要过期的时候，只要会话还有效，就通过 x-jwt-token 返回一个新的长 token
+// 和刷新 token 一样，续约的时候会重新查询用户的角色，被封禁、注销或者合并的用户不续约
+func (m *LoginJWTMiddlewareBuilder) SlidingRenewal(window time.Duration,
+	users service.UserService) *LoginJWTMiddlewareBuilder {
+	m.renewWindow = window
+	m.users = users
+	return m
+}
+
+// Declare 合并 handler 声明的登录策略
+func (m *LoginJWTMiddlewareBuilder) Declare(ds ...AuthPolicyDeclarer) *LoginJWTMiddlewareBuilder {
+	for _, d := range ds {
+		p := d.AuthPolicy()
+		m.IgnorePaths(p.Ignore...)
+		m.OptionalPaths(p.Optional...)
+	}
+	return m
+}
+
 func (m *LoginJWTMiddlewareBuilder) CheckLogin() gin.HandlerFunc {
 	gob.Register(time.Now())
 	return func(ctx *gin.Context) {
-		path := ctx.Request.URL.Path
-		if path == "/users/signup" ||
-			path == "/users/login" ||
-			path == "/users/login_sms/code/send" ||
-			path == "/users/login_sms" ||
-			path == "/users/refresh_token" ||
-			path == "/oauth2/wechat/authurl" ||
-			path == "/oauth2/wechat/callback" {
+		// FullPath 是注册路由时候的路径，比如说 /articles/pub/:id
+		fullPath := ctx.FullPath()
+		urlPath := ctx.Request.URL.Path
+		if matchAny(m.ignore, fullPath, urlPath) {
 			return
 		}
+		optional := matchAny(m.optional, fullPath, urlPath)
 
 		tokenStr := m.ExtractToken(ctx)
-		var uc ijwt.UserClaims
-		token, err := jwt.ParseWithClaims(tokenStr, &uc, func(token *jwt.Token) (interface{}, error) {
-			return ijwt.JWTKey, nil
-		})
-		if err != nil {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
+		if tokenStr == "" && optional {
 			return
 		}
-		if !token.Valid {
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
+		uc, err := m.VerifyAccessToken(tokenStr)
+		if err == nil {
+			err = m.CheckSession(ctx, uc.Ssid)
 		}
-
-		//expires := uc.ExpiresAt
-		//if expires.Sub(time.Now()) < 50*time.Second {
-		//	uc.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Minute * 30))
-		//	tokenStr, err = token.SignedString(web.JWTKey)
-		//	ctx.Header("x-jwt-token", tokenStr)
-		//	if err != nil {
-		//		fmt.Println(err)
-		//	}
-		//}
-		err = m.CheckSession(ctx, uc.Ssid)
 		if err != nil {
+			if optional {
+				// token 不对就当作没有登录
+				return
+			}
 			ctx.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
 
+		m.renew(ctx, uc)
 		ctx.Set("user", uc)
 	}
 }
+
+func (m *LoginJWTMiddlewareBuilder) renew(ctx *gin.Context, uc ijwt.UserClaims) {
+	if m.renewWindow <= 0 || uc.ExpiresAt == nil {
+		return
+	}
+	if time.Until(uc.ExpiresAt.Time) >= m.renewWindow {
+		return
+	}
+	// 续约失败不影响这一次请求，token 过期之后前端还可以用刷新 token
+	// 角色可能变了，不能沿用老 token 里面的
+	u, err := m.users.FindById(ctx, uc.Uid)
+	if err != nil || u.Status != domain.UserStatusActive {
+		return
+	}
+	_ = m.RenewJWTToken(ctx, uc, u.Roles)
+}
+
+func matchAny(patterns []string, fullPath, urlPath string) bool {
+	for _, p := range patterns {
+		if match(p, fullPath, urlPath) {
+			return true
+		}
+	}
+	return false
+}
+
+func match(pattern, fullPath, urlPath string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return urlPath == prefix || strings.HasPrefix(urlPath, prefix+"/")
+	}
+	if fullPath != "" && pattern == fullPath {
+		return true
+	}
+	ok, _ := path.Match(pattern, urlPath)
+	return ok
+}
@@ -0,0 +1,389 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/service"
+	svcmocks "webook/internal/service/mocks"
+	ijwt "webook/internal/web/jwt"
+	jwtmocks "webook/internal/web/jwt/mocks"
+)
+
+type testPolicy AuthPolicy
+
+func (p testPolicy) AuthPolicy() AuthPolicy {
+	return AuthPolicy(p)
+}
+
+func TestLoginJWTMiddlewareBuilder_CheckLogin(t *testing.T) {
+	uc := ijwt.UserClaims{Uid: 123, Ssid: "ssid-1"}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) ijwt.Handler
+		path string
+
+		wantCode int
+		// 接口里面拿到的 uid，0 就是没有登录
+		wantUid int64
+	}{
+		{
+			name: "不需要登录",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				return jwtmocks.NewMockHandler(ctrl)
+			},
+			path:     "/users/login",
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "通配符",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				return jwtmocks.NewMockHandler(ctrl)
+			},
+			path:     "/oauth2/wechat/callback",
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "前缀",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				return jwtmocks.NewMockHandler(ctrl)
+			},
+			path:     "/static/js/app.js",
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "需要登录，没有 token",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("")
+				hdl.EXPECT().VerifyAccessToken("").Return(ijwt.UserClaims{}, errors.New("token 无效"))
+				return hdl
+			},
+			path:     "/users/profile",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "需要登录，已经退出登录",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("token")
+				hdl.EXPECT().VerifyAccessToken("token").Return(uc, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), "ssid-1").Return(errors.New("token 无效"))
+				return hdl
+			},
+			path:     "/users/profile",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "需要登录，已经登录",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("token")
+				hdl.EXPECT().VerifyAccessToken("token").Return(uc, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), "ssid-1").Return(nil)
+				return hdl
+			},
+			path:     "/users/profile",
+			wantCode: http.StatusOK,
+			wantUid:  123,
+		},
+		{
+			name: "可选登录，没有 token",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("")
+				return hdl
+			},
+			path:     "/articles/pub/1",
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "可选登录，token 无效",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("token")
+				hdl.EXPECT().VerifyAccessToken("token").Return(ijwt.UserClaims{}, errors.New("token 无效"))
+				return hdl
+			},
+			path:     "/articles/pub/1",
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "可选登录，已经登录",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("token")
+				hdl.EXPECT().VerifyAccessToken("token").Return(uc, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), "ssid-1").Return(nil)
+				return hdl
+			},
+			path:     "/articles/pub/1",
+			wantCode: http.StatusOK,
+			wantUid:  123,
+		},
+		{
+			name: "和可选登录同一个分组，但是需要登录",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("")
+				hdl.EXPECT().VerifyAccessToken("").Return(ijwt.UserClaims{}, errors.New("token 无效"))
+				return hdl
+			},
+			path:     "/articles/pub/like",
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := gin.New()
+			server.Use(NewLoginJWTMiddlewareBuilder(tc.mock(ctrl)).
+				IgnorePaths("/users/login", "/oauth2/*/callback", "/static/**").
+				Declare(testPolicy{Optional: []string{"/articles/pub/:id"}}).
+				CheckLogin())
+			var uid int64
+			hdl := func(ctx *gin.Context) {
+				val, _ := ctx.Get("user")
+				uid = val.(ijwt.UserClaims).Uid
+			}
+			anonymous := func(ctx *gin.Context) {
+				val, _ := ctx.Get("user")
+				c, _ := val.(ijwt.UserClaims)
+				uid = c.Uid
+			}
+			server.GET("/users/login", anonymous)
+			server.GET("/oauth2/wechat/callback", anonymous)
+			server.GET("/static/js/app.js", anonymous)
+			server.GET("/users/profile", hdl)
+			server.GET("/articles/pub/:id", anonymous)
+			server.GET("/articles/pub/like", hdl)
+
+			req, err := http.NewRequest(http.MethodGet, tc.path, nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantUid, uid)
+		})
+	}
+}
+
+func TestLoginJWTMiddlewareBuilder_SlidingRenewal(t *testing.T) {
+	claims := func(expiresIn time.Duration) ijwt.UserClaims {
+		return ijwt.UserClaims{
+			Uid:  123,
+			Ssid: "ssid-1",
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			},
+		}
+	}
+	// 校验通过、会话有效
+	valid := func(hdl *jwtmocks.MockHandler, uc ijwt.UserClaims) {
+		hdl.EXPECT().ExtractToken(gomock.Any()).Return("token")
+		hdl.EXPECT().VerifyAccessToken("token").Return(uc, nil)
+		hdl.EXPECT().CheckSession(gomock.Any(), "ssid-1").Return(nil)
+	}
+	testCases := []struct {
+		name   string
+		window time.Duration
+		mock   func(ctrl *gomock.Controller) (ijwt.Handler, service.UserService)
+
+		wantCode int
+	}{
+		{
+			name:   "离过期还早，不续约",
+			window: time.Minute * 5,
+			mock: func(ctrl *gomock.Controller) (ijwt.Handler, service.UserService) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				users := svcmocks.NewMockUserService(ctrl)
+				valid(hdl, claims(time.Minute*20))
+				return hdl, users
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "刚好在窗口外面，不续约",
+			window: time.Minute * 5,
+			mock: func(ctrl *gomock.Controller) (ijwt.Handler, service.UserService) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				users := svcmocks.NewMockUserService(ctrl)
+				valid(hdl, claims(time.Minute*5+time.Second*10))
+				return hdl, users
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "进入窗口，续约",
+			window: time.Minute * 5,
+			mock: func(ctrl *gomock.Controller) (ijwt.Handler, service.UserService) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				users := svcmocks.NewMockUserService(ctrl)
+				valid(hdl, claims(time.Minute*5-time.Second*10))
+				users.EXPECT().FindById(gomock.Any(), int64(123)).Return(domain.User{Id: 123}, nil)
+				hdl.EXPECT().RenewJWTToken(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+				return hdl, users
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "马上就要过期，续约",
+			window: time.Minute * 5,
+			mock: func(ctrl *gomock.Controller) (ijwt.Handler, service.UserService) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				users := svcmocks.NewMockUserService(ctrl)
+				valid(hdl, claims(time.Second))
+				users.EXPECT().FindById(gomock.Any(), int64(123)).Return(domain.User{Id: 123}, nil)
+				hdl.EXPECT().RenewJWTToken(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+				return hdl, users
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "已经过期，不能续约",
+			window: time.Minute * 5,
+			mock: func(ctrl *gomock.Controller) (ijwt.Handler, service.UserService) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				users := svcmocks.NewMockUserService(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("token")
+				hdl.EXPECT().VerifyAccessToken("token").Return(ijwt.UserClaims{}, jwt.ErrTokenExpired)
+				return hdl, users
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "会话已经失效，不能续约",
+			window: time.Minute * 5,
+			mock: func(ctrl *gomock.Controller) (ijwt.Handler, service.UserService) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				users := svcmocks.NewMockUserService(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("token")
+				hdl.EXPECT().VerifyAccessToken("token").Return(claims(time.Second), nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), "ssid-1").Return(errors.New("token 无效"))
+				return hdl, users
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "没有过期时间，不续约",
+			window: time.Minute * 5,
+			mock: func(ctrl *gomock.Controller) (ijwt.Handler, service.UserService) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				users := svcmocks.NewMockUserService(ctrl)
+				valid(hdl, ijwt.UserClaims{Uid: 123, Ssid: "ssid-1"})
+				return hdl, users
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "没有开启续约",
+			mock: func(ctrl *gomock.Controller) (ijwt.Handler, service.UserService) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				users := svcmocks.NewMockUserService(ctrl)
+				valid(hdl, claims(time.Second))
+				return hdl, users
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "续约失败，请求照常处理",
+			window: time.Minute * 5,
+			mock: func(ctrl *gomock.Controller) (ijwt.Handler, service.UserService) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				users := svcmocks.NewMockUserService(ctrl)
+				valid(hdl, claims(time.Second))
+				users.EXPECT().FindById(gomock.Any(), int64(123)).Return(domain.User{Id: 123}, nil)
+				hdl.EXPECT().RenewJWTToken(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("签名失败"))
+				return hdl, users
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "续约的时候用重新查出来的角色",
+			window: time.Minute * 5,
+			mock: func(ctrl *gomock.Controller) (ijwt.Handler, service.UserService) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				users := svcmocks.NewMockUserService(ctrl)
+				uc := claims(time.Second)
+				uc.Roles = []string{domain.RoleAdmin}
+				valid(hdl, uc)
+				// 管理员被降级了
+				users.EXPECT().FindById(gomock.Any(), int64(123)).Return(domain.User{Id: 123}, nil)
+				hdl.EXPECT().RenewJWTToken(gomock.Any(), uc, []string(nil)).Return(nil)
+				return hdl, users
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "被封禁了，不续约",
+			window: time.Minute * 5,
+			mock: func(ctrl *gomock.Controller) (ijwt.Handler, service.UserService) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				users := svcmocks.NewMockUserService(ctrl)
+				valid(hdl, claims(time.Second))
+				users.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Status: domain.UserStatusBanned}, nil)
+				return hdl, users
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "被合并了，不续约",
+			window: time.Minute * 5,
+			mock: func(ctrl *gomock.Controller) (ijwt.Handler, service.UserService) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				users := svcmocks.NewMockUserService(ctrl)
+				valid(hdl, claims(time.Second))
+				users.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Status: domain.UserStatusMerged}, nil)
+				return hdl, users
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "查询用户失败，不续约",
+			window: time.Minute * 5,
+			mock: func(ctrl *gomock.Controller) (ijwt.Handler, service.UserService) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				users := svcmocks.NewMockUserService(ctrl)
+				valid(hdl, claims(time.Second))
+				users.EXPECT().FindById(gomock.Any(), int64(123)).Return(domain.User{}, errors.New("db 错误"))
+				return hdl, users
+			},
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := gin.New()
+			hdl, users := tc.mock(ctrl)
+			server.Use(NewLoginJWTMiddlewareBuilder(hdl).
+				SlidingRenewal(tc.window, users).
+				CheckLogin())
+			server.GET("/users/profile", func(ctx *gin.Context) {})
+
+			req, err := http.NewRequest(http.MethodGet, "/users/profile", nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+		})
+	}
+}
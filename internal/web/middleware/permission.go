@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"webook/internal/domain"
+	ijwt "webook/internal/web/jwt"
+)
+
+// RequirePermission 校验登录用户有没有权限，要放在登录校验的后面
+// 没有登录返回 401，没有权限返回 403
+func RequirePermission(p domain.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		val, ok := ctx.Get("user")
+		if !ok {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		uc, ok := val.(ijwt.UserClaims)
+		if !ok {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if !domain.HasPermission(uc.Roles, p) {
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+	}
+}
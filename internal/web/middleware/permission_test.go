@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"webook/internal/domain"
+	ijwt "webook/internal/web/jwt"
+)
+
+func TestRequirePermission(t *testing.T) {
+	testCases := []struct {
+		name string
+		// user 为 nil 就是没有登录
+		user any
+		p    domain.Permission
+
+		wantCode int
+	}{
+		{
+			name:     "没有登录",
+			p:        domain.PermissionUserList,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "没有角色",
+			user:     ijwt.UserClaims{Uid: 123},
+			p:        domain.PermissionUserList,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "运营不能封禁用户",
+			user:     ijwt.UserClaims{Uid: 123, Roles: []string{domain.RoleOperator}},
+			p:        domain.PermissionUserBan,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "运营可以下架文章",
+			user:     ijwt.UserClaims{Uid: 123, Roles: []string{domain.RoleOperator}},
+			p:        domain.PermissionArticleTakedown,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "多个角色，有一个有权限就可以",
+			user:     ijwt.UserClaims{Uid: 123, Roles: []string{"unknown", domain.RoleAdmin}},
+			p:        domain.PermissionUserBan,
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				if tc.user != nil {
+					ctx.Set("user", tc.user)
+				}
+			})
+			server.GET("/admin/test", RequirePermission(tc.p), func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			req, err := http.NewRequest(http.MethodGet, "/admin/test", nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+		})
+	}
+}
@@ -1,5 +1,26 @@
 package web
 
+// codeUserBanned 账号被封禁，和 4 区分开，前端可以引导用户去申诉
+const codeUserBanned = 6
+
+// codeTwoFactorRequired 密码对了，但是还要输入两步验证的验证码
+const codeTwoFactorRequired = 7
+
+// codeLoginLocked 登录失败次数太多，暂时锁定了，和密码错误区分开，前端可以展示倒计时
+const codeLoginLocked = 8
+
+// bannedResult 被封禁的用户登录时候的响应
+var bannedResult = Result{
+	Code: codeUserBanned,
+	Msg:  "账号已被封禁",
+}
+
+// frozenResult 已经注销或者被合并的账号登录时候的响应
+var frozenResult = Result{
+	Code: 4,
+	Msg:  "账号已经注销或者被合并",
+}
+
 type Result struct {
 	Code int    `json:"code"`
 	Msg  string `json:"msg"`
@@ -5,13 +5,13 @@ import (
 	regexp "github.com/dlclark/regexp2"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/redis/go-redis/v9"
 	"net/http"
 	"time"
 	"webook/internal/domain"
 	"webook/internal/service"
 	ijwt "webook/internal/web/jwt"
+	"webook/internal/web/middleware"
 )
 
 const (
@@ -19,6 +19,11 @@ const (
 	// 和上面比起来，用 ` 看起来就比较清爽
 	passwordRegexPattern = `^(?=.*[A-Za-z])(?=.*\d)(?=.*[$@$!%*#?&])[A-Za-z\d$@$!%*#?&]{8,}$`
 	bizLogin             = "login"
+	bizResetPassword     = "reset_password"
+	bizVerifyEmail       = "verify_email"
+	bizBindPhone         = "bind_phone"
+	bizBindEmail         = "bind_email"
+	bizDeleteAccount     = "delete_account"
 )
 
 type UserHandler struct {
@@ -26,16 +31,26 @@ type UserHandler struct {
 	passwordRegexExp *regexp.Regexp
 	svc              service.UserService
 	codeSvc          service.CodeService
+	emailCodeSvc     service.EmailCodeService
+	accountSvc       service.UserAccountService
+	totpSvc          service.TOTPService
+	attemptSvc       service.LoginAttemptService
 	ijwt.Handler
 	client redis.Cmdable
 }
 
-func NewUserHandler(svc service.UserService, hdl ijwt.Handler, codeSvc service.CodeService) *UserHandler {
+func NewUserHandler(svc service.UserService, hdl ijwt.Handler, codeSvc service.CodeService,
+	emailCodeSvc service.EmailCodeService, accountSvc service.UserAccountService,
+	totpSvc service.TOTPService, attemptSvc service.LoginAttemptService) *UserHandler {
 	return &UserHandler{
 		emailRegexExp:    regexp.MustCompile(emailRegexPattern, regexp.None),
 		passwordRegexExp: regexp.MustCompile(passwordRegexPattern, regexp.None),
 		svc:              svc,
 		codeSvc:          codeSvc,
+		emailCodeSvc:     emailCodeSvc,
+		accountSvc:       accountSvc,
+		totpSvc:          totpSvc,
+		attemptSvc:       attemptSvc,
 		Handler:          hdl,
 	}
 }
@@ -45,16 +60,64 @@ func (h *UserHandler) RegisterRoutes(server *gin.Engine) {
 	ug.POST("/signup", h.SignUp)
 	//ug.POST("/login", h.Login)
 	ug.POST("/login", h.LoginJWT)
+	ug.POST("/login/2fa", h.LoginTOTP)
 	ug.POST("/logout", h.LogoutJWT)
 	ug.POST("/edit", h.Edit)
 	ug.GET("/profile", h.Profile)
 
 	ug.GET("/refresh_token", h.RefreshToken)
 
+	// 多设备登录管理
+	ug.GET("/sessions", h.Sessions)
+	ug.POST("/sessions/revoke", h.RevokeSession)
+	ug.POST("/sessions/revoke_all", h.RevokeAllSessions)
+
 	//手机验证码登录相关功能
 	ug.POST("/login_sms/code/send", h.SendLoginSMSCode)
 	ug.POST("/login_sms", h.LoginSMS)
 
+	// 修改密码和忘记密码
+	ug.POST("/password/change", h.ChangePassword)
+	ug.POST("/password/reset/code/send", h.SendResetPasswordCode)
+	ug.POST("/password/reset", h.ResetPassword)
+
+	// 验证邮箱
+	ug.POST("/email/verify/code/send", h.SendVerifyEmailCode)
+	ug.POST("/email/verify", h.VerifyEmail)
+
+	// 绑定和解绑登录方式，微信的绑定在 OAuth2WechatHandler 里面
+	ug.POST("/bind/phone/code/send", h.SendBindPhoneCode)
+	ug.POST("/bind/phone", h.BindPhone)
+	ug.POST("/bind/email/code/send", h.SendBindEmailCode)
+	ug.POST("/bind/email", h.BindEmail)
+	ug.POST("/unbind", h.Unbind)
+
+	// TOTP 两步验证
+	ug.POST("/2fa/totp/enroll", h.EnrollTOTP)
+	ug.POST("/2fa/totp/enable", h.EnableTOTP)
+	ug.POST("/2fa/totp/disable", h.DisableTOTP)
+
+	// 注销账号和导出个人数据
+	ug.POST("/delete/code/send", h.SendDeleteAccountCode)
+	ug.POST("/delete", h.DeleteAccount)
+	ug.GET("/export", h.ExportData)
+}
+
+func (h *UserHandler) AuthPolicy() middleware.AuthPolicy {
+	return middleware.AuthPolicy{
+		Ignore: []string{
+			"/users/signup",
+			"/users/login",
+			// 用挑战 token 证明密码已经校验过了
+			"/users/login/2fa",
+			"/users/login_sms/code/send",
+			"/users/login_sms",
+			// 刷新 token 自己校验
+			"/users/refresh_token",
+			"/users/password/reset/code/send",
+			"/users/password/reset",
+		},
+	}
 }
 
 func (h *UserHandler) LoginSMS(ctx *gin.Context) {
@@ -85,6 +148,14 @@ func (h *UserHandler) LoginSMS(ctx *gin.Context) {
 		return
 	}
 	u, err := h.svc.FindOrCreate(ctx, req.Phone)
+	if err == service.ErrUserBanned {
+		ctx.JSON(http.StatusOK, bannedResult)
+		return
+	}
+	if err == service.ErrUserStatusFrozen {
+		ctx.JSON(http.StatusOK, frozenResult)
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Code: 5,
@@ -92,7 +163,7 @@ func (h *UserHandler) LoginSMS(ctx *gin.Context) {
 		})
 		return
 	}
-	err = h.SetLoginToken(ctx, u.Id)
+	err = h.SetLoginToken(ctx, u.Id, u.Roles, ijwt.LoginMethodSMS)
 	if err != nil {
 		ctx.String(http.StatusOK, "系统错误")
 		return
@@ -103,6 +174,14 @@ func (h *UserHandler) LoginSMS(ctx *gin.Context) {
 }
 
 func (h *UserHandler) SendLoginSMSCode(ctx *gin.Context) {
+	h.sendSMSCode(ctx, bizLogin)
+}
+
+func (h *UserHandler) SendResetPasswordCode(ctx *gin.Context) {
+	h.sendSMSCode(ctx, bizResetPassword)
+}
+
+func (h *UserHandler) sendSMSCode(ctx *gin.Context, biz string) {
 	type SMSReq struct {
 		Phone string `json:"phone"`
 	}
@@ -120,7 +199,7 @@ func (h *UserHandler) SendLoginSMSCode(ctx *gin.Context) {
 		return
 	}
 
-	err := h.codeSvc.Send(ctx, bizLogin, req.Phone)
+	err := h.codeSvc.Send(ctx, biz, req.Phone)
 	switch err {
 	case nil:
 		ctx.JSON(http.StatusOK, Result{
@@ -186,6 +265,8 @@ func (h *UserHandler) SignUp(ctx *gin.Context) {
 
 	switch err {
 	case nil:
+		// 注册完顺手发一封验证邮件，发送失败了用户登录之后还可以重新发
+		_ = h.emailCodeSvc.Send(ctx, bizVerifyEmail, req.Email)
 		ctx.String(http.StatusOK, "注册成功")
 	case service.ErrDuplicateEmail:
 		ctx.String(http.StatusOK, "邮箱冲突，请换一个")
@@ -203,17 +284,38 @@ func (h *UserHandler) LoginJWT(ctx *gin.Context) {
 	if err := ctx.Bind(&req); err != nil {
 		return
 	}
+	if h.loginLocked(ctx, req.Email) {
+		return
+	}
 	u, err := h.svc.Login(ctx, req.Email, req.Password)
 	switch err {
 	case nil:
-		err = h.SetLoginToken(ctx, u.Id)
+		enabled, er := h.totpSvc.Enabled(ctx, u.Id)
+		if er != nil {
+			ctx.String(http.StatusOK, "系统错误")
+			return
+		}
+		if enabled {
+			// 失败次数要等两步验证也通过了才清空
+			h.twoFactorChallenge(ctx, u.Id)
+			return
+		}
+		h.loginSucceeded(ctx, req.Email)
+		err = h.SetLoginToken(ctx, u.Id, u.Roles, ijwt.LoginMethodPassword)
 		if err != nil {
 			ctx.String(http.StatusOK, "系统错误")
 			return
 		}
 		ctx.String(http.StatusOK, "登录成功")
 	case service.ErrInvalidUserOrPassword:
+		if h.loginFailed(ctx, req.Email) {
+			return
+		}
 		ctx.String(http.StatusOK, "用户名或密码错误")
+	case service.ErrUserBanned:
+		ctx.JSON(http.StatusOK, bannedResult)
+	case service.ErrUserStatusFrozen:
+		ctx.JSON(http.StatusOK, frozenResult)
 	default:
 		ctx.String(http.StatusOK, "系统错误")
 
@@ -245,6 +347,10 @@ func (h *UserHandler) Login(ctx *gin.Context) {
 		ctx.String(http.StatusOK, "登录成功")
 	case service.ErrInvalidUserOrPassword:
 		ctx.String(http.StatusOK, "用户名或密码错误")
+	case service.ErrUserBanned:
+		ctx.JSON(http.StatusOK, bannedResult)
+	case service.ErrUserStatusFrozen:
+		ctx.JSON(http.StatusOK, frozenResult)
 	default:
 		ctx.String(http.StatusOK, "系统错误")
 
@@ -301,11 +407,12 @@ func (h *UserHandler) Edit(ctx *gin.Context) {
 
 func (h *UserHandler) Profile(ctx *gin.Context) {
 	type Profile struct {
-		Id       int64
-		Email    string
-		NickName string
-		Birthday string
-		AboutMe  string
+		Id            int64
+		Email         string
+		EmailVerified bool
+		NickName      string
+		Birthday      string
+		AboutMe       string
 	}
 	//sess := sessions.Default(ctx)
 	//id := sess.Get("userId").(int64)
@@ -336,38 +443,46 @@ func (h *UserHandler) Profile(ctx *gin.Context) {
 	// 将Time类型格式化为字符串
 	dateString := timeValue.Format("2006-01-02")
 	ctx.JSON(http.StatusOK, Profile{
-		Id:       id,
-		Email:    u.Email,
-		NickName: u.NickName,
-		Birthday: dateString,
-		AboutMe:  u.AboutMe,
+		Id:            id,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		NickName:      u.NickName,
+		Birthday:      dateString,
+		AboutMe:       u.AboutMe,
 	})
 }
 
 func (h *UserHandler) RefreshToken(ctx *gin.Context) {
 	tokenStr := h.ExtractToken(ctx)
-	var rc ijwt.RefreshClaims
-
-	token, err := jwt.ParseWithClaims(tokenStr, &rc, func(token *jwt.Token) (interface{}, error) {
-		return ijwt.RefreshKey, nil
-	})
-
+	rc, err := h.VerifyRefreshToken(tokenStr)
 	if err != nil {
 		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
-	if token == nil || !token.Valid {
+
+	err = h.CheckSession(ctx, rc.Ssid)
+	if err != nil {
 		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
 
-	err = h.CheckSession(ctx, rc.Ssid)
+	// 角色可能变了，重新查一遍
+	u, err := h.svc.FindById(ctx, rc.Uid)
 	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	// 封禁、合并和注销的时候会吊销所有会话，这里再兜底一次
+	if u.Status != domain.UserStatusActive {
 		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
 
-	err = h.SetJWTToken(ctx, rc.Uid, rc.Ssid)
+	// 长 token 和刷新 token 都换新的，刷新 token 被重复使用的时候整个会话都会被吊销
+	err = h.RotateRefreshToken(ctx, rc, u.Roles)
 	if err != nil {
 		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
@@ -376,6 +491,86 @@ func (h *UserHandler) RefreshToken(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, "刷新成功")
 }
 
+func (h *UserHandler) Sessions(ctx *gin.Context) {
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.String(http.StatusOK, "系统错误")
+		return
+	}
+	sessions, err := h.ListSessions(ctx, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	res := make([]SessionVO, 0, len(sessions))
+	for _, s := range sessions {
+		res = append(res, SessionVO{
+			Ssid:        s.Ssid,
+			UserAgent:   s.UserAgent,
+			IP:          s.IP,
+			LoginTime:   time.UnixMilli(s.LoginTime).Format(time.DateTime),
+			LoginMethod: s.LoginMethod,
+			Current:     s.Ssid == uc.Ssid,
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+func (h *UserHandler) RevokeSession(ctx *gin.Context) {
+	type Req struct {
+		Ssid string `json:"ssid"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.String(http.StatusOK, "系统错误")
+		return
+	}
+	err := h.Handler.RevokeSession(ctx, uc.Uid, req.Ssid)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+	case ijwt.ErrSessionNotFound:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "会话不存在",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// RevokeAllSessions 退出所有设备，包括当前这个
+func (h *UserHandler) RevokeAllSessions(ctx *gin.Context) {
+	uc, ok := ctx.MustGet("user").(ijwt.UserClaims)
+	if !ok {
+		ctx.String(http.StatusOK, "系统错误")
+		return
+	}
+	err := h.Handler.RevokeAllSessions(ctx, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.Header("x-jwt-token", "")
+	ctx.Header("x-refresh-token", "")
+	ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+}
+
 func (h *UserHandler) LogoutJWT(ctx *gin.Context) {
 	err := h.ClearToken(ctx)
 	if err != nil {
@@ -385,3 +580,250 @@ func (h *UserHandler) LogoutJWT(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, "退出登录成功")
 
 }
+
+func (h *UserHandler) ChangePassword(ctx *gin.Context) {
+	type Req struct {
+		OldPassword     string `json:"oldPassword"`
+		NewPassword     string `json:"newPassword"`
+		ConfirmPassword string `json:"confirmPassword"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	if !h.checkNewPassword(ctx, req.NewPassword, req.ConfirmPassword) {
+		return
+	}
+	u, err := h.svc.FindById(ctx, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	// 拿到 token 的人也不能无限次地猜旧密码，失败次数和登录记在一起
+	account := attemptAccount(u)
+	if h.loginLocked(ctx, account) {
+		return
+	}
+	err = h.svc.ChangePassword(ctx, uc.Uid, req.OldPassword, req.NewPassword)
+	if err == service.ErrInvalidUserOrPassword {
+		if h.loginFailed(ctx, account) {
+			return
+		}
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "旧密码错误",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	// 密码可能泄露了，所有设备都下线，当前设备重新登录一次
+	err = h.Handler.RevokeAllSessions(ctx, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	err = h.SetLoginToken(ctx, uc.Uid, uc.Roles, ijwt.LoginMethodPassword)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Msg: "修改成功"})
+}
+
+func (h *UserHandler) ResetPassword(ctx *gin.Context) {
+	type Req struct {
+		Phone           string `json:"phone"`
+		Code            string `json:"code"`
+		Password        string `json:"password"`
+		ConfirmPassword string `json:"confirmPassword"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	// 先校验密码格式，不然验证码用掉了还得重新发
+	if !h.checkNewPassword(ctx, req.Password, req.ConfirmPassword) {
+		return
+	}
+	ok, err := h.codeSvc.Verify(ctx, bizResetPassword, req.Phone, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "验证码错误，请重新输入",
+		})
+		return
+	}
+	u, err := h.svc.ResetPassword(ctx, req.Phone, req.Password)
+	if err == service.ErrUserNotFound {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "用户不存在",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	// 忘记密码的时候不知道是谁登录着，全部下线
+	err = h.Handler.RevokeAllSessions(ctx, u.Id)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Msg: "重置成功，请重新登录"})
+}
+
+// checkNewPassword 校验新密码，不通过的时候已经写好了响应
+func (h *UserHandler) checkNewPassword(ctx *gin.Context, password, confirmPassword string) bool {
+	if password != confirmPassword {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "两次密码输入的不一样",
+		})
+		return false
+	}
+	isPassword, err := h.passwordRegexExp.MatchString(password)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return false
+	}
+	if !isPassword {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "密码必须包含数字、特殊字符、并且长度不能小于8位",
+		})
+		return false
+	}
+	return true
+}
+
+// SendVerifyEmailCode 给自己的邮箱发验证码
+func (h *UserHandler) SendVerifyEmailCode(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	u, err := h.svc.FindById(ctx, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if u.Email == "" {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "还没有设置邮箱",
+		})
+		return
+	}
+	if u.EmailVerified {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "邮箱已经验证过了",
+		})
+		return
+	}
+	h.sendEmailCode(ctx, bizVerifyEmail, u.Email)
+}
+
+func (h *UserHandler) sendEmailCode(ctx *gin.Context, biz string, addr string) {
+	err := h.emailCodeSvc.Send(ctx, biz, addr)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "发送成功",
+		})
+	case service.ErrCodeSendTooMany:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "邮件发送太频繁，请稍后再试",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+	}
+}
+
+func (h *UserHandler) VerifyEmail(ctx *gin.Context) {
+	type Req struct {
+		Code string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	u, err := h.svc.FindById(ctx, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if u.Email == "" {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "还没有设置邮箱",
+		})
+		return
+	}
+	ok, err := h.emailCodeSvc.Verify(ctx, bizVerifyEmail, u.Email, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "验证码错误，请重新输入",
+		})
+		return
+	}
+	err = h.svc.VerifyEmail(ctx, uc.Uid, u.Email)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Msg: "验证成功"})
+}
@@ -0,0 +1,225 @@
+package web
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/service"
+	ijwt "webook/internal/web/jwt"
+)
+
+// SendDeleteAccountCode 注销前的验证码，绑定了手机号就发短信，不然发到邮箱
+func (h *UserHandler) SendDeleteAccountCode(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	u, err := h.svc.FindById(ctx, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	switch {
+	case u.Phone != "":
+		err = h.codeSvc.Send(ctx, bizDeleteAccount, u.Phone)
+		switch err {
+		case nil:
+			ctx.JSON(http.StatusOK, Result{
+				Msg: "发送成功",
+			})
+		case service.ErrCodeSendTooMany:
+			ctx.JSON(http.StatusOK, Result{
+				Code: 4,
+				Msg:  "短信发送太频繁，请稍后再试",
+			})
+		default:
+			ctx.JSON(http.StatusOK, Result{
+				Code: 5,
+				Msg:  "系统错误",
+			})
+		}
+	case u.Email != "":
+		h.sendEmailCode(ctx, bizDeleteAccount, u.Email)
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "没有绑定手机号或者邮箱，请输入密码",
+		})
+	}
+}
+
+// DeleteAccount 注销账号，所有设备都会下线
+// 没法撤销，所以要再输入一次密码，或者是刚收到的验证码
+func (h *UserHandler) DeleteAccount(ctx *gin.Context) {
+	type Req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	if !h.reauthenticate(ctx, uc.Uid, req.Password, req.Code) {
+		return
+	}
+	err := h.accountSvc.Delete(ctx, uc.Uid)
+	if err == service.ErrUserNotFound {
+		// 上一次注销之后吊销会话失败了，能走到这里说明会话还在，再吊销一次
+		if h.Handler.RevokeAllSessions(ctx, uc.Uid) != nil {
+			ctx.JSON(http.StatusOK, Result{
+				Code: 5,
+				Msg:  "系统错误",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "账号已经注销了",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	// 账号已经注销了，没法回滚，告诉前端失败了，重试的时候会再吊销一次
+	err = h.Handler.RevokeAllSessions(ctx, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.Header("x-jwt-token", "")
+	ctx.Header("x-refresh-token", "")
+	ctx.JSON(http.StatusOK, Result{Msg: "注销成功"})
+}
+
+// ExportData 导出个人数据，以附件的形式下载
+func (h *UserHandler) ExportData(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	a, err := h.accountSvc.Export(ctx, uc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	p := a.Profile
+	res := UserArchiveVO{
+		Profile: UserProfileVO{
+			Id:            p.Id,
+			Email:         p.Email,
+			EmailVerified: p.EmailVerified,
+			Phone:         p.Phone,
+			NickName:      p.NickName,
+			Birthday:      time.Unix(p.Birthday, 0).Format(time.DateOnly),
+			AboutMe:       p.AboutMe,
+			WechatBound:   p.WechatInfo.OpenId != "",
+		},
+		Articles: make([]ArticleVO, 0, len(a.Articles)),
+		Likes:    make([]InteractionVO, 0, len(a.Likes)),
+		Collects: make([]InteractionVO, 0, len(a.Collects)),
+		// 导出时间
+		Ctime: time.Now().Format(time.DateTime),
+	}
+	for _, art := range a.Articles {
+		res.Articles = append(res.Articles, ArticleVO{
+			Id:       art.Id,
+			Title:    art.Title,
+			Content:  art.Content,
+			AuthorId: art.Author.Id,
+			Status:   art.Status.ToUint8(),
+			Ctime:    art.Ctime.Format(time.DateTime),
+			Utime:    art.Utime.Format(time.DateTime),
+		})
+	}
+	for _, l := range a.Likes {
+		res.Likes = append(res.Likes, InteractionVO{
+			Biz:   l.Biz,
+			BizId: l.BizId,
+			Ctime: l.Ctime.Format(time.DateTime),
+		})
+	}
+	for _, c := range a.Collects {
+		res.Collects = append(res.Collects, InteractionVO{
+			Biz:   c.Biz,
+			BizId: c.BizId,
+			Ctime: c.Ctime.Format(time.DateTime),
+		})
+	}
+	ctx.Header("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="webook-%d.json"`, uc.Uid))
+	ctx.JSON(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// reauthenticate 敏感操作之前确认是本人，密码错误和登录共用失败次数
+func (h *UserHandler) reauthenticate(ctx *gin.Context, uid int64, password string, code string) bool {
+	u, err := h.svc.FindById(ctx, uid)
+	if err == service.ErrUserNotFound || u.Status == domain.UserStatusDeleted {
+		// 已经注销了，没有什么可以校验的，交给后面重新吊销会话
+		return true
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return false
+	}
+	var ok bool
+	switch {
+	case password != "":
+		account := attemptAccount(u)
+		if h.loginLocked(ctx, account) {
+			return false
+		}
+		err = h.svc.CheckPassword(ctx, uid, password)
+		if err == service.ErrInvalidUserOrPassword {
+			if h.loginFailed(ctx, account) {
+				return false
+			}
+			ctx.JSON(http.StatusOK, Result{
+				Code: 4,
+				Msg:  "密码错误",
+			})
+			return false
+		}
+		ok = err == nil
+	case code != "" && u.Phone != "":
+		ok, err = h.codeSvc.Verify(ctx, bizDeleteAccount, u.Phone, code)
+	case code != "" && u.Email != "":
+		ok, err = h.emailCodeSvc.Verify(ctx, bizDeleteAccount, u.Email, code)
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "请输入密码或者验证码",
+		})
+		return false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return false
+	}
+	if !ok {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "验证码错误，请重新输入",
+		})
+		return false
+	}
+	return true
+}
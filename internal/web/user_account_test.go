@@ -0,0 +1,161 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/service"
+	svcmocks "webook/internal/service/mocks"
+	ijwt "webook/internal/web/jwt"
+	jwtmocks "webook/internal/web/jwt/mocks"
+)
+
+func TestUserHandler_DeleteAccount(t *testing.T) {
+	type mocks struct {
+		userSvc      *svcmocks.MockUserService
+		accountSvc   *svcmocks.MockUserAccountService
+		codeSvc      *svcmocks.MockCodeService
+		emailCodeSvc *svcmocks.MockEmailCodeService
+		attemptSvc   *svcmocks.MockLoginAttemptService
+		jwtHdl       *jwtmocks.MockHandler
+	}
+	user := domain.User{Id: 123, Email: "123@qq.com", Phone: "15212345678"}
+	// 密码校验通过
+	passwordOK := func(m mocks) {
+		m.userSvc.EXPECT().FindById(gomock.Any(), int64(123)).Return(user, nil)
+		m.attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+		m.userSvc.EXPECT().CheckPassword(gomock.Any(), int64(123), "hello#world123").Return(nil)
+	}
+	testCases := []struct {
+		name    string
+		mock    func(m mocks)
+		reqBody string
+
+		wantRes Result
+	}{
+		{
+			name: "用密码注销成功",
+			mock: func(m mocks) {
+				passwordOK(m)
+				m.accountSvc.EXPECT().Delete(gomock.Any(), int64(123)).Return(nil)
+				m.jwtHdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(123)).Return(nil)
+			},
+			reqBody: `{"password":"hello#world123"}`,
+			wantRes: Result{Msg: "注销成功"},
+		},
+		{
+			name: "用短信验证码注销成功",
+			mock: func(m mocks) {
+				m.userSvc.EXPECT().FindById(gomock.Any(), int64(123)).Return(user, nil)
+				m.codeSvc.EXPECT().Verify(gomock.Any(), bizDeleteAccount, "15212345678", "123456").Return(true, nil)
+				m.accountSvc.EXPECT().Delete(gomock.Any(), int64(123)).Return(nil)
+				m.jwtHdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(123)).Return(nil)
+			},
+			reqBody: `{"code":"123456"}`,
+			wantRes: Result{Msg: "注销成功"},
+		},
+		{
+			name: "没有手机号，用邮件验证码",
+			mock: func(m mocks) {
+				m.userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				m.emailCodeSvc.EXPECT().Verify(gomock.Any(), bizDeleteAccount, "123@qq.com", "123456").Return(false, nil)
+			},
+			reqBody: `{"code":"123456"}`,
+			wantRes: Result{Code: 4, Msg: "验证码错误，请重新输入"},
+		},
+		{
+			name: "没有输入密码和验证码",
+			mock: func(m mocks) {
+				m.userSvc.EXPECT().FindById(gomock.Any(), int64(123)).Return(user, nil)
+			},
+			reqBody: `{}`,
+			wantRes: Result{Code: 4, Msg: "请输入密码或者验证码"},
+		},
+		{
+			name: "密码错误",
+			mock: func(m mocks) {
+				m.userSvc.EXPECT().FindById(gomock.Any(), int64(123)).Return(user, nil)
+				m.attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				m.userSvc.EXPECT().CheckPassword(gomock.Any(), int64(123), "hello#world123").
+					Return(service.ErrInvalidUserOrPassword)
+				m.attemptSvc.EXPECT().Fail(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+			},
+			reqBody: `{"password":"hello#world123"}`,
+			wantRes: Result{Code: 4, Msg: "密码错误"},
+		},
+		{
+			name: "吊销会话失败",
+			mock: func(m mocks) {
+				passwordOK(m)
+				m.accountSvc.EXPECT().Delete(gomock.Any(), int64(123)).Return(nil)
+				m.jwtHdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(123)).Return(errors.New("redis 错误"))
+			},
+			reqBody: `{"password":"hello#world123"}`,
+			wantRes: Result{Code: 5, Msg: "系统错误"},
+		},
+		{
+			name: "已经注销过了，再吊销一次会话",
+			mock: func(m mocks) {
+				m.userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Status: domain.UserStatusDeleted}, nil)
+				m.accountSvc.EXPECT().Delete(gomock.Any(), int64(123)).Return(service.ErrUserNotFound)
+				m.jwtHdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(123)).Return(nil)
+			},
+			reqBody: `{}`,
+			wantRes: Result{Code: 4, Msg: "账号已经注销了"},
+		},
+		{
+			name: "系统错误",
+			mock: func(m mocks) {
+				passwordOK(m)
+				m.accountSvc.EXPECT().Delete(gomock.Any(), int64(123)).Return(errors.New("db 错误"))
+			},
+			reqBody: `{"password":"hello#world123"}`,
+			wantRes: Result{Code: 5, Msg: "系统错误"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			m := mocks{
+				userSvc:      svcmocks.NewMockUserService(ctrl),
+				accountSvc:   svcmocks.NewMockUserAccountService(ctrl),
+				codeSvc:      svcmocks.NewMockCodeService(ctrl),
+				emailCodeSvc: svcmocks.NewMockEmailCodeService(ctrl),
+				attemptSvc:   svcmocks.NewMockLoginAttemptService(ctrl),
+				jwtHdl:       jwtmocks.NewMockHandler(ctrl),
+			}
+			tc.mock(m)
+			hdl := NewUserHandler(m.userSvc, m.jwtHdl, m.codeSvc, m.emailCodeSvc, m.accountSvc, nil, m.attemptSvc)
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 123})
+			})
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/delete", bytes.NewBufferString(tc.reqBody))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"webook/internal/domain"
+	"webook/internal/service"
+	ijwt "webook/internal/web/jwt"
+)
+
+func (h *UserHandler) SendBindPhoneCode(ctx *gin.Context) {
+	h.sendSMSCode(ctx, bizBindPhone)
+}
+
+func (h *UserHandler) BindPhone(ctx *gin.Context) {
+	type Req struct {
+		Phone string `json:"phone"`
+		Code  string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	ok, err := h.codeSvc.Verify(ctx, bizBindPhone, req.Phone, req.Code)
+	if !h.checkCode(ctx, ok, err) {
+		return
+	}
+	err = h.svc.BindPhone(ctx, uc.Uid, req.Phone)
+	h.bindResult(ctx, err, "该手机号已经绑定了别的账号")
+}
+
+func (h *UserHandler) SendBindEmailCode(ctx *gin.Context) {
+	type Req struct {
+		Email string `json:"email"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	isEmail, err := h.emailRegexExp.MatchString(req.Email)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if !isEmail {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "邮箱格式不正确",
+		})
+		return
+	}
+	h.sendEmailCode(ctx, bizBindEmail, req.Email)
+}
+
+func (h *UserHandler) BindEmail(ctx *gin.Context) {
+	type Req struct {
+		Email string `json:"email"`
+		Code  string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	ok, err := h.emailCodeSvc.Verify(ctx, bizBindEmail, req.Email, req.Code)
+	if !h.checkCode(ctx, ok, err) {
+		return
+	}
+	err = h.svc.BindEmail(ctx, uc.Uid, req.Email)
+	h.bindResult(ctx, err, "该邮箱已经绑定了别的账号")
+}
+
+func (h *UserHandler) Unbind(ctx *gin.Context) {
+	type Req struct {
+		// Type 取值 phone、email、wechat
+		Type string `json:"type"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	identity := domain.Identity(req.Type)
+	switch identity {
+	case domain.IdentityPhone, domain.IdentityEmail, domain.IdentityWechat:
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	err := h.svc.Unbind(ctx, uc.Uid, identity)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{Msg: "解绑成功"})
+	case service.ErrLastLoginMethod:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "至少要保留一种登录方式",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// checkCode 验证码不对的时候已经写好了响应
+func (h *UserHandler) checkCode(ctx *gin.Context, ok bool, err error) bool {
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return false
+	}
+	if !ok {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "验证码错误，请重新输入",
+		})
+		return false
+	}
+	return true
+}
+
+func (h *UserHandler) bindResult(ctx *gin.Context, err error, conflictMsg string) {
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{Msg: "绑定成功"})
+	case service.ErrIdentityConflict:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  conflictMsg,
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+	}
+}
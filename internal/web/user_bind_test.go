@@ -0,0 +1,151 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"webook/internal/domain"
+	"webook/internal/service"
+	svcmocks "webook/internal/service/mocks"
+	ijwt "webook/internal/web/jwt"
+)
+
+func TestUserHandler_BindPhone(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.CodeService)
+
+		wantRes Result
+	}{
+		{
+			name: "绑定成功",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), bizBindPhone, "15811111111", "123456").Return(true, nil)
+				userSvc.EXPECT().BindPhone(gomock.Any(), int64(123), "15811111111").Return(nil)
+				return userSvc, codeSvc
+			},
+			wantRes: Result{Msg: "绑定成功"},
+		},
+		{
+			name: "验证码错误",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), bizBindPhone, "15811111111", "123456").Return(false, nil)
+				return svcmocks.NewMockUserService(ctrl), codeSvc
+			},
+			wantRes: Result{Code: 4, Msg: "验证码错误，请重新输入"},
+		},
+		{
+			name: "已经绑定了别的账号",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), bizBindPhone, "15811111111", "123456").Return(true, nil)
+				userSvc.EXPECT().BindPhone(gomock.Any(), int64(123), "15811111111").
+					Return(service.ErrIdentityConflict)
+				return userSvc, codeSvc
+			},
+			wantRes: Result{Code: 4, Msg: "该手机号已经绑定了别的账号"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, codeSvc := tc.mock(ctrl)
+			hdl := NewUserHandler(userSvc, nil, codeSvc, nil, nil, nil, nil)
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 123})
+			})
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/bind/phone",
+				bytes.NewBufferString(`{"phone":"15811111111","code":"123456"}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestUserHandler_Unbind(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) service.UserService
+		reqBody string
+
+		wantRes Result
+	}{
+		{
+			name: "解绑成功",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Unbind(gomock.Any(), int64(123), domain.IdentityWechat).Return(nil)
+				return userSvc
+			},
+			reqBody: `{"type":"wechat"}`,
+			wantRes: Result{Msg: "解绑成功"},
+		},
+		{
+			name: "类型不对",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				return svcmocks.NewMockUserService(ctrl)
+			},
+			reqBody: `{"type":"qq"}`,
+			wantRes: Result{Code: 4, Msg: "参数错误"},
+		},
+		{
+			name: "最后一种登录方式",
+			mock: func(ctrl *gomock.Controller) service.UserService {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Unbind(gomock.Any(), int64(123), domain.IdentityPhone).
+					Return(service.ErrLastLoginMethod)
+				return userSvc
+			},
+			reqBody: `{"type":"phone"}`,
+			wantRes: Result{Code: 4, Msg: "至少要保留一种登录方式"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			hdl := NewUserHandler(tc.mock(ctrl), nil, nil, nil, nil, nil, nil)
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 123})
+			})
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/unbind", bytes.NewBufferString(tc.reqBody))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/service"
+)
+
+// loginLocked 账号或者 IP 被锁定了就直接返回，不再校验密码
+// redis 出问题的时候放行，不能让所有人都登录不了
+func (h *UserHandler) loginLocked(ctx *gin.Context, account string) bool {
+	ttl, err := h.attemptSvc.Check(ctx, account, ctx.ClientIP())
+	if err != service.ErrLoginLocked {
+		return false
+	}
+	h.lockedResult(ctx, ttl)
+	return true
+}
+
+// loginFailed 记录一次失败，这一次触发了锁定就直接告诉用户
+func (h *UserHandler) loginFailed(ctx *gin.Context, account string) bool {
+	ttl, err := h.attemptSvc.Fail(ctx, account, ctx.ClientIP())
+	if err != nil || ttl <= 0 {
+		return false
+	}
+	h.lockedResult(ctx, ttl)
+	return true
+}
+
+// loginSucceeded 清空失败次数，失败了也不影响这一次登录
+func (h *UserHandler) loginSucceeded(ctx *gin.Context, account string) {
+	_ = h.attemptSvc.Succeed(ctx, account)
+}
+
+func (h *UserHandler) lockedResult(ctx *gin.Context, ttl time.Duration) {
+	// 不足一分钟按一分钟算
+	minutes := int64((ttl + time.Minute - 1) / time.Minute)
+	ctx.JSON(http.StatusOK, Result{
+		Code: codeLoginLocked,
+		Msg:  fmt.Sprintf("登录失败次数太多，请 %d 分钟后再试", minutes),
+	})
+}
+
+// attemptAccount 已经登录的用户再次校验密码或者验证码的时候，失败次数和登录记在同一个账号上
+// 只绑定了手机号的用户没有邮箱，就用 uid
+func attemptAccount(u domain.User) string {
+	if u.Email != "" {
+		return u.Email
+	}
+	return "uid:" + strconv.FormatInt(u.Id, 10)
+}
@@ -0,0 +1,100 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/service"
+	svcmocks "webook/internal/service/mocks"
+)
+
+func TestUserHandler_LoginJWT_BruteForce(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.LoginAttemptService)
+
+		wantBody string
+	}{
+		{
+			name: "已经被锁定了，不校验密码",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginAttemptService) {
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", "10.0.0.1").
+					Return(time.Second*90, service.ErrLoginLocked)
+				return svcmocks.NewMockUserService(ctrl), attemptSvc
+			},
+			wantBody: `{"code":8,"msg":"登录失败次数太多，请 2 分钟后再试","data":null}`,
+		},
+		{
+			name: "密码错误",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginAttemptService) {
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", "10.0.0.1").Return(time.Duration(0), nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Login(gomock.Any(), "123@qq.com", "hello#world123").
+					Return(domain.User{}, service.ErrInvalidUserOrPassword)
+				attemptSvc.EXPECT().Fail(gomock.Any(), "123@qq.com", "10.0.0.1").Return(time.Duration(0), nil)
+				return userSvc, attemptSvc
+			},
+			wantBody: "用户名或密码错误",
+		},
+		{
+			name: "密码错误触发锁定",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginAttemptService) {
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", "10.0.0.1").Return(time.Duration(0), nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Login(gomock.Any(), "123@qq.com", "hello#world123").
+					Return(domain.User{}, service.ErrInvalidUserOrPassword)
+				attemptSvc.EXPECT().Fail(gomock.Any(), "123@qq.com", "10.0.0.1").Return(time.Minute, nil)
+				return userSvc, attemptSvc
+			},
+			wantBody: `{"code":8,"msg":"登录失败次数太多，请 1 分钟后再试","data":null}`,
+		},
+		{
+			name: "redis 出错的时候放行",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginAttemptService) {
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", "10.0.0.1").
+					Return(time.Duration(0), errors.New("redis 错误"))
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Login(gomock.Any(), "123@qq.com", "hello#world123").
+					Return(domain.User{}, service.ErrInvalidUserOrPassword)
+				attemptSvc.EXPECT().Fail(gomock.Any(), "123@qq.com", "10.0.0.1").
+					Return(time.Duration(0), errors.New("redis 错误"))
+				return userSvc, attemptSvc
+			},
+			wantBody: "用户名或密码错误",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, attemptSvc := tc.mock(ctrl)
+			hdl := NewUserHandler(userSvc, nil, nil, nil, nil, nil, attemptSvc)
+			server := gin.Default()
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/login",
+				bytes.NewBufferString(`{"email":"123@qq.com","password":"hello#world123"}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.RemoteAddr = "10.0.0.1:12345"
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
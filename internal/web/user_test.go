@@ -2,6 +2,7 @@ package web
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -10,9 +11,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"webook/internal/domain"
 	"webook/internal/service"
 	svcmocks "webook/internal/service/mocks"
+	ijwt "webook/internal/web/jwt"
+	jwtmocks "webook/internal/web/jwt/mocks"
 )
 
 func TestUserEmailPattern(t *testing.T) {
@@ -31,7 +35,7 @@ func TestUserEmailPattern(t *testing.T) {
 		},
 	}
 
-	h := NewUserHandler(nil, nil, nil)
+	h := NewUserHandler(nil, nil, nil, nil, nil, nil, nil)
 
 	for _, tc := range testcase {
 		t.Run(tc.name, func(t *testing.T) {
@@ -47,7 +51,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 		name string
 
 		// mock
-		mock func(ctrl *gomock.Controller) (service.UserService, service.CodeService)
+		mock func(ctrl *gomock.Controller) (service.UserService, service.CodeService, service.EmailCodeService)
 
 		// 构造请求，预期中的输入
 		reqBuilder func(t *testing.T) *http.Request
@@ -58,14 +62,16 @@ func TestUserHandler_SignUp(t *testing.T) {
 	}{
 		{
 			name: "注册成功",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, service.EmailCodeService) {
 				userSvc := svcmocks.NewMockUserService(ctrl)
 				codeSvc := svcmocks.NewMockCodeService(ctrl)
 				userSvc.EXPECT().Signup(gomock.Any(), domain.User{
 					Email:    "123@qq.com",
 					Password: "Hello#world123",
 				}).Return(nil)
-				return userSvc, codeSvc
+				emailCodeSvc := svcmocks.NewMockEmailCodeService(ctrl)
+				emailCodeSvc.EXPECT().Send(gomock.Any(), bizVerifyEmail, "123@qq.com").Return(nil)
+				return userSvc, codeSvc, emailCodeSvc
 			},
 			reqBuilder: func(t *testing.T) *http.Request {
 				req, err := http.NewRequest(http.MethodPost, "/users/signup", bytes.NewReader([]byte(`{
@@ -82,11 +88,11 @@ func TestUserHandler_SignUp(t *testing.T) {
 		},
 		{
 			name: "非JSON输入",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, service.EmailCodeService) {
 				userSvc := svcmocks.NewMockUserService(ctrl)
 				codeSvc := svcmocks.NewMockCodeService(ctrl)
 
-				return userSvc, codeSvc
+				return userSvc, codeSvc, svcmocks.NewMockEmailCodeService(ctrl)
 			},
 			reqBuilder: func(t *testing.T) *http.Request {
 				req, err := http.NewRequest(http.MethodPost, "/users/signup", bytes.NewReader([]byte(`{
@@ -102,10 +108,10 @@ func TestUserHandler_SignUp(t *testing.T) {
 		},
 		{
 			name: "邮箱格式不对",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, service.EmailCodeService) {
 				userSvc := svcmocks.NewMockUserService(ctrl)
 				codeSvc := svcmocks.NewMockCodeService(ctrl)
-				return userSvc, codeSvc
+				return userSvc, codeSvc, svcmocks.NewMockEmailCodeService(ctrl)
 			},
 			reqBuilder: func(t *testing.T) *http.Request {
 				req, err := http.NewRequest(http.MethodPost, "/users/signup", bytes.NewReader([]byte(`{
@@ -122,10 +128,10 @@ func TestUserHandler_SignUp(t *testing.T) {
 		},
 		{
 			name: "两次密码输入不同",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, service.EmailCodeService) {
 				userSvc := svcmocks.NewMockUserService(ctrl)
 				codeSvc := svcmocks.NewMockCodeService(ctrl)
-				return userSvc, codeSvc
+				return userSvc, codeSvc, svcmocks.NewMockEmailCodeService(ctrl)
 			},
 			reqBuilder: func(t *testing.T) *http.Request {
 				req, err := http.NewRequest(http.MethodPost, "/users/signup", bytes.NewReader([]byte(`{
@@ -142,10 +148,10 @@ func TestUserHandler_SignUp(t *testing.T) {
 		},
 		{
 			name: "密码格式不对",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, service.EmailCodeService) {
 				userSvc := svcmocks.NewMockUserService(ctrl)
 				codeSvc := svcmocks.NewMockCodeService(ctrl)
-				return userSvc, codeSvc
+				return userSvc, codeSvc, svcmocks.NewMockEmailCodeService(ctrl)
 			},
 			reqBuilder: func(t *testing.T) *http.Request {
 				req, err := http.NewRequest(http.MethodPost, "/users/signup", bytes.NewReader([]byte(`{
@@ -162,14 +168,14 @@ func TestUserHandler_SignUp(t *testing.T) {
 		},
 		{
 			name: "系统错误",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, service.EmailCodeService) {
 				userSvc := svcmocks.NewMockUserService(ctrl)
 				codeSvc := svcmocks.NewMockCodeService(ctrl)
 				userSvc.EXPECT().Signup(gomock.Any(), domain.User{
 					Email:    "123@qq.com",
 					Password: "Hello#world123",
 				}).Return(errors.New("db connection failure"))
-				return userSvc, codeSvc
+				return userSvc, codeSvc, svcmocks.NewMockEmailCodeService(ctrl)
 			},
 			reqBuilder: func(t *testing.T) *http.Request {
 				req, err := http.NewRequest(http.MethodPost, "/users/signup", bytes.NewReader([]byte(`{
@@ -186,14 +192,14 @@ func TestUserHandler_SignUp(t *testing.T) {
 		},
 		{
 			name: "邮箱冲突",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, service.EmailCodeService) {
 				userSvc := svcmocks.NewMockUserService(ctrl)
 				codeSvc := svcmocks.NewMockCodeService(ctrl)
 				userSvc.EXPECT().Signup(gomock.Any(), domain.User{
 					Email:    "123@qq.com",
 					Password: "Hello#world123",
 				}).Return(service.ErrDuplicateEmail)
-				return userSvc, codeSvc
+				return userSvc, codeSvc, svcmocks.NewMockEmailCodeService(ctrl)
 			},
 			reqBuilder: func(t *testing.T) *http.Request {
 				req, err := http.NewRequest(http.MethodPost, "/users/signup", bytes.NewReader([]byte(`{
@@ -214,10 +220,10 @@ func TestUserHandler_SignUp(t *testing.T) {
 		t.Run(testCase.name, func(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
-			userSvc, codeSvc := testCase.mock(ctrl)
+			userSvc, codeSvc, emailCodeSvc := testCase.mock(ctrl)
 
 			// 利用mock构造UserHandler
-			hdl := NewUserHandler(userSvc, nil, codeSvc)
+			hdl := NewUserHandler(userSvc, nil, codeSvc, emailCodeSvc, nil, nil, nil)
 
 			// 准备服务器 注册路由
 			server := gin.Default()
@@ -237,3 +243,481 @@ func TestUserHandler_SignUp(t *testing.T) {
 		})
 	}
 }
+
+func TestUserHandler_RefreshToken(t *testing.T) {
+	rc := ijwt.RefreshClaims{
+		Uid:  123,
+		Ssid: "ssid-1",
+	}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler)
+
+		wantCode int
+	}{
+		{
+			name: "刷新成功",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("refresh-token")
+				hdl.EXPECT().VerifyRefreshToken("refresh-token").Return(rc, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), "ssid-1").Return(nil)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Roles: []string{"admin"}}, nil)
+				hdl.EXPECT().RotateRefreshToken(gomock.Any(), rc, []string{"admin"}).Return(nil)
+				return userSvc, hdl
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "刷新 token 无效",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("refresh-token")
+				hdl.EXPECT().VerifyRefreshToken("refresh-token").Return(ijwt.RefreshClaims{}, errors.New("token 无效"))
+				return userSvc, hdl
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "会话已经退出",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("refresh-token")
+				hdl.EXPECT().VerifyRefreshToken("refresh-token").Return(rc, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), "ssid-1").Return(errors.New("token 无效"))
+				return userSvc, hdl
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "刷新 token 被重复使用",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("refresh-token")
+				hdl.EXPECT().VerifyRefreshToken("refresh-token").Return(rc, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), "ssid-1").Return(nil)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).Return(domain.User{Id: 123}, nil)
+				hdl.EXPECT().RotateRefreshToken(gomock.Any(), rc, []string(nil)).Return(ijwt.ErrRefreshTokenReused)
+				return userSvc, hdl
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "账号已被封禁",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("refresh-token")
+				hdl.EXPECT().VerifyRefreshToken("refresh-token").Return(rc, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), "ssid-1").Return(nil)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Status: domain.UserStatusBanned}, nil)
+				return userSvc, hdl
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "账号已经被合并",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("refresh-token")
+				hdl.EXPECT().VerifyRefreshToken("refresh-token").Return(rc, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), "ssid-1").Return(nil)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Status: domain.UserStatusMerged}, nil)
+				return userSvc, hdl
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "查询用户失败",
+			mock: func(ctrl *gomock.Controller) (service.UserService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractToken(gomock.Any()).Return("refresh-token")
+				hdl.EXPECT().VerifyRefreshToken("refresh-token").Return(rc, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), "ssid-1").Return(nil)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).Return(domain.User{}, errors.New("db 错误"))
+				return userSvc, hdl
+			},
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, jwtHdl := tc.mock(ctrl)
+			hdl := NewUserHandler(userSvc, jwtHdl, nil, nil, nil, nil, nil)
+			server := gin.Default()
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodGet, "/users/refresh_token", nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+		})
+	}
+}
+
+func TestUserHandler_Sessions(t *testing.T) {
+	loginTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) ijwt.Handler
+
+		reqBuilder func(t *testing.T) *http.Request
+
+		wantBody string
+	}{
+		{
+			name: "列出所有设备",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ListSessions(gomock.Any(), int64(123)).Return([]ijwt.Session{
+					{
+						Ssid:        "ssid-1",
+						UserAgent:   "Chrome",
+						IP:          "127.0.0.1",
+						LoginTime:   loginTime.UnixMilli(),
+						LoginMethod: ijwt.LoginMethodPassword,
+					},
+					{
+						Ssid:        "ssid-2",
+						UserAgent:   "iPhone",
+						IP:          "10.0.0.1",
+						LoginTime:   loginTime.UnixMilli(),
+						LoginMethod: ijwt.LoginMethodSMS,
+					},
+				}, nil)
+				return hdl
+			},
+			reqBuilder: func(t *testing.T) *http.Request {
+				req, err := http.NewRequest(http.MethodGet, "/users/sessions", nil)
+				require.NoError(t, err)
+				return req
+			},
+			wantBody: `{"code":0,"msg":"","data":[` +
+				`{"ssid":"ssid-1","userAgent":"Chrome","ip":"127.0.0.1","loginTime":"2024-01-02 03:04:05","loginMethod":"password","current":true},` +
+				`{"ssid":"ssid-2","userAgent":"iPhone","ip":"10.0.0.1","loginTime":"2024-01-02 03:04:05","loginMethod":"sms","current":false}]}`,
+		},
+		{
+			name: "踢掉某个设备",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeSession(gomock.Any(), int64(123), "ssid-2").Return(nil)
+				return hdl
+			},
+			reqBuilder: func(t *testing.T) *http.Request {
+				req, err := http.NewRequest(http.MethodPost, "/users/sessions/revoke",
+					bytes.NewReader([]byte(`{"ssid":"ssid-2"}`)))
+				require.NoError(t, err)
+				req.Header.Set("Content-Type", "application/json")
+				return req
+			},
+			wantBody: `{"code":0,"msg":"OK","data":null}`,
+		},
+		{
+			name: "踢掉别人的设备",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeSession(gomock.Any(), int64(123), "ssid-other").Return(ijwt.ErrSessionNotFound)
+				return hdl
+			},
+			reqBuilder: func(t *testing.T) *http.Request {
+				req, err := http.NewRequest(http.MethodPost, "/users/sessions/revoke",
+					bytes.NewReader([]byte(`{"ssid":"ssid-other"}`)))
+				require.NoError(t, err)
+				req.Header.Set("Content-Type", "application/json")
+				return req
+			},
+			wantBody: `{"code":4,"msg":"会话不存在","data":null}`,
+		},
+		{
+			name: "退出所有设备",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(123)).Return(nil)
+				return hdl
+			},
+			reqBuilder: func(t *testing.T) *http.Request {
+				req, err := http.NewRequest(http.MethodPost, "/users/sessions/revoke_all", nil)
+				require.NoError(t, err)
+				return req
+			},
+			wantBody: `{"code":0,"msg":"OK","data":null}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			hdl := NewUserHandler(nil, tc.mock(ctrl), nil, nil, nil, nil, nil)
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{
+					Uid:  123,
+					Ssid: "ssid-1",
+				})
+			})
+			hdl.RegisterRoutes(server)
+
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, tc.reqBuilder(t))
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_ResetPassword(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler)
+
+		reqBody string
+
+		wantRes Result
+	}{
+		{
+			name: "重置成功",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), bizResetPassword, "15811111111", "123456").Return(true, nil)
+				userSvc.EXPECT().ResetPassword(gomock.Any(), "15811111111", "New#world123").
+					Return(domain.User{Id: 1}, nil)
+				hdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(1)).Return(nil)
+				return userSvc, codeSvc, hdl
+			},
+			reqBody: `{"phone":"15811111111","code":"123456","password":"New#world123","confirmPassword":"New#world123"}`,
+			wantRes: Result{Msg: "重置成功，请重新登录"},
+		},
+		{
+			name: "两次密码不一样",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				return svcmocks.NewMockUserService(ctrl), svcmocks.NewMockCodeService(ctrl), jwtmocks.NewMockHandler(ctrl)
+			},
+			reqBody: `{"phone":"15811111111","code":"123456","password":"New#world123","confirmPassword":"New#world1234"}`,
+			wantRes: Result{Code: 4, Msg: "两次密码输入的不一样"},
+		},
+		{
+			name: "验证码错误",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), bizResetPassword, "15811111111", "123456").Return(false, nil)
+				return svcmocks.NewMockUserService(ctrl), codeSvc, jwtmocks.NewMockHandler(ctrl)
+			},
+			reqBody: `{"phone":"15811111111","code":"123456","password":"New#world123","confirmPassword":"New#world123"}`,
+			wantRes: Result{Code: 4, Msg: "验证码错误，请重新输入"},
+		},
+		{
+			name: "用户不存在",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, ijwt.Handler) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), bizResetPassword, "15811111111", "123456").Return(true, nil)
+				userSvc.EXPECT().ResetPassword(gomock.Any(), "15811111111", "New#world123").
+					Return(domain.User{}, service.ErrUserNotFound)
+				return userSvc, codeSvc, jwtmocks.NewMockHandler(ctrl)
+			},
+			reqBody: `{"phone":"15811111111","code":"123456","password":"New#world123","confirmPassword":"New#world123"}`,
+			wantRes: Result{Code: 4, Msg: "用户不存在"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, codeSvc, jwtHdl := tc.mock(ctrl)
+			hdl := NewUserHandler(userSvc, jwtHdl, codeSvc, nil, nil, nil, nil)
+			server := gin.Default()
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/password/reset", bytes.NewBufferString(tc.reqBody))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestUserHandler_VerifyEmail(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService)
+
+		wantRes Result
+	}{
+		{
+			name: "验证成功",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				emailCodeSvc := svcmocks.NewMockEmailCodeService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				emailCodeSvc.EXPECT().Verify(gomock.Any(), bizVerifyEmail, "123@qq.com", "123456").Return(true, nil)
+				userSvc.EXPECT().VerifyEmail(gomock.Any(), int64(123), "123@qq.com").Return(nil)
+				return userSvc, emailCodeSvc
+			},
+			wantRes: Result{Msg: "验证成功"},
+		},
+		{
+			name: "没有邮箱",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).Return(domain.User{Id: 123}, nil)
+				return userSvc, svcmocks.NewMockEmailCodeService(ctrl)
+			},
+			wantRes: Result{Code: 4, Msg: "还没有设置邮箱"},
+		},
+		{
+			name: "验证码错误",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				emailCodeSvc := svcmocks.NewMockEmailCodeService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				emailCodeSvc.EXPECT().Verify(gomock.Any(), bizVerifyEmail, "123@qq.com", "123456").Return(false, nil)
+				return userSvc, emailCodeSvc
+			},
+			wantRes: Result{Code: 4, Msg: "验证码错误，请重新输入"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, emailCodeSvc := tc.mock(ctrl)
+			hdl := NewUserHandler(userSvc, nil, nil, emailCodeSvc, nil, nil, nil)
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 123})
+			})
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/email/verify", bytes.NewBufferString(`{"code":"123456"}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestUserHandler_ChangePassword(t *testing.T) {
+	type mocks struct {
+		userSvc    *svcmocks.MockUserService
+		attemptSvc *svcmocks.MockLoginAttemptService
+		jwtHdl     *jwtmocks.MockHandler
+	}
+	user := domain.User{Id: 123, Email: "123@qq.com"}
+	const reqBody = `{"oldPassword":"hello#world123","newPassword":"hello#world456","confirmPassword":"hello#world456"}`
+	testCases := []struct {
+		name string
+		mock func(m mocks)
+
+		wantRes Result
+	}{
+		{
+			name: "修改成功",
+			mock: func(m mocks) {
+				m.userSvc.EXPECT().FindById(gomock.Any(), int64(123)).Return(user, nil)
+				m.attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				m.userSvc.EXPECT().ChangePassword(gomock.Any(), int64(123), "hello#world123", "hello#world456").Return(nil)
+				m.jwtHdl.EXPECT().RevokeAllSessions(gomock.Any(), int64(123)).Return(nil)
+				m.jwtHdl.EXPECT().SetLoginToken(gomock.Any(), int64(123), gomock.Any(), ijwt.LoginMethodPassword).Return(nil)
+			},
+			wantRes: Result{Msg: "修改成功"},
+		},
+		{
+			name: "旧密码错误，记一次失败",
+			mock: func(m mocks) {
+				m.userSvc.EXPECT().FindById(gomock.Any(), int64(123)).Return(user, nil)
+				m.attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				m.userSvc.EXPECT().ChangePassword(gomock.Any(), int64(123), "hello#world123", "hello#world456").
+					Return(service.ErrInvalidUserOrPassword)
+				m.attemptSvc.EXPECT().Fail(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+			},
+			wantRes: Result{Code: 4, Msg: "旧密码错误"},
+		},
+		{
+			name: "旧密码错误，触发锁定",
+			mock: func(m mocks) {
+				m.userSvc.EXPECT().FindById(gomock.Any(), int64(123)).Return(user, nil)
+				m.attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				m.userSvc.EXPECT().ChangePassword(gomock.Any(), int64(123), "hello#world123", "hello#world456").
+					Return(service.ErrInvalidUserOrPassword)
+				m.attemptSvc.EXPECT().Fail(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Minute*15, nil)
+			},
+			wantRes: Result{Code: codeLoginLocked, Msg: "登录失败次数太多，请 15 分钟后再试"},
+		},
+		{
+			name: "已经被锁定了，不再校验旧密码",
+			mock: func(m mocks) {
+				m.userSvc.EXPECT().FindById(gomock.Any(), int64(123)).Return(user, nil)
+				m.attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(time.Minute, service.ErrLoginLocked)
+			},
+			wantRes: Result{Code: codeLoginLocked, Msg: "登录失败次数太多，请 1 分钟后再试"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			m := mocks{
+				userSvc:    svcmocks.NewMockUserService(ctrl),
+				attemptSvc: svcmocks.NewMockLoginAttemptService(ctrl),
+				jwtHdl:     jwtmocks.NewMockHandler(ctrl),
+			}
+			tc.mock(m)
+			hdl := NewUserHandler(m.userSvc, m.jwtHdl, nil, nil, nil, nil, m.attemptSvc)
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 123})
+			})
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/password/change", bytes.NewBufferString(reqBody))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"webook/internal/domain"
+	"webook/internal/service"
+	ijwt "webook/internal/web/jwt"
+)
+
+// twoFactorChallenge 开启了两步验证，先不发 token，让前端带着挑战 token 来输入验证码
+func (h *UserHandler) twoFactorChallenge(ctx *gin.Context, uid int64) {
+	token, err := h.NewChallengeToken(uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Code: codeTwoFactorRequired,
+		Msg:  "请输入两步验证的验证码",
+		Data: TwoFactorChallengeVO{ChallengeToken: token},
+	})
+}
+
+// LoginTOTP 两步登录的第二步，code 可以是验证码，也可以是恢复码
+func (h *UserHandler) LoginTOTP(ctx *gin.Context) {
+	type Req struct {
+		ChallengeToken string `json:"challengeToken"`
+		Code           string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	cc, err := h.VerifyChallengeToken(ctx, req.ChallengeToken)
+	if err != nil {
+		// 过期了、试太多次了或者是伪造的，都要重新输入密码
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "登录已失效，请重新登录",
+		})
+		return
+	}
+	// 输入验证码的时候可能被封禁了，角色也重新查一遍
+	u, err := h.svc.FindById(ctx, cc.Uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	// 和密码共用失败次数，不然拿到密码之后可以不停地换挑战 token 来猜验证码
+	account := attemptAccount(u)
+	if h.loginLocked(ctx, account) {
+		return
+	}
+	err = h.totpSvc.Verify(ctx, cc.Uid, req.Code)
+	if err == service.ErrInvalidTOTPCode {
+		if h.loginFailed(ctx, account) {
+			return
+		}
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "验证码错误",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if u.Status == domain.UserStatusBanned {
+		ctx.JSON(http.StatusOK, bannedResult)
+		return
+	}
+	err = h.FinishChallenge(ctx, cc)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	h.loginSucceeded(ctx, account)
+	err = h.SetLoginToken(ctx, u.Id, u.Roles, ijwt.LoginMethodPassword)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Msg: "登录成功"})
+}
+
+// EnrollTOTP 返回 otpauth URI，前端转成二维码给验证器 App 扫描
+func (h *UserHandler) EnrollTOTP(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	uri, err := h.totpSvc.Enroll(ctx, uc.Uid)
+	if err == service.ErrTOTPEnabled {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "已经开启了两步验证",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: TOTPEnrollVO{URI: uri},
+	})
+}
+
+// EnableTOTP 扫码之后输入一次验证码，确认验证器能用了再启用
+func (h *UserHandler) EnableTOTP(ctx *gin.Context) {
+	type Req struct {
+		Code string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	account, ok := h.totpAttemptCheck(ctx, uc.Uid)
+	if !ok {
+		return
+	}
+	codes, err := h.totpSvc.Enable(ctx, uc.Uid, req.Code)
+	switch err {
+	case nil:
+		h.loginSucceeded(ctx, account)
+		ctx.JSON(http.StatusOK, Result{
+			Msg:  "开启成功，请妥善保存恢复码",
+			Data: codes,
+		})
+	case service.ErrInvalidTOTPCode:
+		if h.loginFailed(ctx, account) {
+			return
+		}
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "验证码错误",
+		})
+	case service.ErrTOTPNotEnrolled:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "请先绑定验证器",
+		})
+	case service.ErrTOTPEnabled:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "已经开启了两步验证",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// DisableTOTP 关闭两步验证，验证码错误和登录共用失败次数，拿到会话也不能暴力猜验证码
+func (h *UserHandler) DisableTOTP(ctx *gin.Context) {
+	type Req struct {
+		Code string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	account, ok := h.totpAttemptCheck(ctx, uc.Uid)
+	if !ok {
+		return
+	}
+	err := h.totpSvc.Disable(ctx, uc.Uid, req.Code)
+	switch err {
+	case nil:
+		h.loginSucceeded(ctx, account)
+		ctx.JSON(http.StatusOK, Result{Msg: "已关闭两步验证"})
+	case service.ErrInvalidTOTPCode:
+		if h.loginFailed(ctx, account) {
+			return
+		}
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "验证码错误",
+		})
+	case service.ErrTOTPNotEnabled:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "没有开启两步验证",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// totpAttemptCheck 查出用户记失败次数用的账号，被锁定了就直接返回
+func (h *UserHandler) totpAttemptCheck(ctx *gin.Context, uid int64) (string, bool) {
+	u, err := h.svc.FindById(ctx, uid)
+	if err != nil {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return "", false
+	}
+	account := attemptAccount(u)
+	if h.loginLocked(ctx, account) {
+		return "", false
+	}
+	return account, true
+}
@@ -0,0 +1,321 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"webook/internal/domain"
+	"webook/internal/service"
+	svcmocks "webook/internal/service/mocks"
+	ijwt "webook/internal/web/jwt"
+	jwtmocks "webook/internal/web/jwt/mocks"
+)
+
+func TestUserHandler_LoginJWT_TwoFactor(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler)
+
+		wantBody string
+	}{
+		{
+			name: "没有开启两步验证",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Login(gomock.Any(), "123@qq.com", "hello#world123").
+					Return(domain.User{Id: 123}, nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Enabled(gomock.Any(), int64(123)).Return(false, nil)
+				attemptSvc.EXPECT().Succeed(gomock.Any(), "123@qq.com").Return(nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().SetLoginToken(gomock.Any(), int64(123), []string(nil), ijwt.LoginMethodPassword).
+					Return(nil)
+				return userSvc, totpSvc, attemptSvc, hdl
+			},
+			wantBody: "登录成功",
+		},
+		{
+			name: "开启了两步验证",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Login(gomock.Any(), "123@qq.com", "hello#world123").
+					Return(domain.User{Id: 123}, nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Enabled(gomock.Any(), int64(123)).Return(true, nil)
+				// 不会发真正的 token，失败次数也还不能清空
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().NewChallengeToken(int64(123)).Return("challenge-token", nil)
+				return userSvc, totpSvc, attemptSvc, hdl
+			},
+			wantBody: `{"code":7,"msg":"请输入两步验证的验证码","data":{"challengeToken":"challenge-token"}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, totpSvc, attemptSvc, jwtHdl := tc.mock(ctrl)
+			hdl := NewUserHandler(userSvc, jwtHdl, nil, nil, nil, totpSvc, attemptSvc)
+			server := gin.Default()
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/login",
+				bytes.NewBufferString(`{"email":"123@qq.com","password":"hello#world123"}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_LoginTOTP(t *testing.T) {
+	cc := ijwt.ChallengeClaims{Uid: 123}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler)
+
+		wantBody string
+	}{
+		{
+			name: "登录成功",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().VerifyChallengeToken(gomock.Any(), "challenge-token").Return(cc, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "123@qq.com", Roles: []string{domain.RoleAdmin}}, nil)
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Verify(gomock.Any(), int64(123), "123456").Return(nil)
+				hdl.EXPECT().FinishChallenge(gomock.Any(), cc).Return(nil)
+				attemptSvc.EXPECT().Succeed(gomock.Any(), "123@qq.com").Return(nil)
+				hdl.EXPECT().SetLoginToken(gomock.Any(), int64(123), []string{domain.RoleAdmin},
+					ijwt.LoginMethodPassword).Return(nil)
+				return userSvc, totpSvc, attemptSvc, hdl
+			},
+			wantBody: `{"code":0,"msg":"登录成功","data":null}`,
+		},
+		{
+			name: "挑战 token 失效",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().VerifyChallengeToken(gomock.Any(), "challenge-token").
+					Return(ijwt.ChallengeClaims{}, ijwt.ErrChallengeExhausted)
+				return svcmocks.NewMockUserService(ctrl), svcmocks.NewMockTOTPService(ctrl),
+					svcmocks.NewMockLoginAttemptService(ctrl), hdl
+			},
+			wantBody: `{"code":4,"msg":"登录已失效，请重新登录","data":null}`,
+		},
+		{
+			name: "验证码错误",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().VerifyChallengeToken(gomock.Any(), "challenge-token").Return(cc, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Verify(gomock.Any(), int64(123), "123456").Return(service.ErrInvalidTOTPCode)
+				attemptSvc.EXPECT().Fail(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				return userSvc, totpSvc, attemptSvc, hdl
+			},
+			wantBody: `{"code":4,"msg":"验证码错误","data":null}`,
+		},
+		{
+			name: "验证码错误太多次被锁定",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().VerifyChallengeToken(gomock.Any(), "challenge-token").Return(cc, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Verify(gomock.Any(), int64(123), "123456").Return(service.ErrInvalidTOTPCode)
+				attemptSvc.EXPECT().Fail(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Minute, nil)
+				return userSvc, totpSvc, attemptSvc, hdl
+			},
+			wantBody: `{"code":8,"msg":"登录失败次数太多，请 1 分钟后再试","data":null}`,
+		},
+		{
+			name: "已经被锁定了",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().VerifyChallengeToken(gomock.Any(), "challenge-token").Return(cc, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(time.Minute*2, service.ErrLoginLocked)
+				return userSvc, svcmocks.NewMockTOTPService(ctrl), attemptSvc, hdl
+			},
+			wantBody: `{"code":8,"msg":"登录失败次数太多，请 2 分钟后再试","data":null}`,
+		},
+		{
+			name: "账号已被封禁",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().VerifyChallengeToken(gomock.Any(), "challenge-token").Return(cc, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "123@qq.com", Status: domain.UserStatusBanned}, nil)
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Verify(gomock.Any(), int64(123), "123456").Return(nil)
+				return userSvc, totpSvc, attemptSvc, hdl
+			},
+			wantBody: `{"code":6,"msg":"账号已被封禁","data":null}`,
+		},
+		{
+			name: "系统错误",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService, ijwt.Handler) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().VerifyChallengeToken(gomock.Any(), "challenge-token").Return(cc, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Verify(gomock.Any(), int64(123), "123456").Return(errors.New("db 错误"))
+				return userSvc, totpSvc, attemptSvc, hdl
+			},
+			wantBody: `{"code":5,"msg":"系统错误","data":null}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, totpSvc, attemptSvc, jwtHdl := tc.mock(ctrl)
+			hdl := NewUserHandler(userSvc, jwtHdl, nil, nil, nil, totpSvc, attemptSvc)
+			server := gin.Default()
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/login/2fa",
+				bytes.NewBufferString(`{"challengeToken":"challenge-token","code":"123456"}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_DisableTOTP(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService)
+
+		wantBody string
+	}{
+		{
+			name: "关闭成功",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Disable(gomock.Any(), int64(123), "123456").Return(nil)
+				attemptSvc.EXPECT().Succeed(gomock.Any(), "123@qq.com").Return(nil)
+				return userSvc, totpSvc, attemptSvc
+			},
+			wantBody: `{"code":0,"msg":"已关闭两步验证","data":null}`,
+		},
+		{
+			name: "验证码错误，记一次失败",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Disable(gomock.Any(), int64(123), "123456").Return(service.ErrInvalidTOTPCode)
+				attemptSvc.EXPECT().Fail(gomock.Any(), "123@qq.com", gomock.Any()).Return(time.Duration(0), nil)
+				return userSvc, totpSvc, attemptSvc
+			},
+			wantBody: `{"code":4,"msg":"验证码错误","data":null}`,
+		},
+		{
+			name: "没有邮箱的用户按 uid 锁定",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Phone: "15212345678"}, nil)
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "uid:123", gomock.Any()).Return(time.Duration(0), nil)
+				totpSvc := svcmocks.NewMockTOTPService(ctrl)
+				totpSvc.EXPECT().Disable(gomock.Any(), int64(123), "123456").Return(service.ErrInvalidTOTPCode)
+				attemptSvc.EXPECT().Fail(gomock.Any(), "uid:123", gomock.Any()).Return(time.Minute, nil)
+				return userSvc, totpSvc, attemptSvc
+			},
+			wantBody: `{"code":8,"msg":"登录失败次数太多，请 1 分钟后再试","data":null}`,
+		},
+		{
+			name: "已经被锁定了",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.TOTPService, service.LoginAttemptService) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().FindById(gomock.Any(), int64(123)).
+					Return(domain.User{Id: 123, Email: "123@qq.com"}, nil)
+				attemptSvc := svcmocks.NewMockLoginAttemptService(ctrl)
+				attemptSvc.EXPECT().Check(gomock.Any(), "123@qq.com", gomock.Any()).
+					Return(time.Minute*2, service.ErrLoginLocked)
+				return userSvc, svcmocks.NewMockTOTPService(ctrl), attemptSvc
+			},
+			wantBody: `{"code":8,"msg":"登录失败次数太多，请 2 分钟后再试","data":null}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userSvc, totpSvc, attemptSvc := tc.mock(ctrl)
+			hdl := NewUserHandler(userSvc, jwtmocks.NewMockHandler(ctrl), nil, nil, nil, totpSvc, attemptSvc)
+			server := gin.Default()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Uid: 123})
+			})
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(http.MethodPost, "/users/2fa/totp/disable",
+				bytes.NewBufferString(`{"code":"123456"}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
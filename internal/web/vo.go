@@ -0,0 +1,91 @@
+package web
+
+type ArticleVO struct {
+	Id       int64  `json:"id"`
+	Title    string `json:"title"`
+	Abstract string `json:"abstract"`
+	Content  string `json:"content"`
+	AuthorId int64  `json:"authorId"`
+	Status   uint8  `json:"status"`
+
+	ReadCnt    int64 `json:"readCnt"`
+	LikeCnt    int64 `json:"likeCnt"`
+	CollectCnt int64 `json:"collectCnt"`
+	Liked      bool  `json:"liked"`
+	Collected  bool  `json:"collected"`
+
+	Ctime string `json:"ctime"`
+	Utime string `json:"utime"`
+}
+
+type ListReq struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+type SessionVO struct {
+	Ssid        string `json:"ssid"`
+	UserAgent   string `json:"userAgent"`
+	IP          string `json:"ip"`
+	LoginTime   string `json:"loginTime"`
+	LoginMethod string `json:"loginMethod"`
+	// Current 是不是当前正在用的这个设备
+	Current bool `json:"current"`
+}
+
+// UserVO 管理后台看到的用户信息
+type UserVO struct {
+	Id       int64    `json:"id"`
+	Email    string   `json:"email"`
+	Phone    string   `json:"phone"`
+	NickName string   `json:"nickName"`
+	Roles    []string `json:"roles"`
+	Status   uint8    `json:"status"`
+}
+
+// UserMergeVO 合并账号的时候搬过来了哪些数据
+type UserMergeVO struct {
+	Email    bool  `json:"email"`
+	Phone    bool  `json:"phone"`
+	Wechat   bool  `json:"wechat"`
+	Articles int64 `json:"articles"`
+	Likes    int64 `json:"likes"`
+	Collects int64 `json:"collects"`
+}
+
+// UserArchiveVO 导出的个人数据
+type UserArchiveVO struct {
+	Profile  UserProfileVO   `json:"profile"`
+	Articles []ArticleVO     `json:"articles"`
+	Likes    []InteractionVO `json:"likes"`
+	Collects []InteractionVO `json:"collects"`
+	Ctime    string          `json:"ctime"`
+}
+
+type UserProfileVO struct {
+	Id            int64  `json:"id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"emailVerified"`
+	Phone         string `json:"phone"`
+	NickName      string `json:"nickName"`
+	Birthday      string `json:"birthday"`
+	AboutMe       string `json:"aboutMe"`
+	// WechatBound 微信的 openid 对用户没有意义，只告诉用户绑定了没有
+	WechatBound bool `json:"wechatBound"`
+}
+
+// InteractionVO 点赞或者收藏过的资源
+type InteractionVO struct {
+	Biz   string `json:"biz"`
+	BizId int64  `json:"bizId"`
+	Ctime string `json:"ctime"`
+}
+
+type TwoFactorChallengeVO struct {
+	ChallengeToken string `json:"challengeToken"`
+}
+
+type TOTPEnrollVO struct {
+	// URI otpauth://totp/... 里面带着密钥，也可以手动输入到验证器里面
+	URI string `json:"uri"`
+}
@@ -6,9 +6,11 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	uuid "github.com/lithammer/shortuuid/v4"
 	"net/http"
+	"webook/internal/domain"
 	"webook/internal/service"
 	"webook/internal/service/auth2/wechat"
 	ijwt "webook/internal/web/jwt"
+	"webook/internal/web/middleware"
 )
 
 type OAuth2WechatHandler struct {
@@ -31,17 +33,38 @@ func NewOAuth2WechatHandler(svc wechat.Service, hdl ijwt.Handler, userSvc servic
 func (o *OAuth2WechatHandler) RegisterRoutes(server *gin.Engine) {
 	g := server.Group("/oauth2/wechat")
 	g.GET("/authurl", o.Auth2URL)
+	// 已经登录的用户绑定微信，要登录才能访问
+	g.GET("/bind/authurl", o.BindAuthURL)
 	g.Any("/callback", o.Callback)
 }
 
+func (o *OAuth2WechatHandler) AuthPolicy() middleware.AuthPolicy {
+	return middleware.AuthPolicy{
+		Ignore: []string{
+			"/oauth2/wechat/authurl",
+			"/oauth2/wechat/callback",
+		},
+	}
+}
+
 func (o *OAuth2WechatHandler) Auth2URL(ctx *gin.Context) {
+	o.authURL(ctx, 0)
+}
+
+func (o *OAuth2WechatHandler) BindAuthURL(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	o.authURL(ctx, uc.Uid)
+}
+
+// authURL uid 大于 0 说明是绑定，回调的时候绑定到这个用户上
+func (o *OAuth2WechatHandler) authURL(ctx *gin.Context, uid int64) {
 	state := uuid.New()
 	val, err := o.svc.AuthURL(ctx, state)
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{Msg: "构造跳转URL失败", Code: 5})
 		return
 	}
-	err = o.setStateCookie(ctx, state)
+	err = o.setStateCookie(ctx, state, uid)
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Code: 5,
@@ -52,7 +75,7 @@ func (o *OAuth2WechatHandler) Auth2URL(ctx *gin.Context) {
 }
 
 func (o *OAuth2WechatHandler) Callback(ctx *gin.Context) {
-	err := o.verifyState(ctx)
+	sc, err := o.verifyState(ctx)
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Msg:  "非法请求",
@@ -69,8 +92,19 @@ func (o *OAuth2WechatHandler) Callback(ctx *gin.Context) {
 		})
 		return
 	}
+	if sc.Uid > 0 {
+		o.bind(ctx, sc.Uid, wechatInfo)
+		return
+	}
 	u, err := o.userSvc.FindOrCreateByWeChat(ctx, wechatInfo)
-
+	if err == service.ErrUserBanned {
+		ctx.JSON(http.StatusOK, bannedResult)
+		return
+	}
+	if err == service.ErrUserStatusFrozen {
+		ctx.JSON(http.StatusOK, frozenResult)
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Msg:  "系统错误",
@@ -78,7 +112,7 @@ func (o *OAuth2WechatHandler) Callback(ctx *gin.Context) {
 		})
 		return
 	}
-	err = o.SetLoginToken(ctx, u.Id)
+	err = o.SetLoginToken(ctx, u.Id, u.Roles, ijwt.LoginMethodWechat)
 	if err != nil {
 		ctx.String(http.StatusOK, "系统错误")
 		return
@@ -89,11 +123,29 @@ func (o *OAuth2WechatHandler) Callback(ctx *gin.Context) {
 	return
 }
 
-func (o *OAuth2WechatHandler) verifyState(ctx *gin.Context) error {
+func (o *OAuth2WechatHandler) bind(ctx *gin.Context, uid int64, info domain.WechatInfo) {
+	err := o.userSvc.BindWechat(ctx, uid, info)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{Msg: "绑定成功"})
+	case service.ErrIdentityConflict:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "该微信已经绑定了别的账号",
+		})
+	default:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+	}
+}
+
+func (o *OAuth2WechatHandler) verifyState(ctx *gin.Context) (StateClaims, error) {
 	state := ctx.Query("state")
 	ck, err := ctx.Cookie(o.stateCookieName)
 	if err != nil {
-		return fmt.Errorf("无法获得 Cookie %w", err)
+		return StateClaims{}, fmt.Errorf("无法获得 Cookie %w", err)
 
 	}
 	var sc StateClaims
@@ -101,17 +153,18 @@ func (o *OAuth2WechatHandler) verifyState(ctx *gin.Context) error {
 		return o.key, nil
 	})
 	if err != nil {
-		return fmt.Errorf("解析 Token 失败 %w", err)
+		return StateClaims{}, fmt.Errorf("解析 Token 失败 %w", err)
 	}
 	if state != sc.State {
-		return fmt.Errorf("state 不匹配")
+		return StateClaims{}, fmt.Errorf("state 不匹配")
 	}
-	return nil
+	return sc, nil
 }
 
-func (o *OAuth2WechatHandler) setStateCookie(ctx *gin.Context, state string) error {
+func (o *OAuth2WechatHandler) setStateCookie(ctx *gin.Context, state string, uid int64) error {
 	claims := StateClaims{
 		State: state,
+		Uid:   uid,
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
 
@@ -127,4 +180,6 @@ func (o *OAuth2WechatHandler) setStateCookie(ctx *gin.Context, state string) err
 type StateClaims struct {
 	jwt.RegisteredClaims
 	State string
+	// Uid 绑定微信的用户，登录的时候是 0
+	Uid int64
 }
@@ -0,0 +1,33 @@
+package ioc
+
+import (
+	"github.com/spf13/viper"
+	"os"
+	"webook/internal/service/email"
+	"webook/internal/service/email/localemail"
+	"webook/internal/service/email/smtp"
+)
+
+func InitEmailService() email.Service {
+	return localemail.NewService()
+	//return initSMTPEmailService()
+}
+
+func initSMTPEmailService() email.Service {
+	type Config struct {
+		Host     string `yaml:"host"`
+		Port     int    `yaml:"port"`
+		Username string `yaml:"username"`
+		From     string `yaml:"from"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("email", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	password, ok := os.LookupEnv("EMAIL_PASSWORD")
+	if !ok {
+		panic("找不到邮箱的密码")
+	}
+	return smtp.NewService(cfg.Host, cfg.Port, cfg.Username, password, cfg.From)
+}
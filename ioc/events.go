@@ -0,0 +1,16 @@
+package ioc
+
+import (
+	"webook/internal/events"
+	"webook/internal/events/article"
+	"webook/pkg/logger"
+)
+
+// InitMemoryBroker 本地先用进程内的实现，上 Kafka 的时候替换掉这里就可以
+func InitMemoryBroker(l logger.LoggerV1) *events.MemoryBroker {
+	return events.NewMemoryBroker(1024, l)
+}
+
+func InitConsumers(c1 *article.InteractiveReadEventBatchConsumer) []events.Consumer {
+	return []events.Consumer{c1}
+}
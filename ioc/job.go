@@ -0,0 +1,42 @@
+package ioc
+
+import (
+	"github.com/redis/go-redis/v9"
+	"github.com/robfig/cron/v3"
+	"time"
+	"webook/internal/job"
+	"webook/internal/service"
+	"webook/pkg/logger"
+	"webook/pkg/rlock"
+)
+
+func InitRLockClient(client redis.Cmdable) *rlock.Client {
+	return rlock.NewClient(client)
+}
+
+func InitRankingJob(svc service.RankingService, client *rlock.Client, l logger.LoggerV1) *job.RankingJob {
+	return job.NewRankingJob(svc, client, l, time.Second*30)
+}
+
+func InitJobs(l logger.LoggerV1, rjob *job.RankingJob) *cron.Cron {
+	builder := job.NewCronJobBuilder(l)
+	expr := cron.New(cron.WithSeconds())
+	// 每三分钟计算一次热榜
+	_, err := expr.AddJob("0 */3 * * * ?", builder.Build(rjob))
+	if err != nil {
+		panic(err)
+	}
+	return expr
+}
+
+// InitLocalFuncExecutor 需要定时执行的本地方法都在这里注册，
+// 再往 jobs 表里面插入一条 executor 为 local 的任务
+func InitLocalFuncExecutor() *job.LocalFuncExecutor {
+	return job.NewLocalFuncExecutor()
+}
+
+func InitScheduler(svc service.CronJobService, local *job.LocalFuncExecutor, l logger.LoggerV1) *job.Scheduler {
+	scheduler := job.NewScheduler(svc, l)
+	scheduler.RegisterExecutor(local)
+	return scheduler
+}
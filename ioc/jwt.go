@@ -0,0 +1,46 @@
+package ioc
+
+import (
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	ijwt "webook/internal/web/jwt"
+)
+
+type jwtConfig struct {
+	// SigningMethod 长 token 的签名算法，HS512、RS256 或者 EdDSA
+	SigningMethod string `yaml:"signingMethod"`
+	// AccessKeys 第一个是当前签名用的密钥，后面的是轮换之前的旧密钥
+	AccessKeys  []ijwt.Key `yaml:"accessKeys"`
+	RefreshKeys []ijwt.Key `yaml:"refreshKeys"`
+}
+
+// InitJWTHandler 密钥只在启动的时候加载一次，轮换密钥要改配置之后重启
+func InitJWTHandler(cmd redis.Cmdable) ijwt.Handler {
+	c := jwtConfig{
+		SigningMethod: jwt.SigningMethodHS512.Alg(),
+	}
+	err := viper.UnmarshalKey("jwt", &c)
+	if err != nil {
+		panic(fmt.Errorf("JWT初始化配置失败，错误信息:%v", err))
+	}
+	method := jwt.GetSigningMethod(c.SigningMethod)
+	if method == nil {
+		panic(fmt.Errorf("JWT不支持的签名算法:%s", c.SigningMethod))
+	}
+	accessKeys, err := ijwt.NewKeyRing(c.AccessKeys...)
+	if err != nil {
+		panic(fmt.Errorf("JWT初始化长 token 密钥失败，错误信息:%v", err))
+	}
+	refreshKeys, err := ijwt.NewKeyRing(c.RefreshKeys...)
+	if err != nil {
+		panic(fmt.Errorf("JWT初始化刷新 token 密钥失败，错误信息:%v", err))
+	}
+	// 启动的时候就确认密钥和签名算法是匹配的，免得登录的时候才发现
+	_, err = accessKeys.Sign(method, jwt.RegisteredClaims{})
+	if err != nil {
+		panic(fmt.Errorf("JWT长 token 密钥和签名算法 %s 不匹配，错误信息:%v", c.SigningMethod, err))
+	}
+	return ijwt.NewRedisJWTHandler(cmd, method, accessKeys, refreshKeys)
+}
@@ -0,0 +1,44 @@
+package ioc
+
+import (
+	"context"
+	"fmt"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"webook/internal/repository"
+	"webook/internal/repository/cache"
+	"webook/internal/repository/dao"
+	"webook/pkg/logger"
+)
+
+// InitUserCache 本地缓存加 redis 缓存，并且监听其它实例发出的失效通知
+func InitUserCache(cmd redis.Cmdable, l logger.LoggerV1) cache.UserCache {
+	local, err := lru.New(10000)
+	if err != nil {
+		panic(err)
+	}
+	c := cache.NewLocalUserCache(cache.NewUserCache(cmd), cmd, local, l)
+	go func() {
+		err := c.Subscribe(context.Background())
+		if err != nil {
+			l.Error("订阅用户缓存失效通知失败", logger.Error(err))
+		}
+	}()
+	return c
+}
+
+func InitUserRepository(d dao.UserDAO, c cache.UserCache) repository.UserRepository {
+	type Config struct {
+		// DBLoadThreshold 缓存不可用的时候，同时查询数据库的请求数上限
+		DBLoadThreshold int64 `yaml:"dbLoadThreshold"`
+	}
+	c1 := Config{
+		DBLoadThreshold: 100,
+	}
+	err := viper.UnmarshalKey("user", &c1)
+	if err != nil {
+		panic(fmt.Errorf("用户模块初始化配置失败，错误信息:%v", err))
+	}
+	return repository.NewCachedUserRepositoryV1(d, c, c1.DBLoadThreshold)
+}
@@ -5,8 +5,10 @@ import (
 	"github.com/gin-gonic/contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
 	"strings"
 	"time"
+	"webook/internal/service"
 	"webook/internal/web"
 	ijwt "webook/internal/web/jwt"
 	"webook/internal/web/middleware"
@@ -15,16 +17,21 @@ import (
 	"webook/pkg/logger"
 )
 
-func InitWebServer(mdls []gin.HandlerFunc, userHdl *web.UserHandler, artHdl *web.ArticleHandler, wechat *web.OAuth2WechatHandler) *gin.Engine {
+func InitWebServer(mdls []gin.HandlerFunc, userHdl *web.UserHandler, artHdl *web.ArticleHandler,
+	wechat *web.OAuth2WechatHandler, jwksHdl *web.JWKSHandler, adminHdl *web.AdminHandler) *gin.Engine {
 	server := gin.Default()
 	server.Use(mdls...)
 	userHdl.RegisterRoutes(server)
 	wechat.RegisterRoutes(server)
 	artHdl.RegisterRoutes(server)
+	jwksHdl.RegisterRoutes(server)
+	adminHdl.RegisterRoutes(server)
 	return server
 }
 
-func InitGinMiddlewares(redisClient redis.Cmdable, hdl ijwt.Handler, log logger.LoggerV1) []gin.HandlerFunc {
+func InitGinMiddlewares(redisClient redis.Cmdable, hdl ijwt.Handler, log logger.LoggerV1,
+	userSvc service.UserService, userHdl *web.UserHandler, artHdl *web.ArticleHandler,
+	wechat *web.OAuth2WechatHandler, jwksHdl *web.JWKSHandler) []gin.HandlerFunc {
 	return []gin.HandlerFunc{
 		cors.New(cors.Config{
 			AllowCredentials: true,
@@ -43,6 +50,9 @@ func InitGinMiddlewares(redisClient redis.Cmdable, hdl ijwt.Handler, log logger.
 		middleware.NewLogMiddlewareBuilder(func(ctx context.Context, al middleware.AccessLog) {
 			log.Debug("", logger.Field{Key: "req", Value: al})
 		}).AllowReqBody().AllowRespBody().Build(),
-		middleware.NewLoginJWTMiddlewareBuilder(hdl).CheckLogin(),
+		middleware.NewLoginJWTMiddlewareBuilder(hdl).
+			Declare(userHdl, artHdl, wechat, jwksHdl).
+			SlidingRenewal(viper.GetDuration("jwt.renewWindow"), userSvc).
+			CheckLogin(),
 	}
 }
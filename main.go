@@ -1,17 +1,51 @@
 package main
 
 import (
+	"context"
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	_ "github.com/spf13/viper/remote"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
 	initViperRemote()
-	server := InitWebServer()
-	server.Run(viper.GetString("server.port"))
+	app := InitApp()
+	for _, c := range app.consumers {
+		err := c.Start()
+		if err != nil {
+			panic(err)
+		}
+	}
+	app.cron.Start()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		err := app.scheduler.Schedule(ctx)
+		if err != nil && err != context.Canceled {
+			log.Println("任务调度退出", err)
+		}
+	}()
+	go func() {
+		err := app.server.Run(viper.GetString("server.port"))
+		if err != nil {
+			panic(err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	cancel()
+	// 等正在执行的任务结束，再释放分布式锁，别的实例就能马上接手
+	<-app.cron.Stop().Done()
+	err := app.rankingJob.Close()
+	if err != nil {
+		log.Println("释放热榜任务的分布式锁失败", err)
+	}
 }
 
 func initViper() {
@@ -0,0 +1,6 @@
+-- 只有锁还是自己的，才能释放，避免删掉别人的锁
+if redis.call("get", KEYS[1]) == ARGV[1] then
+    return redis.call("del", KEYS[1])
+else
+    return 0
+end
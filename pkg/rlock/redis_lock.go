@@ -0,0 +1,114 @@
+package rlock
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"time"
+)
+
+var (
+	//go:embed lua/refresh.lua
+	luaRefresh string
+	//go:embed lua/unlock.lua
+	luaUnlock string
+
+	ErrFailedToPreemptLock = errors.New("抢锁失败")
+	// ErrLockNotHold 锁过期了，或者被别人拿走了
+	ErrLockNotHold = errors.New("未持有锁")
+)
+
+// Client 基于 Redis 的分布式锁
+type Client struct {
+	cmd redis.Cmdable
+}
+
+func NewClient(cmd redis.Cmdable) *Client {
+	return &Client{
+		cmd: cmd,
+	}
+}
+
+// TryLock 尝试加锁，锁被别人持有的时候返回 ErrFailedToPreemptLock
+func (c *Client) TryLock(ctx context.Context, key string, expiration time.Duration) (*Lock, error) {
+	val := uuid.New().String()
+	ok, err := c.cmd.SetNX(ctx, key, val, expiration).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrFailedToPreemptLock
+	}
+	return &Lock{
+		cmd:        c.cmd,
+		key:        key,
+		value:      val,
+		expiration: expiration,
+		unlock:     make(chan struct{}),
+	}, nil
+}
+
+type Lock struct {
+	cmd redis.Cmdable
+	key string
+	// value 用来标记这把锁是谁加的
+	value      string
+	expiration time.Duration
+
+	unlock     chan struct{}
+	unlockOnce sync.Once
+}
+
+func (l *Lock) Refresh(ctx context.Context) error {
+	res, err := l.cmd.Eval(ctx, luaRefresh, []string{l.key}, l.value, l.expiration.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if res != 1 {
+		return ErrLockNotHold
+	}
+	return nil
+}
+
+// AutoRefresh 每隔 interval 续约一次，直到调用 Unlock 或者续约失败。
+// 续约超时会重试，其它错误直接返回
+func (l *Lock) AutoRefresh(interval time.Duration, timeout time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	retry := make(chan struct{}, 1)
+	for {
+		select {
+		case <-ticker.C:
+		case <-retry:
+		case <-l.unlock:
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := l.Refresh(ctx)
+		cancel()
+		if err == context.DeadlineExceeded {
+			retry <- struct{}{}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (l *Lock) Unlock(ctx context.Context) error {
+	l.unlockOnce.Do(func() {
+		close(l.unlock)
+	})
+	res, err := l.cmd.Eval(ctx, luaUnlock, []string{l.key}, l.value).Int64()
+	if err != nil {
+		return err
+	}
+	if res != 1 {
+		return ErrLockNotHold
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package rlock
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+	"webook/internal/repository/cache/redismocks"
+)
+
+func TestClient_TryLock(t *testing.T) {
+	testCase := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) redis.Cmdable
+		key     string
+		wantErr error
+	}{
+		{
+			name: "加锁成功",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewBoolResult(true, nil)
+				cmd.EXPECT().SetNX(gomock.Any(), "job:ranking", gomock.Any(), time.Minute).Return(res)
+				return cmd
+			},
+			key: "job:ranking",
+		},
+		{
+			name: "锁被别人持有",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewBoolResult(false, nil)
+				cmd.EXPECT().SetNX(gomock.Any(), "job:ranking", gomock.Any(), time.Minute).Return(res)
+				return cmd
+			},
+			key:     "job:ranking",
+			wantErr: ErrFailedToPreemptLock,
+		},
+		{
+			name: "redis错误",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewBoolResult(false, errors.New("redis错误"))
+				cmd.EXPECT().SetNX(gomock.Any(), "job:ranking", gomock.Any(), time.Minute).Return(res)
+				return cmd
+			},
+			key:     "job:ranking",
+			wantErr: errors.New("redis错误"),
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			c := NewClient(tc.mock(ctrl))
+			l, err := c.TryLock(context.Background(), tc.key, time.Minute)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.key, l.key)
+			assert.NotEmpty(t, l.value)
+		})
+	}
+}
+
+func TestLock_Unlock(t *testing.T) {
+	testCase := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) redis.Cmdable
+		wantErr error
+	}{
+		{
+			name: "释放成功",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(1))
+				cmd.EXPECT().Eval(gomock.Any(), luaUnlock, []string{"job:ranking"}, []any{"value"}).Return(res)
+				return cmd
+			},
+		},
+		{
+			name: "锁不是自己的",
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				res := redis.NewCmd(context.Background())
+				res.SetVal(int64(0))
+				cmd.EXPECT().Eval(gomock.Any(), luaUnlock, []string{"job:ranking"}, []any{"value"}).Return(res)
+				return cmd
+			},
+			wantErr: ErrLockNotHold,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			l := &Lock{
+				cmd:        tc.mock(ctrl),
+				key:        "job:ranking",
+				value:      "value",
+				expiration: time.Minute,
+				unlock:     make(chan struct{}),
+			}
+			err := l.Unlock(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+			// 释放之后自动续约要退出
+			assert.NoError(t, l.AutoRefresh(time.Hour, time.Second))
+		})
+	}
+}
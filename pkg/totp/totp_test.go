@@ -0,0 +1,84 @@
+package totp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// rfcSecret RFC 6238 附录 B 里面 SHA1 用的密钥 "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateCode(t *testing.T) {
+	// RFC 6238 附录 B 的测试数据，取后 6 位
+	testCases := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+	for _, tc := range testCases {
+		code, err := GenerateCode(rfcSecret, Step(time.Unix(tc.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	testCases := []struct {
+		name string
+		code string
+		now  time.Time
+
+		wantStep int64
+		wantOk   bool
+	}{
+		{
+			name:     "当前周期",
+			code:     "050471",
+			now:      now,
+			wantStep: Step(now),
+			wantOk:   true,
+		},
+		{
+			name:     "手机慢了一个周期",
+			code:     "050471",
+			now:      now.Add(time.Second * 30),
+			wantStep: Step(now),
+			wantOk:   true,
+		},
+		{
+			name: "超出容忍范围",
+			code: "050471",
+			now:  now.Add(time.Minute * 2),
+		},
+		{
+			name: "验证码错误",
+			code: "123456",
+			now:  now,
+		},
+		{
+			name: "长度不对",
+			code: "50471",
+			now:  now,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tc.code, tc.now, 1)
+			assert.Equal(t, tc.wantOk, ok)
+			assert.Equal(t, tc.wantStep, step)
+		})
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("webook", "123@qq.com", rfcSecret)
+	assert.Equal(t, "otpauth://totp/webook:123@qq.com?algorithm=SHA1&digits=6&issuer=webook&period=30&secret="+rfcSecret, uri)
+}
@@ -3,46 +3,91 @@
 package main
 
 import (
-	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
+	"webook/internal/events"
+	"webook/internal/events/article"
 	"webook/internal/repository"
 	"webook/internal/repository/cache"
 	"webook/internal/repository/dao"
 	"webook/internal/service"
 	"webook/internal/web"
-	ijwt "webook/internal/web/jwt"
 	"webook/ioc"
 )
 
-func InitWebServer() *gin.Engine {
+var eventsSet = wire.NewSet(
+	ioc.InitMemoryBroker,
+	wire.Bind(new(events.Producer), new(*events.MemoryBroker)),
+	wire.Bind(new(events.Subscriber), new(*events.MemoryBroker)),
+	article.NewEventProducer,
+	article.NewInteractiveReadEventBatchConsumer,
+	ioc.InitConsumers,
+)
+
+func InitApp() *App {
 	wire.Build(
 		// 第三方依赖
 		ioc.InitDB, ioc.InitRedis,
 		ioc.InitLogger,
+		eventsSet,
 		// Dao 部分
 		dao.NewUserDAO,
+		dao.NewGORMUserMergeDAO,
+		dao.NewGORMUserAccountDAO,
+		dao.NewGORMUserTOTPDAO,
 		dao.NewArticleGORMDAO,
+		dao.NewGORMInteractiveDAO,
+		dao.NewGORMJobDAO,
 		// cache 部分
-		cache.NewCodeCache, cache.NewUserCache,
+		cache.NewCodeCache, ioc.InitUserCache,
+		cache.NewInteractiveRedisCache,
+		cache.NewLoginAttemptCache,
+		cache.NewRankingRedisCache, cache.NewRankingLocalCache,
 
 		// Repository 部分
-		repository.NewCachedUserRepository, repository.NewCodeRepository, repository.NewCachedArticleRepository,
+		ioc.InitUserRepository, repository.NewCodeRepository, repository.NewCachedArticleRepository,
+		repository.NewCachedUserMergeRepository,
+		repository.NewCachedUserAccountRepository,
+		repository.NewGORMUserTOTPRepository,
+		repository.NewLoginAttemptRepository,
+		repository.NewCachedInteractiveRepository,
+		repository.NewCachedRankingRepository,
+		repository.NewPreemptCronJobRepository,
 
 		// Service 部分
 		ioc.InitSMSService,
+		ioc.InitEmailService,
 		ioc.InitWechatService,
 		service.NewUserService,
+		service.NewUserMergeService,
+		service.NewUserAccountService,
+		service.NewTOTPService,
+		service.NewLoginAttemptService,
 		service.NewCodeService,
+		service.NewEmailCodeService,
 		service.NewArticleService,
+		service.NewInteractiveService,
+		service.NewBatchRankingService,
+		service.NewCronJobService,
 
 		// Handler 部分
 		web.NewUserHandler,
 		web.NewOAuth2WechatHandler,
-		ijwt.NewRedisJWTHandler,
+		ioc.InitJWTHandler,
 		web.NewArticleHandler,
+		web.NewJWKSHandler,
+		web.NewAdminHandler,
 
 		ioc.InitGinMiddlewares,
 		ioc.InitWebServer,
+
+		// Job 部分
+		ioc.InitRLockClient,
+		ioc.InitRankingJob,
+		ioc.InitJobs,
+		ioc.InitLocalFuncExecutor,
+		ioc.InitScheduler,
+
+		wire.Struct(new(App), "*"),
 	)
-	return gin.Default()
+	return new(App)
 }
@@ -7,13 +7,14 @@
 package main
 
 import (
-	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+	"webook/internal/events"
+	"webook/internal/events/article"
 	"webook/internal/repository"
 	"webook/internal/repository/cache"
 	"webook/internal/repository/dao"
 	"webook/internal/service"
 	"webook/internal/web"
-	"webook/internal/web/jwt"
 	"webook/ioc"
 )
 
@@ -23,27 +24,74 @@ import (
 
 // Injectors from wire.go:
 
-func InitWebServer() *gin.Engine {
+func InitApp() *App {
 	cmdable := ioc.InitRedis()
-	handler := jwt.NewRedisJWTHandler(cmdable)
 	loggerV1 := ioc.InitLogger()
-	v := ioc.InitGinMiddlewares(cmdable, handler, loggerV1)
+	handler := ioc.InitJWTHandler(cmdable)
 	db := ioc.InitDB(loggerV1)
 	userDAO := dao.NewUserDAO(db)
-	userCache := cache.NewUserCache(cmdable)
-	userRepository := repository.NewCachedUserRepository(userDAO, userCache)
+	userCache := ioc.InitUserCache(cmdable, loggerV1)
+	userRepository := ioc.InitUserRepository(userDAO, userCache)
 	userService := service.NewUserService(userRepository)
 	codeRedisCache := cache.NewCodeCache(cmdable)
 	codeRepository := repository.NewCodeRepository(codeRedisCache)
 	smsService := ioc.InitSMSService()
 	codeService := service.NewCodeService(codeRepository, smsService)
-	userHandler := web.NewUserHandler(userService, handler, codeService)
+	emailService := ioc.InitEmailService()
+	emailCodeService := service.NewEmailCodeService(codeRepository, emailService)
+	userAccountDAO := dao.NewGORMUserAccountDAO(db)
+	userAccountRepository := repository.NewCachedUserAccountRepository(userAccountDAO, userCache)
+	userAccountService := service.NewUserAccountService(userAccountRepository)
+	userTOTPDAO := dao.NewGORMUserTOTPDAO(db)
+	userTOTPRepository := repository.NewGORMUserTOTPRepository(userTOTPDAO)
+	totpService := service.NewTOTPService(userTOTPRepository, userRepository)
+	loginAttemptCache := cache.NewLoginAttemptCache(cmdable)
+	loginAttemptRepository := repository.NewLoginAttemptRepository(loginAttemptCache)
+	loginAttemptService := service.NewLoginAttemptService(loginAttemptRepository)
+	userHandler := web.NewUserHandler(userService, handler, codeService, emailCodeService, userAccountService, totpService, loginAttemptService)
 	articleDAO := dao.NewArticleGORMDAO(db)
-	articleRepository := repository.NewCachedArticleRepository(articleDAO)
+	articleRepository := repository.NewCachedArticleRepository(articleDAO, db)
 	articleService := service.NewArticleService(articleRepository)
-	articleHandler := web.NewArticleHandler(loggerV1, articleService)
+	interactiveDAO := dao.NewGORMInteractiveDAO(db)
+	interactiveCache := cache.NewInteractiveRedisCache(cmdable)
+	interactiveRepository := repository.NewCachedInteractiveRepository(interactiveDAO, interactiveCache, loggerV1)
+	interactiveService := service.NewInteractiveService(interactiveRepository)
+	rankingRedisCache := cache.NewRankingRedisCache(cmdable)
+	rankingLocalCache := cache.NewRankingLocalCache()
+	rankingRepository := repository.NewCachedRankingRepository(rankingRedisCache, rankingLocalCache)
+	rankingService := service.NewBatchRankingService(articleService, interactiveService, rankingRepository)
+	memoryBroker := ioc.InitMemoryBroker(loggerV1)
+	producer := article.NewEventProducer(memoryBroker)
+	articleHandler := web.NewArticleHandler(loggerV1, articleService, interactiveService, rankingService, producer)
 	wechatService := ioc.InitWechatService(loggerV1)
 	oAuth2WechatHandler := web.NewOAuth2WechatHandler(wechatService, handler, userService)
-	engine := ioc.InitWebServer(v, userHandler, articleHandler, oAuth2WechatHandler)
-	return engine
+	jwksHandler := web.NewJWKSHandler(handler)
+	userMergeDAO := dao.NewGORMUserMergeDAO(db)
+	userMergeRepository := repository.NewCachedUserMergeRepository(userMergeDAO, userCache, interactiveCache)
+	userMergeService := service.NewUserMergeService(userMergeRepository)
+	adminHandler := web.NewAdminHandler(userService, articleService, userMergeService, handler, loggerV1)
+	v := ioc.InitGinMiddlewares(cmdable, handler, loggerV1, userService, userHandler, articleHandler, oAuth2WechatHandler, jwksHandler)
+	engine := ioc.InitWebServer(v, userHandler, articleHandler, oAuth2WechatHandler, jwksHandler, adminHandler)
+	interactiveReadEventBatchConsumer := article.NewInteractiveReadEventBatchConsumer(memoryBroker, interactiveRepository, loggerV1)
+	v2 := ioc.InitConsumers(interactiveReadEventBatchConsumer)
+	client := ioc.InitRLockClient(cmdable)
+	rankingJob := ioc.InitRankingJob(rankingService, client, loggerV1)
+	cron := ioc.InitJobs(loggerV1, rankingJob)
+	jobDAO := dao.NewGORMJobDAO(db)
+	cronJobRepository := repository.NewPreemptCronJobRepository(jobDAO)
+	cronJobService := service.NewCronJobService(cronJobRepository, loggerV1)
+	localFuncExecutor := ioc.InitLocalFuncExecutor()
+	scheduler := ioc.InitScheduler(cronJobService, localFuncExecutor, loggerV1)
+	app := &App{
+		server:     engine,
+		consumers:  v2,
+		cron:       cron,
+		rankingJob: rankingJob,
+		scheduler:  scheduler,
+	}
+	return app
 }
+
+// wire.go:
+
+var eventsSet = wire.NewSet(ioc.InitMemoryBroker, wire.Bind(new(events.Producer), new(*events.MemoryBroker)), wire.Bind(new(events.Subscriber), new(*events.MemoryBroker)), article.NewEventProducer, article.NewInteractiveReadEventBatchConsumer, ioc.InitConsumers)